@@ -11,6 +11,8 @@ function pretty_json {
 
 # API base URL
 API_URL="http://localhost:8080/api/v1"
+# Partner the admin acts for; staff must name one with the X-Partner-ID header
+PARTNER_ID="${PARTNER_ID:-00000000-0000-4000-8000-000000000001}"
 echo "🚀 Testing Banking Core API (Business Endpoints with KYC) at $API_URL"
 echo "=================================================="
 
@@ -46,6 +48,7 @@ echo "--------------------------------------------------"
 CREATE_RESULT=$(curl -s -X POST "$API_URL/entities/business" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
   -d '{
     "name": "Acme Corporation",
     "registration_number": "ACME-123456",
@@ -75,7 +78,8 @@ echo "📝 Step 3: Retrieving the created business entity"
 echo "--------------------------------------------------"
 
 GET_RESULT=$(curl -s -X GET "$API_URL/entities/business/$BUSINESS_ID" \
-  -H "Authorization: Bearer $TOKEN")
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
 
 if [[ $GET_RESULT == *"name"* ]]; then
   echo "✅ Business entity retrieved successfully"
@@ -95,6 +99,7 @@ echo "--------------------------------------------------"
 UPDATE_RESULT=$(curl -s -X PATCH "$API_URL/entities/business/$BUSINESS_ID" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
   -d '{
     "kyc_status": "verified"
   }')
@@ -115,7 +120,8 @@ echo "📝 Step 5: Listing all business entities"
 echo "--------------------------------------------------"
 
 LIST_RESULT=$(curl -s -X GET "$API_URL/entities/business?limit=10" \
-  -H "Authorization: Bearer $TOKEN")
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
 
 if [[ $LIST_RESULT == \[* ]]; then
   COUNT=$(echo "$LIST_RESULT" | grep -o '"id"' | wc -l)
@@ -11,6 +11,8 @@ function pretty_json {
 
 # API base URL
 API_URL="http://localhost:8080/api/v1"
+# Partner the admin acts for; staff must name one with the X-Partner-ID header
+PARTNER_ID="${PARTNER_ID:-00000000-0000-4000-8000-000000000001}"
 echo "🚀 Testing Banking Core API (Ledger Endpoints) at $API_URL"
 echo "=================================================="
 
@@ -39,18 +41,54 @@ fi
 
 echo
 
-# Step 2: Create Ledger Account #1
-echo "📝 Step 2: Creating the first ledger account (balance=1000)"
+# Step 2: Create a KYC-verified Account Owner
+echo "📝 Step 2: Creating a person entity and marking its KYC as verified"
 echo "--------------------------------------------------"
 
-CREATE_ACC1_RESULT=$(curl -s -X POST "$API_URL/ledger/account?balance=1000" \
-  -H "Authorization: Bearer $TOKEN")
+CREATE_PERSON_RESULT=$(curl -s -X POST "$API_URL/entities/person" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d '{
+    "first_name": "Ledger",
+    "last_name": "Owner",
+    "date_of_birth": "1990-01-01"
+  }')
+PERSON_ID=$(echo "$CREATE_PERSON_RESULT" | grep -o '"id":"[^"]*' | head -1 | grep -o '[^"]*$')
+
+VERIFY_PERSON_RESULT=$(curl -s -X PATCH "$API_URL/entities/person/$PERSON_ID" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d '{"kyc_status": "verified"}')
+
+if [[ -n $PERSON_ID && $VERIFY_PERSON_RESULT == *"\"kyc_status\":\"verified\""* ]]; then
+  echo "✅ Account owner created and verified"
+  echo "Person ID: $PERSON_ID"
+else
+  echo "❌ Failed to create a verified account owner"
+  pretty_json "$CREATE_PERSON_RESULT"
+  pretty_json "$VERIFY_PERSON_RESULT"
+  exit 1
+fi
 
-if [[ $CREATE_ACC1_RESULT == *"account_id"* ]]; then
+echo
+
+# Step 3: Create Ledger Account #1
+echo "📝 Step 3: Creating the first ledger account with an opening deposit of 1000.00 USD"
+echo "--------------------------------------------------"
+
+CREATE_ACC1_RESULT=$(curl -s -X POST "$API_URL/ledger/account" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"owner_type\": \"person\", \"owner_id\": \"$PERSON_ID\", \"currency\": \"USD\", \"initial_balance\": \"1000.00\"}")
+
+if [[ $CREATE_ACC1_RESULT == *"ledger_account_id"* ]]; then
   echo "✅ First account created successfully"
   pretty_json "$CREATE_ACC1_RESULT"
   # Extract the account ID
-  ACC1_ID=$(echo "$CREATE_ACC1_RESULT" | grep -o '"account_id":"[^"]*' | grep -o '[^"]*$')
+  ACC1_ID=$(echo "$CREATE_ACC1_RESULT" | grep -o '"ledger_account_id":"[^"]*' | grep -o '[^"]*$')
   echo "Account 1 ID: $ACC1_ID"
 else
   echo "❌ Failed to create the first ledger account"
@@ -60,18 +98,21 @@ fi
 
 echo
 
-# Step 3: Create Ledger Account #2
-echo "📝 Step 3: Creating the second ledger account (balance=500)"
+# Step 4: Create Ledger Account #2
+echo "📝 Step 4: Creating the second ledger account"
 echo "--------------------------------------------------"
 
-CREATE_ACC2_RESULT=$(curl -s -X POST "$API_URL/ledger/account?balance=500" \
-  -H "Authorization: Bearer $TOKEN")
+CREATE_ACC2_RESULT=$(curl -s -X POST "$API_URL/ledger/account" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"owner_type\": \"person\", \"owner_id\": \"$PERSON_ID\", \"currency\": \"USD\"}")
 
-if [[ $CREATE_ACC2_RESULT == *"account_id"* ]]; then
+if [[ $CREATE_ACC2_RESULT == *"ledger_account_id"* ]]; then
   echo "✅ Second account created successfully"
   pretty_json "$CREATE_ACC2_RESULT"
   # Extract the account ID
-  ACC2_ID=$(echo "$CREATE_ACC2_RESULT" | grep -o '"account_id":"[^"]*' | grep -o '[^"]*$')
+  ACC2_ID=$(echo "$CREATE_ACC2_RESULT" | grep -o '"ledger_account_id":"[^"]*' | grep -o '[^"]*$')
   echo "Account 2 ID: $ACC2_ID"
 else
   echo "❌ Failed to create the second ledger account"
@@ -81,12 +122,15 @@ fi
 
 echo
 
-# Step 4: Transfer Funds
-echo "📝 Step 4: Transferring 300 from the first account to the second"
+# Step 5: Transfer Funds
+echo "📝 Step 5: Transferring 300.00 USD from the first account to the second"
 echo "--------------------------------------------------"
 
-TRANSFER_RESULT=$(curl -s -X POST "$API_URL/ledger/transfer?from=$ACC1_ID&to=$ACC2_ID&amount=300" \
-  -H "Authorization: Bearer $TOKEN")
+TRANSFER_RESULT=$(curl -s -X POST "$API_URL/ledger/transfer" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"from_account_id\": \"$ACC1_ID\", \"to_account_id\": \"$ACC2_ID\", \"amount\": \"300.00\", \"currency\": \"USD\"}")
 
 if [[ $TRANSFER_RESULT == *"Transfer successful"* ]]; then
   echo "✅ Transfer completed successfully"
@@ -97,5 +141,129 @@ else
   exit 1
 fi
 
+echo
+
+# Step 6: Read Balances
+echo "📝 Step 6: Reading the balance of the second account"
+echo "--------------------------------------------------"
+
+BALANCE_RESULT=$(curl -s -X GET "$API_URL/ledger/accounts/$ACC2_ID" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
+
+if [[ $BALANCE_RESULT == *"\"available_balance\":\"300.00\""* ]]; then
+  echo "✅ Balance retrieved successfully"
+  pretty_json "$BALANCE_RESULT"
+else
+  echo "❌ Unexpected balance for the second account"
+  pretty_json "$BALANCE_RESULT"
+  exit 1
+fi
+
+echo
+
+# Step 7: Batch Lookup
+echo "📝 Step 7: Looking up both accounts at once"
+echo "--------------------------------------------------"
+
+LOOKUP_RESULT=$(curl -s -X POST "$API_URL/ledger/accounts/lookup" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"ids\": [\"$ACC1_ID\", \"$ACC2_ID\"]}")
+
+if [[ $LOOKUP_RESULT == *"$ACC1_ID"* && $LOOKUP_RESULT == *"$ACC2_ID"* ]]; then
+  echo "✅ Accounts looked up successfully"
+  pretty_json "$LOOKUP_RESULT"
+else
+  echo "❌ Failed to look up accounts"
+  pretty_json "$LOOKUP_RESULT"
+  exit 1
+fi
+
+echo
+
+# Step 8: Idempotent Retry
+echo "📝 Step 8: Retrying a transfer with the same Idempotency-Key"
+echo "--------------------------------------------------"
+
+IDEMPOTENCY_KEY="test-$(date +%s)"
+FIRST_RESULT=$(curl -s -X POST "$API_URL/ledger/transfer" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -H "Idempotency-Key: $IDEMPOTENCY_KEY" \
+  -d "{\"from_account_id\": \"$ACC2_ID\", \"to_account_id\": \"$ACC1_ID\", \"amount\": \"1.00\", \"currency\": \"USD\"}")
+RETRY_RESULT=$(curl -s -X POST "$API_URL/ledger/transfer" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -H "Idempotency-Key: $IDEMPOTENCY_KEY" \
+  -d "{\"from_account_id\": \"$ACC2_ID\", \"to_account_id\": \"$ACC1_ID\", \"amount\": \"1.00\", \"currency\": \"USD\"}")
+
+if [[ $FIRST_RESULT == *"transfer_id"* && $FIRST_RESULT == "$RETRY_RESULT" ]]; then
+  echo "✅ Retry replayed the original transfer"
+  pretty_json "$RETRY_RESULT"
+else
+  echo "❌ Retry did not replay the original transfer"
+  pretty_json "$FIRST_RESULT"
+  pretty_json "$RETRY_RESULT"
+  exit 1
+fi
+
+echo
+
+# Step 9: Deposits and Withdrawals
+echo "📝 Step 9: Depositing into and withdrawing from the second account"
+echo "--------------------------------------------------"
+
+DEPOSIT_RESULT=$(curl -s -X POST "$API_URL/ledger/deposits" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"account_id\": \"$ACC2_ID\", \"amount\": \"50.00\", \"currency\": \"USD\"}")
+WITHDRAWAL_RESULT=$(curl -s -X POST "$API_URL/ledger/withdrawals" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"account_id\": \"$ACC2_ID\", \"amount\": \"25.00\", \"currency\": \"USD\"}")
+OVERDRAFT_RESULT=$(curl -s -X POST "$API_URL/ledger/withdrawals" \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
+  -d "{\"account_id\": \"$ACC2_ID\", \"amount\": \"1000000.00\", \"currency\": \"USD\"}")
+
+if [[ $DEPOSIT_RESULT == *"transfer_id"* && $WITHDRAWAL_RESULT == *"transfer_id"* && $OVERDRAFT_RESULT == *"insufficient funds"* ]]; then
+  echo "✅ Deposit and withdrawal posted; overdraft rejected"
+  pretty_json "$DEPOSIT_RESULT"
+  pretty_json "$WITHDRAWAL_RESULT"
+  pretty_json "$OVERDRAFT_RESULT"
+else
+  echo "❌ Deposits and withdrawals did not behave as expected"
+  pretty_json "$DEPOSIT_RESULT"
+  pretty_json "$WITHDRAWAL_RESULT"
+  pretty_json "$OVERDRAFT_RESULT"
+  exit 1
+fi
+
+echo
+
+# Step 10: List the Owner's Accounts
+echo "📝 Step 10: Listing the accounts owned by the person"
+echo "--------------------------------------------------"
+
+OWNER_ACCOUNTS_RESULT=$(curl -s -X GET "$API_URL/entities/person/$PERSON_ID/accounts" \
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
+
+if [[ $OWNER_ACCOUNTS_RESULT == *"$ACC1_ID"* && $OWNER_ACCOUNTS_RESULT == *"$ACC2_ID"* ]]; then
+  echo "✅ Owner accounts listed successfully"
+  pretty_json "$OWNER_ACCOUNTS_RESULT"
+else
+  echo "❌ Failed to list the owner's accounts"
+  pretty_json "$OWNER_ACCOUNTS_RESULT"
+  exit 1
+fi
+
 echo
 echo "🎉 All Ledger endpoint tests completed successfully!"
\ No newline at end of file
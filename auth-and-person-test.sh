@@ -11,6 +11,8 @@ function pretty_json {
 
 # API base URL
 API_URL="http://localhost:8080/api/v1"
+# Partner the admin acts for; staff must name one with the X-Partner-ID header
+PARTNER_ID="${PARTNER_ID:-00000000-0000-4000-8000-000000000001}"
 echo "🚀 Testing Banking Core API (Person KYC) at $API_URL"
 echo "=================================================="
 
@@ -46,6 +48,7 @@ echo "--------------------------------------------------"
 CREATE_RESULT=$(curl -s -X POST "$API_URL/entities/person" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
   -d '{
     "first_name": "John",
     "last_name": "Doe",
@@ -82,7 +85,8 @@ echo "📝 Step 3: Retrieving the created person entity"
 echo "--------------------------------------------------"
 
 GET_RESULT=$(curl -s -X GET "$API_URL/entities/person/$PERSON_ID" \
-  -H "Authorization: Bearer $TOKEN")
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
 
 if [[ $GET_RESULT == *"first_name"* ]]; then
   echo "✅ Person entity retrieved successfully"
@@ -102,6 +106,7 @@ echo "--------------------------------------------------"
 UPDATE_RESULT=$(curl -s -X PATCH "$API_URL/entities/person/$PERSON_ID" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID" \
   -d '{
     "kyc_status": "verified"
   }')
@@ -122,7 +127,8 @@ echo "📝 Step 5: Listing all person entities"
 echo "--------------------------------------------------"
 
 LIST_RESULT=$(curl -s -X GET "$API_URL/entities/person?limit=10" \
-  -H "Authorization: Bearer $TOKEN")
+  -H "Authorization: Bearer $TOKEN" \
+  -H "X-Partner-ID: $PARTNER_ID")
 
 if [[ $LIST_RESULT == \[* ]]; then
   PEOPLE_COUNT=$(echo "$LIST_RESULT" | grep -o '"id"' | wc -l)
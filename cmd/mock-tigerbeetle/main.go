@@ -1,62 +1,310 @@
+// Command mock-tigerbeetle serves an in-memory ledger over HTTP so that test
+// scripts and local frontends can run against realistic ledger behaviour,
+// including rejected transfers, without a TigerBeetle cluster.
+//
+// The routes mirror the TigerBeetle client: batches of accounts and transfers
+// are created with POST /accounts/create and POST /transfers/create, and only
+// the events that failed are returned. Results use TigerBeetle's numeric codes
+// with their names alongside.
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger/memory"
+	"github.com/google/uuid"
 )
 
-// CreateAccountRequest represents the expected payload for account creation.
-type CreateAccountRequest struct {
-	AccountID      string `json:"account_id"`
-	InitialBalance int64  `json:"initial_balance"`
+// defaultHistoryLimit is the number of transfers returned by the history read
+// when no limit is given.
+const defaultHistoryLimit = 100
+
+// accountResult is an account event result with the name of its code.
+type accountResult struct {
+	ledger.AccountEventResult
+	Name string `json:"name"`
+}
+
+// transferResult is a transfer event result with the name of its code.
+type transferResult struct {
+	ledger.TransferEventResult
+	Name string `json:"name"`
 }
 
-// TransferRequest represents the expected payload for a funds transfer.
-type TransferRequest struct {
-	FromAccountID string `json:"from_account_id"`
-	ToAccountID   string `json:"to_account_id"`
-	Amount        int64  `json:"amount"`
+// server holds the ledger served by the mock.
+type server struct {
+	ledger *memory.Ledger
+	// snapshotPath is the file the ledger is saved to and loaded from.
+	snapshotPath string
 }
 
-// createAccountHandler handles account creation requests.
-func createAccountHandler(w http.ResponseWriter, r *http.Request) {
-	var req CreateAccountRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// createAccountsHandler creates a batch of accounts.
+func (s *server) createAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	var accounts []ledger.Account
+	if err := json.NewDecoder(r.Body).Decode(&accounts); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	log.Printf("Mock TigerBeetle Server: Creating account: %+v\n", req)
-	
-	response := map[string]string{
-		"message":   "Account created successfully",
-		"accountID": req.AccountID,
+	results, err := s.ledger.CreateAccounts(r.Context(), accounts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+
+	response := make([]accountResult, len(results))
+	for i, result := range results {
+		response[i] = accountResult{AccountEventResult: result, Name: result.Result.String()}
+		log.Printf("Mock TigerBeetle Server: Account %s rejected: %s\n", accounts[result.Index].ID, result.Result)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// createTransfersHandler creates a batch of transfers.
+func (s *server) createTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	var transfers []ledger.Transfer
+	if err := json.NewDecoder(r.Body).Decode(&transfers); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	results, err := s.ledger.CreateTransfers(r.Context(), transfers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]transferResult, len(results))
+	for i, result := range results {
+		response[i] = transferResult{TransferEventResult: result, Name: result.Result.String()}
+		log.Printf("Mock TigerBeetle Server: Transfer %s rejected: %s\n", transfers[result.Index].ID, result.Result)
+	}
+	writeJSON(w, http.StatusOK, response)
 }
 
-// transferHandler handles funds transfer requests.
-func transferHandler(w http.ResponseWriter, r *http.Request) {
-	var req TransferRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// lookupAccountsHandler returns the accounts that exist among a list of IDs.
+func (s *server) lookupAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []uuid.UUID
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	log.Printf("Mock TigerBeetle Server: Transferring funds: %+v\n", req)
-	
-	response := map[string]string{
-		"message": "Transfer successful",
+	accounts, err := s.ledger.LookupAccounts(r.Context(), ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// lookupTransfersHandler returns the transfers that exist among a list of IDs.
+func (s *server) lookupTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []uuid.UUID
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	transfers, err := s.ledger.LookupTransfers(r.Context(), ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+// accountTransfersHandler returns the transfers that match an account filter.
+func (s *server) accountTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	var filter ledger.AccountFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	transfers, err := s.ledger.GetAccountTransfers(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+// getAccountHandler returns a single account and its balances.
+func (s *server) getAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	accounts, err := s.ledger.LookupAccounts(r.Context(), []uuid.UUID{id})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(accounts) == 0 {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts[0])
+}
+
+// getAccountHistoryHandler returns the transfers that touched an account,
+// newest first unless ?reversed=false, up to ?limit transfers.
+func (s *server) getAccountHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	filter := ledger.AccountFilter{
+		AccountID: id,
+		Limit:     defaultHistoryLimit,
+		Debits:    true,
+		Credits:   true,
+		Reversed:  r.URL.Query().Get("reversed") != "false",
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	transfers, err := s.ledger.GetAccountTransfers(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, transfers)
+}
+
+// getSnapshotHandler downloads the state of the ledger.
+func (s *server) getSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.ledger.Save(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// putSnapshotHandler replaces the state of the ledger with an uploaded snapshot.
+func (s *server) putSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.ledger.Load(r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Println("Mock TigerBeetle Server: Snapshot loaded")
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Snapshot loaded"})
+}
+
+// saveSnapshotHandler writes the state of the ledger to the snapshot file.
+func (s *server) saveSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.saveSnapshot(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Snapshot saved", "path": s.snapshotPath})
+}
+
+// loadSnapshotHandler replaces the state of the ledger with the snapshot file.
+func (s *server) loadSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.loadSnapshot(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Snapshot loaded", "path": s.snapshotPath})
+}
+
+// saveSnapshot writes the ledger to the snapshot file, replacing it atomically.
+func (s *server) saveSnapshot() error {
+	if s.snapshotPath == "" {
+		return errors.New("no snapshot file configured; start the server with -snapshot")
+	}
+	var buf bytes.Buffer
+	if err := s.ledger.Save(&buf); err != nil {
+		return err
+	}
+	tmp := s.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.snapshotPath); err != nil {
+		return err
+	}
+	log.Printf("Mock TigerBeetle Server: Snapshot saved to %s\n", s.snapshotPath)
+	return nil
+}
+
+// loadSnapshot loads the ledger from the snapshot file.
+func (s *server) loadSnapshot() error {
+	if s.snapshotPath == "" {
+		return errors.New("no snapshot file configured; start the server with -snapshot")
+	}
+	f, err := os.Open(s.snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := s.ledger.Load(f); err != nil {
+		return err
+	}
+	log.Printf("Mock TigerBeetle Server: Snapshot loaded from %s\n", s.snapshotPath)
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
 }
 
 func main() {
-	http.HandleFunc("/account", createAccountHandler)
-	http.HandleFunc("/transfer", transferHandler)
-	addr := ":9000"
-	fmt.Printf("Starting TigerBeetle mock server on %s...\n", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
-}
\ No newline at end of file
+	addr := flag.String("addr", ":9000", "address to listen on")
+	snapshotPath := flag.String("snapshot", "", "JSON snapshot file loaded at startup, if it exists, and saved on shutdown")
+	flag.Parse()
+
+	s := &server{ledger: memory.NewLedger(), snapshotPath: *snapshotPath}
+	if s.snapshotPath != "" {
+		if err := s.loadSnapshot(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Failed to load snapshot: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accounts/create", s.createAccountsHandler)
+	mux.HandleFunc("POST /transfers/create", s.createTransfersHandler)
+	mux.HandleFunc("POST /accounts/lookup", s.lookupAccountsHandler)
+	mux.HandleFunc("POST /transfers/lookup", s.lookupTransfersHandler)
+	mux.HandleFunc("POST /accounts/transfers", s.accountTransfersHandler)
+	mux.HandleFunc("GET /accounts/{id}", s.getAccountHandler)
+	mux.HandleFunc("GET /accounts/{id}/transfers", s.getAccountHistoryHandler)
+	mux.HandleFunc("GET /snapshot", s.getSnapshotHandler)
+	mux.HandleFunc("PUT /snapshot", s.putSnapshotHandler)
+	mux.HandleFunc("POST /snapshot/save", s.saveSnapshotHandler)
+	mux.HandleFunc("POST /snapshot/load", s.loadSnapshotHandler)
+
+	if s.snapshotPath != "" {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			if err := s.saveSnapshot(); err != nil {
+				log.Printf("Failed to save snapshot: %v", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}()
+	}
+
+	fmt.Printf("Starting TigerBeetle mock server on %s...\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
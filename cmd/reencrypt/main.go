@@ -0,0 +1,87 @@
+// Command reencrypt brings stored SSNs, government IDs and tax IDs up to date
+// with the field encryption keys: values stored before encryption was turned
+// on are encrypted, values under an older KEK are encrypted again with the
+// current one, and missing SSN blind indexes are filled in.
+//
+// It reads the same environment as the server. Run it after turning on field
+// encryption and after every change of FIELD_ENCRYPTION_CURRENT_KEY; once it
+// has finished, old KEKs can be removed from FIELD_ENCRYPTION_KEYS. Records
+// that are up to date are left alone, so it is safe to run again. It is also
+// safe to run alongside the server: a value is only written back if it is
+// still the one that was read, and one the server changed in the meantime is
+// skipped, since the server wrote it with the current key.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/Cassandra-Labs-Foundation/core/internal/config"
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/redact"
+	"github.com/google/uuid"
+)
+
+// reencryptFunc re-encrypts a page of one table's records
+type reencryptFunc func(ctx context.Context, after uuid.UUID, limit int) (*repository.ReencryptPage, error)
+
+func main() {
+	log.SetOutput(redact.NewWriter(os.Stderr))
+	batch := flag.Int("batch", 100, "number of records read per request")
+	flag.Parse()
+	if *batch <= 0 {
+		log.Fatalf("-batch must be positive")
+	}
+
+	cfg := config.Load()
+	if len(cfg.Encryption.Keys) == 0 || cfg.Encryption.BlindIndexKey == "" {
+		log.Fatalf("FIELD_ENCRYPTION_KEYS and FIELD_BLIND_INDEX_KEY are not set")
+	}
+	keys, err := fieldcrypt.LoadKeyring(cfg.Encryption.Keys, cfg.Encryption.CurrentKey, cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		log.Fatalf("Invalid field encryption keys: %v", err)
+	}
+	client := supabase.NewClient(cfg.Supabase.URL, cfg.Supabase.APIKey)
+
+	// Every partner's records are re-encrypted
+	ctx := tenant.WithAllPartners(context.Background())
+	tables := []struct {
+		name      string
+		reencrypt reencryptFunc
+	}{
+		{"person_entities", repository.NewPersonRestRepository(client, keys).Reencrypt},
+		{"business_entities", repository.NewBusinessRestRepository(client, keys).Reencrypt},
+	}
+	for _, table := range tables {
+		total, err := reencryptTable(ctx, table.reencrypt, *batch)
+		if err != nil {
+			log.Fatalf("Re-encrypting %s: %v (%d of %d records read so far updated)", table.name, err, total.Updated, total.Scanned)
+		}
+		log.Printf("Re-encrypted %s: %d of %d records updated, %d changed meanwhile and skipped",
+			table.name, total.Updated, total.Scanned, total.Skipped)
+	}
+}
+
+// reencryptTable re-encrypts a table page by page until a page comes back
+// short, and returns the totals of all pages
+func reencryptTable(ctx context.Context, reencrypt reencryptFunc, batch int) (repository.ReencryptPage, error) {
+	var total repository.ReencryptPage
+	for {
+		page, err := reencrypt(ctx, total.Last, batch)
+		if err != nil {
+			return total, err
+		}
+		total.Scanned += page.Scanned
+		total.Updated += page.Updated
+		total.Skipped += page.Skipped
+		total.Last = page.Last
+		if page.Scanned < batch {
+			return total, nil
+		}
+	}
+}
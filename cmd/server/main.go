@@ -1,26 +1,49 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"strconv"
 	"github.com/joho/godotenv"
 	"github.com/gin-gonic/gin"
 	"github.com/Cassandra-Labs-Foundation/core/internal/api/auth"
 	"github.com/Cassandra-Labs-Foundation/core/internal/api/middleware"
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/mocktigerbeetle"
 	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
 	"github.com/Cassandra-Labs-Foundation/core/internal/clients/tigerbeetle"
 	"github.com/Cassandra-Labs-Foundation/core/internal/config"
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger/memory"
+	"github.com/Cassandra-Labs-Foundation/core/internal/ratelimit"
+	"github.com/Cassandra-Labs-Foundation/core/internal/rbac"
 	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
 	authService "github.com/Cassandra-Labs-Foundation/core/internal/service/auth"
+	auditApi "github.com/Cassandra-Labs-Foundation/core/internal/api/audit"
+	auditService "github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	userApi "github.com/Cassandra-Labs-Foundation/core/internal/api/user"
+	userService "github.com/Cassandra-Labs-Foundation/core/internal/service/user"
+	apikeyApi "github.com/Cassandra-Labs-Foundation/core/internal/api/apikey"
+	apikeyService "github.com/Cassandra-Labs-Foundation/core/internal/service/apikey"
+	oauthApi "github.com/Cassandra-Labs-Foundation/core/internal/api/oauth"
+	oauthService "github.com/Cassandra-Labs-Foundation/core/internal/service/oauth"
+	signingApi "github.com/Cassandra-Labs-Foundation/core/internal/api/signing"
+	signingService "github.com/Cassandra-Labs-Foundation/core/internal/service/signing"
 	personApi "github.com/Cassandra-Labs-Foundation/core/internal/api/person"
 	personService "github.com/Cassandra-Labs-Foundation/core/internal/service/person"
 	businessApi "github.com/Cassandra-Labs-Foundation/core/internal/api/business"
 	businessService "github.com/Cassandra-Labs-Foundation/core/internal/service/business"
 	ledgerApi "github.com/Cassandra-Labs-Foundation/core/internal/api/ledger"
 	ledgerService "github.com/Cassandra-Labs-Foundation/core/internal/service/ledger"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
 	"github.com/Cassandra-Labs-Foundation/core/pkg/jwt"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/redact"
+	"github.com/google/uuid"
 )
 
 func main() {
+	// Keep SSNs, tax IDs, dates of birth and bearer tokens out of the logs
+	log.SetOutput(redact.NewWriter(os.Stderr))
+	
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
@@ -33,74 +56,361 @@ func main() {
 	log.Printf("Connecting to Supabase at: %s", cfg.Supabase.URL)
 	supabaseClient := supabase.NewClient(cfg.Supabase.URL, cfg.Supabase.APIKey)
 	
-	// Create JWT service
-	jwtService := jwt.NewService(cfg.JWT.Secret, cfg.JWT.ExpiryMinutes)
+	// Create JWT service. Tokens are signed with an asymmetric key so that
+	// partners can verify them against our JWKS without holding a secret.
+	if cfg.JWT.PrivateKeyFile == "" {
+		log.Fatalf("JWT_PRIVATE_KEY_FILE is not set; refusing to start without a signing key")
+	}
+	signingKey, err := jwt.LoadKey(cfg.JWT.PrivateKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing key: %v", err)
+	}
+	var verificationKeys []*jwt.Key
+	for _, path := range cfg.JWT.VerificationKeyFiles {
+		key, err := jwt.LoadKey(path)
+		if err != nil {
+			log.Fatalf("Failed to load JWT verification key: %v", err)
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+	jwtService, err := jwt.NewService(signingKey, verificationKeys, cfg.JWT.ExpiryMinutes)
+	if err != nil {
+		log.Fatalf("Failed to create JWT service: %v", err)
+	}
+	log.Printf("Signing JWTs with %s key %s", signingKey.Method.Alg(), signingKey.ID)
+	
+	// Create audit log repository, service and handler. Services record
+	// logins and changes to entities, accounts and transfers with it.
+	auditRepo := repository.NewAuditRestRepository(supabaseClient)
+	auditSvc := auditService.NewService(auditRepo)
+	auditHandler := auditApi.NewHandler(auditSvc)
 	
-	// Create auth service and handler
-	authSvc := authService.NewService(jwtService)
+	// Create user repository, service and handler; users and their password
+	// hashes are stored in Supabase
+	userRepo := repository.NewUserRestRepository(supabaseClient)
+	userSvc := userService.NewService(userRepo)
+	userHandler := userApi.NewHandler(userSvc)
+	if cfg.Auth.BootstrapAdminPassword != "" {
+		if err := userSvc.EnsureAdmin(context.Background(), cfg.Auth.BootstrapAdminUsername, cfg.Auth.BootstrapAdminPassword); err != nil {
+			log.Fatalf("Failed to create bootstrap admin user: %v", err)
+		}
+	}
+	
+	// Create auth service and handler. Refresh tokens and the denylist of
+	// revoked access tokens are stored in Supabase.
+	refreshTokenRepo := repository.NewRefreshTokenRestRepository(supabaseClient)
+	revokedTokenRepo := repository.NewRevokedTokenRestRepository(supabaseClient)
+	authSvc, err := authService.NewService(jwtService, userRepo, refreshTokenRepo, revokedTokenRepo, authService.LockoutPolicy{
+		MaxFailedAttempts: cfg.Auth.MaxFailedLogins,
+		Duration:          cfg.Auth.LockoutDuration,
+	}, cfg.Auth.RefreshTokenTTL, cfg.Auth.MFAIssuer, auditSvc)
+	if err != nil {
+		log.Fatalf("Failed to create auth service: %v", err)
+	}
 	authHandler := auth.NewHandler(authSvc)
 	
+	// Create API key repository, service and handler for partner credentials
+	apiKeyRepo := repository.NewAPIKeyRestRepository(supabaseClient)
+	apiKeySvc := apikeyService.NewService(apiKeyRepo)
+	apiKeyHandler := apikeyApi.NewHandler(apiKeySvc)
+	
+	// Create OAuth client repository, service and handler for partners using
+	// the client credentials grant
+	oauthClientRepo := repository.NewOAuthClientRestRepository(supabaseClient)
+	oauthSvc := oauthService.NewService(oauthClientRepo, authSvc, jwtService)
+	oauthHandler := oauthApi.NewHandler(oauthSvc)
+	
+	// Create signing key repository, service and handler for partners that
+	// sign their requests
+	signingKeyRepo := repository.NewSigningKeyRestRepository(supabaseClient)
+	signingSvc := signingService.NewService(signingKeyRepo)
+	signingHandler := signingApi.NewHandler(signingSvc)
+	var signingRequired []uuid.UUID
+	for _, partner := range cfg.Signing.RequiredPartners {
+		partnerID, err := uuid.Parse(partner)
+		if err != nil {
+			log.Fatalf("Invalid partner ID in SIGNING_REQUIRED_PARTNERS: %s", partner)
+		}
+		signingRequired = append(signingRequired, partnerID)
+	}
+	
+	// Create the keyring that SSNs, government IDs and tax IDs are encrypted
+	// with before they are stored
+	if len(cfg.Encryption.Keys) == 0 || cfg.Encryption.BlindIndexKey == "" {
+		log.Fatalf("FIELD_ENCRYPTION_KEYS and FIELD_BLIND_INDEX_KEY are not set; refusing to start without field encryption keys")
+	}
+	fieldKeys, err := fieldcrypt.LoadKeyring(cfg.Encryption.Keys, cfg.Encryption.CurrentKey, cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		log.Fatalf("Failed to create field encryption keyring: %v", err)
+	}
+	
 	// Create person repository, service and handler using Supabase REST API
-	personRepo := repository.NewPersonRestRepository(supabaseClient)
-	personSvc := personService.NewService(personRepo)
+	personRepo := repository.NewPersonRestRepository(supabaseClient, fieldKeys)
+	personSvc := personService.NewService(personRepo, auditSvc)
 	personHandler := personApi.NewHandler(personSvc)
 	
 	// Create business repository, service and handler using Supabase REST API
-	businessRepo := repository.NewBusinessRestRepository(supabaseClient)
-	businessSvc := businessService.NewService(businessRepo)
+	businessRepo := repository.NewBusinessRestRepository(supabaseClient, fieldKeys)
+	businessSvc := businessService.NewService(businessRepo, auditSvc)
 	businessHandler := businessApi.NewHandler(businessSvc)
 
-	// Create TigerBeetle client (you'll need an endpoint; this is a stub/example)
-	tbClient := tigerbeetle.NewClient("http://localhost:9000")
-
+	// Create the ledger backend: a TigerBeetle cluster, or an in-memory ledger
+	// or the mock TigerBeetle server for development machines and CI without a cluster
+	var ledgerBackend repository.LedgerBackend
+	switch cfg.Ledger.Backend {
+	case "memory":
+		log.Println("Using in-memory ledger; balances are lost on restart")
+		ledgerBackend = memory.NewLedger()
+	case "mock":
+		log.Printf("Using mock TigerBeetle server at: %s", cfg.Ledger.MockURL)
+		ledgerBackend = mocktigerbeetle.NewClient(cfg.Ledger.MockURL)
+	case "tigerbeetle":
+		log.Printf("Connecting to TigerBeetle cluster %d at: %v", cfg.TigerBeetle.ClusterID, cfg.TigerBeetle.Addresses)
+		tbClient, err := tigerbeetle.NewClient(cfg.TigerBeetle.ClusterID, cfg.TigerBeetle.Addresses)
+		if err != nil {
+			log.Fatalf("Failed to create TigerBeetle client: %v", err)
+		}
+		defer tbClient.Close()
+		ledgerBackend = tbClient
+	default:
+		log.Fatalf("Unknown ledger backend: %s", cfg.Ledger.Backend)
+	}
 
     // Create ledger repository and service
-    ledgerRepo := repository.NewLedgerRepository(tbClient)
-    ledgerSvc := ledgerService.NewService(ledgerRepo)
+    ledgerRepo := repository.NewLedgerRepository(ledgerBackend, cfg.Ledger.Code)
+
+	// Each currency is held on its own ledger, with its own settlement accounts
+	// that fund deposits and receive withdrawals
+	ledgerConfig := ledgerService.Config{
+		Ledgers: make(map[string]uint32),
+		Settlement: ledgerService.SettlementAccounts{
+			Accounts: make(map[string]uuid.UUID),
+			Default:  cfg.Ledger.DefaultSettlementAccount,
+		},
+	}
+	for currency, ledgerStr := range cfg.Ledger.Currencies {
+		ledgerID, err := strconv.ParseUint(ledgerStr, 10, 32)
+		if err != nil {
+			log.Fatalf("Invalid ledger for currency %s: %v", currency, err)
+		}
+		ledgerConfig.Ledgers[currency] = uint32(ledgerID)
+	}
+	for name, idStr := range cfg.Ledger.SettlementAccounts {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			log.Fatalf("Invalid ID for settlement account %s: %v", name, err)
+		}
+		ledgerConfig.Settlement.Accounts[name] = id
+	}
+	liquidityAccount, err := uuid.Parse(cfg.Ledger.LiquidityAccount)
+	if err != nil {
+		log.Fatalf("Invalid ID for liquidity account: %v", err)
+	}
+	ledgerConfig.LiquidityAccount = liquidityAccount
+
+    // Accounts are registered against their owners in the Supabase account registry
+    accountRepo := repository.NewAccountRestRepository(supabaseClient)
+    transferDetailsRepo := repository.NewTransferDetailsRestRepository(supabaseClient)
+    ledgerSvc, err := ledgerService.NewService(ledgerRepo, accountRepo, transferDetailsRepo, personRepo, businessRepo, auditSvc, ledgerConfig)
+	if err != nil {
+		log.Fatalf("Invalid ledger configuration: %v", err)
+	}
+	if err := ledgerSvc.CreateSystemAccounts(context.Background()); err != nil {
+		log.Fatalf("Failed to create system accounts: %v", err)
+	}
     ledgerHandler := ledgerApi.NewHandler(ledgerSvc)
 	
-	// Create gin router
+	// Roles map to permissions through a policy that can be replaced without a release
+	policy := rbac.DefaultPolicy
+	if cfg.RBAC.PolicyFile != "" {
+		log.Printf("Loading RBAC policy from: %s", cfg.RBAC.PolicyFile)
+		policy, err = rbac.LoadPolicy(cfg.RBAC.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load RBAC policy: %v", err)
+		}
+	}
+	
+	// Rate limits per route group and partner plan, like the RBAC policy
+	rateLimits := ratelimit.DefaultPolicy
+	if cfg.RateLimit.PolicyFile != "" {
+		log.Printf("Loading rate limit policy from: %s", cfg.RateLimit.PolicyFile)
+		rateLimits, err = ratelimit.LoadPolicy(cfg.RateLimit.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load rate limit policy: %v", err)
+		}
+	}
+	rateLimitStore := middleware.NewMemoryRateLimitStore()
+	
+	// Create gin router. Its request log, like ours, goes through redaction
+	// so that personal data and tokens never reach the logs.
+	gin.DefaultWriter = redact.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = redact.NewWriter(os.Stderr)
 	r := gin.Default()
+	// Client IPs key the rate limits of unauthenticated callers and go in the
+	// audit log, so X-Forwarded-For is only believed from our own proxies
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid SERVER_TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.RequestIDMiddleware())
+	
+	// Public keys for verifying our tokens
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+	
+	// OAuth2 endpoints; clients authenticate with their own credentials
+	oauthRoutes := r.Group("/oauth", middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Auth))
+	oauthRoutes.POST("/token", oauthHandler.Token)
+	oauthRoutes.POST("/introspect", oauthHandler.Introspect)
 	
 	// Define API routes
 	api := r.Group("/api/v1")
 	
 	// Auth routes (no authentication required)
-	authRoutes := api.Group("/auth")
+	authRoutes := api.Group("/auth", middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Auth))
 	{
 		authRoutes.POST("/login", authHandler.Login)
 		authRoutes.POST("/refresh", authHandler.RefreshToken)
+		authRoutes.POST("/mfa/challenge", authHandler.MFAChallenge)
 	}
 	
 	// Protected routes (authentication required)
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware(authSvc))
+	protected.Use(middleware.AuthMiddleware(authSvc, apiKeySvc))
+	protected.Use(middleware.ActorMiddleware())
+	// Idempotency-Key handling goes last on each mutating route, after every
+	// check that can reject a request, so that a rejected request fixed and
+	// retried with the same key is not answered with the stored rejection
+	idempotent := middleware.IdempotencyMiddleware(middleware.NewMemoryIdempotencyStore(), cfg.Idempotency.TTL)
 	{
 		protected.GET("/auth/validate", authHandler.ValidateToken)
+		protected.POST("/auth/logout", idempotent, authHandler.Logout)
+		protected.POST("/auth/password", middleware.RequirePermission(policy, rbac.PasswordChange), idempotent, authHandler.ChangePassword)
+		protected.POST("/auth/mfa/enroll", idempotent, authHandler.EnrollMFA)
+		protected.POST("/auth/mfa/activate", idempotent, authHandler.ActivateMFA)
+		protected.POST("/auth/mfa/disable", idempotent, authHandler.DisableMFA)
+		
+		// Everything else needs a second factor for the roles that require MFA;
+		// the auth routes above stay open so those users can enroll
+		secured := protected.Group("", middleware.RequireMFA(cfg.Auth.MFARequiredRoles))
+		
+		// User management
+		userRoutes := secured.Group("/users")
+		userRoutes.Use(middleware.RequirePermission(policy, rbac.UsersManage), idempotent)
+		{
+			userRoutes.POST("", userHandler.Create)
+			userRoutes.GET("", userHandler.List)
+			userRoutes.GET("/:id", userHandler.Get)
+			userRoutes.PATCH("/:id", userHandler.Update)
+			userRoutes.DELETE("/:id", userHandler.Delete)
+			userRoutes.POST("/:id/unlock", userHandler.Unlock)
+			userRoutes.DELETE("/:id/mfa", userHandler.ResetMFA)
+		}
+		
+		// Partner API key management
+		apiKeyRoutes := secured.Group("/api-keys")
+		apiKeyRoutes.Use(middleware.RequirePermission(policy, rbac.APIKeysManage), idempotent)
+		{
+			apiKeyRoutes.POST("", apiKeyHandler.Issue)
+			apiKeyRoutes.GET("", apiKeyHandler.List)
+			apiKeyRoutes.POST("/:id/rotate", apiKeyHandler.Rotate)
+			apiKeyRoutes.DELETE("/:id", apiKeyHandler.Revoke)
+		}
+		
+		// OAuth client management
+		oauthClientRoutes := secured.Group("/oauth-clients")
+		oauthClientRoutes.Use(middleware.RequirePermission(policy, rbac.OAuthClientsManage), idempotent)
+		{
+			oauthClientRoutes.POST("", oauthHandler.Register)
+			oauthClientRoutes.GET("", oauthHandler.List)
+			oauthClientRoutes.DELETE("/:id", oauthHandler.Revoke)
+		}
+		
+		// Request signing key management
+		signingKeyRoutes := secured.Group("/signing-keys")
+		signingKeyRoutes.Use(middleware.RequirePermission(policy, rbac.SigningKeysManage), idempotent)
+		{
+			signingKeyRoutes.POST("", signingHandler.Issue)
+			signingKeyRoutes.GET("", signingHandler.List)
+			signingKeyRoutes.DELETE("/:id", signingHandler.Revoke)
+		}
 		
 		// Person entity routes
-		personRoutes := protected.Group("/entities/person")
+		personRoutes := secured.Group("/entities/person")
+		personRoutes.Use(middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Entities))
+		personRoutes.Use(middleware.TenantMiddleware(policy))
 		{
-			personRoutes.POST("", personHandler.Create)
-			personRoutes.GET("", personHandler.List)
-			personRoutes.GET("/:id", personHandler.Get)
-			personRoutes.PATCH("/:id", personHandler.Update)
+			personRead := personRoutes.Group("", middleware.RequireScope("entities:read"), middleware.RequirePermission(policy, rbac.EntitiesRead))
+			personRead.GET("", personHandler.List)
+			personRead.GET("/:id", middleware.RequireRevealPermission(policy), personHandler.Get)
+			personRead.GET("/:id/accounts", ledgerHandler.ListPersonAccountsHandler)
+			personRead.POST("/lookup", personHandler.Lookup)
+			
+			personWrite := personRoutes.Group("", middleware.RequireScope("entities:write"), middleware.RequirePermission(policy, rbac.EntitiesWrite), idempotent)
+			personWrite.POST("", personHandler.Create)
+			personWrite.PATCH("/:id", personHandler.Update)
 		}
 		
 		// Business entity routes
-		businessRoutes := protected.Group("/entities/business")
+		businessRoutes := secured.Group("/entities/business")
+		businessRoutes.Use(middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Entities))
+		businessRoutes.Use(middleware.TenantMiddleware(policy))
 		{
-			businessRoutes.POST("", businessHandler.Create)
-			businessRoutes.GET("", businessHandler.List)
-			businessRoutes.GET("/:id", businessHandler.Get)
-			businessRoutes.PATCH("/:id", businessHandler.Update)
+			businessRead := businessRoutes.Group("", middleware.RequireScope("entities:read"), middleware.RequirePermission(policy, rbac.EntitiesRead))
+			businessRead.GET("", businessHandler.List)
+			businessRead.GET("/:id", businessHandler.Get)
+			businessRead.GET("/:id/accounts", ledgerHandler.ListBusinessAccountsHandler)
+			
+			businessWrite := businessRoutes.Group("", middleware.RequireScope("entities:write"), middleware.RequirePermission(policy, rbac.EntitiesWrite), idempotent)
+			businessWrite.POST("", businessHandler.Create)
+			businessWrite.PATCH("/:id", businessHandler.Update)
 		}
 		
 		// Ledger routes (TigerBeetle)
-		ledgerRoutes := protected.Group("/ledger")
+		ledgerRoutes := secured.Group("/ledger")
+		ledgerRoutes.Use(middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Ledger))
+		ledgerRoutes.Use(middleware.TenantMiddleware(policy))
+		ledgerRoutes.Use(middleware.SignatureMiddleware(signingSvc, middleware.NewMemoryNonceStore(), cfg.Signing.Window, signingRequired))
+		{
+			ledgerRead := ledgerRoutes.Group("", middleware.RequireScope("ledger:read"), middleware.RequirePermission(policy, rbac.LedgerRead))
+			ledgerRead.GET("/accounts/:id", ledgerHandler.GetAccountHandler)
+			ledgerRead.GET("/accounts/:id/transfers", ledgerHandler.ListTransfersHandler)
+			ledgerRead.POST("/accounts/lookup", ledgerHandler.LookupAccountsHandler)
+			
+			ledgerWrite := ledgerRoutes.Group("", middleware.RequireScope("ledger:write"), middleware.RequirePermission(policy, rbac.LedgerWrite), idempotent)
+			ledgerWrite.POST("/account", ledgerHandler.CreateAccountHandler)
+			
+			// Anything that moves money
+			ledgerTransfer := ledgerRoutes.Group("", middleware.RequireScope("ledger:write"), middleware.RequirePermission(policy, rbac.LedgerTransfer),
+				middleware.RateLimitMiddleware(rateLimits, rateLimitStore, ratelimit.Transfers), idempotent)
+			ledgerTransfer.POST("/transfer", ledgerHandler.TransferHandler)
+			ledgerTransfer.POST("/deposits", ledgerHandler.DepositHandler)
+			ledgerTransfer.POST("/withdrawals", ledgerHandler.WithdrawalHandler)
+			ledgerTransfer.POST("/transfers/batch", ledgerHandler.TransferBatchHandler)
+			ledgerTransfer.POST("/transfers/exchange", ledgerHandler.ExchangeHandler)
+			ledgerTransfer.POST("/transfers/pending", ledgerHandler.CreatePendingTransferHandler)
+			ledgerTransfer.POST("/transfers/:id/post", ledgerHandler.PostPendingTransferHandler)
+			ledgerTransfer.POST("/transfers/:id/void", ledgerHandler.VoidPendingTransferHandler)
+		}
+	
+		// Cross-tenant admin view, read-only. Lists every partner's records
+		// unless narrowed with ?partner_id=
+		adminRoutes := secured.Group("/admin")
+		adminRoutes.Use(middleware.RequirePermission(policy, rbac.TenantsAll))
+		adminRoutes.Use(middleware.CrossTenantMiddleware())
+		{
+			adminRoutes.GET("/entities/person", personHandler.List)
+			adminRoutes.GET("/entities/person/:id", middleware.RequireRevealPermission(policy), personHandler.Get)
+			adminRoutes.GET("/entities/person/:id/accounts", ledgerHandler.ListPersonAccountsHandler)
+			adminRoutes.GET("/entities/business", businessHandler.List)
+			adminRoutes.GET("/entities/business/:id", businessHandler.Get)
+			adminRoutes.GET("/entities/business/:id/accounts", ledgerHandler.ListBusinessAccountsHandler)
+		}
+	
+		// Audit log, read-only
+		auditRoutes := secured.Group("/audit")
+		auditRoutes.Use(middleware.RequirePermission(policy, rbac.AuditRead))
 		{
-			ledgerRoutes.POST("/account", ledgerHandler.CreateAccountHandler)
-			ledgerRoutes.POST("/transfer", ledgerHandler.TransferHandler)
+			auditRoutes.GET("", auditHandler.List)
+			auditRoutes.GET("/verify", auditHandler.Verify)
 		}
 	
 		// Additional protected route example
@@ -0,0 +1,37 @@
+// Package actor carries who made a request, and where it came from, through
+// to the services that record it in the audit log.
+package actor
+
+import "context"
+
+type contextKey struct{}
+
+// Actor is the caller of a request. ID is a user, API key or OAuth client ID,
+// and is empty until the caller is authenticated.
+type Actor struct {
+	ID        string
+	Role      string
+	PartnerID string
+	RequestID string
+	IP        string
+}
+
+// WithRequest returns a copy of ctx for the request with the given ID, sent
+// from ip.
+func WithRequest(ctx context.Context, requestID, ip string) context.Context {
+	return context.WithValue(ctx, contextKey{}, Actor{RequestID: requestID, IP: ip})
+}
+
+// WithCaller returns a copy of ctx whose request was made by the given
+// caller, keeping the request ID and IP.
+func WithCaller(ctx context.Context, id, role, partnerID string) context.Context {
+	a := FromContext(ctx)
+	a.ID, a.Role, a.PartnerID = id, role, partnerID
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// FromContext returns the actor of ctx, which is empty if none was set.
+func FromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(contextKey{}).(Actor)
+	return a
+}
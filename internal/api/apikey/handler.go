@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/apikey"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler provides HTTP handlers for partner API key management
+type Handler struct {
+	service apikey.Service
+}
+
+// NewHandler creates a new API key handler
+func NewHandler(service apikey.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// Issue creates an API key for a partner. The secret key is only included in
+// this response.
+func (h *Handler) Issue(c *gin.Context) {
+	var input apikey.IssueKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.service.Issue(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue API key", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, output)
+}
+
+// List returns API keys without their secrets, optionally filtered by
+// ?partner_id
+func (h *Handler) List(c *gin.Context) {
+	var partnerID *uuid.UUID
+	if partnerIDStr := c.Query("partner_id"); partnerIDStr != "" {
+		id, err := uuid.Parse(partnerIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner_id format"})
+			return
+		}
+		partnerID = &id
+	}
+
+	keys, err := h.service.List(c.Request.Context(), partnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// Rotate issues a replacement for an API key and retires the old one
+func (h *Handler) Rotate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var input apikey.RotateKeyInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	output, err := h.service.Rotate(c.Request.Context(), id, input)
+	if err != nil {
+		h.keyError(c, "Failed to rotate API key", err)
+		return
+	}
+	c.JSON(http.StatusCreated, output)
+}
+
+// Revoke stops an API key from being accepted
+func (h *Handler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), id); err != nil {
+		h.keyError(c, "Failed to revoke API key", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked", "id": id})
+}
+
+func (h *Handler) keyError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, apikey.ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+	case errors.Is(err, apikey.ErrKeyInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": message, "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler provides HTTP handlers for reading the audit log
+type Handler struct {
+	service audit.Service
+}
+
+// NewHandler creates a new audit log handler
+func NewHandler(service audit.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// List returns audit entries, newest first, filtered by ?partner_id,
+// ?actor_id, ?action, ?entity_type, ?entity_id, and ?since and ?until as
+// RFC 3339 times. ?before continues from the next_before of a previous page.
+func (h *Handler) List(c *gin.Context) {
+	input := audit.ListInput{
+		ActorID:    c.Query("actor_id"),
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+	}
+	if partnerIDStr := c.Query("partner_id"); partnerIDStr != "" {
+		id, err := uuid.Parse(partnerIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner_id format"})
+			return
+		}
+		input.PartnerID = &id
+	}
+	for name, target := range map[string]**time.Time{"since": &input.Since, "until": &input.Until} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " format; use RFC 3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+	if before := c.Query("before"); before != "" {
+		n, err := strconv.ParseInt(before, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before format"})
+			return
+		}
+		input.Before = n
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit format"})
+			return
+		}
+		input.Limit = n
+	}
+
+	page, err := h.service.List(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit entries", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Verify checks that the audit log's hash chain is intact
+func (h *Handler) Verify(c *gin.Context) {
+	output, err := h.service.Verify(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
@@ -1,11 +1,13 @@
 package auth
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/Cassandra-Labs-Foundation/core/internal/service/auth"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/jwt"
 )
 
 // Handler provides authentication HTTP handlers
@@ -26,9 +28,47 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ChangePasswordRequest represents the password change request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// RefreshRequest represents the token refresh request body
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents the logout request body. The refresh token is
+// optional; if given, it and every token refreshed from the same login are
+// revoked.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MFAChallengeRequest represents the second step of logging in with MFA
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest represents a request confirmed with a TOTP code. Disabling
+// MFA also accepts a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
 // TokenResponse represents the token response
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MFARequiredResponse is the login response of users with MFA enabled. The
+// MFA token is exchanged for a TokenResponse at the MFA challenge endpoint.
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
 }
 
 // Login handles the login request
@@ -39,42 +79,133 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 	
-	token, err := h.service.Login(req.Username, req.Password)
+	tokens, err := h.service.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		credentialsError(c, err)
+		return
+	}
+	if tokens.MFAToken != "" {
+		c.JSON(http.StatusOK, MFARequiredResponse{MFARequired: true, MFAToken: tokens.MFAToken})
 		return
 	}
 	
-	c.JSON(http.StatusOK, TokenResponse{Token: token})
+	c.JSON(http.StatusOK, TokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
 }
 
-// RefreshToken handles the token refresh request
-func (h *Handler) RefreshToken(c *gin.Context) {
-	// Get the Authorization header
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+// MFAChallenge handles the second step of logging in for users with MFA
+// enabled, taking the MFA token from the login and a TOTP or recovery code
+func (h *Handler) MFAChallenge(c *gin.Context) {
+	var req MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	tokens, err := h.service.CompleteMFA(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFAToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+			return
+		}
+		mfaError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, TokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
+
+// EnrollMFA handles starting MFA enrollment for the caller. The secret is
+// added to an authenticator app and confirmed with ActivateMFA.
+func (h *Handler) EnrollMFA(c *gin.Context) {
+	enrollment, err := h.service.EnrollMFA(c.Request.Context(), c.GetString("userID"))
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ActivateMFA handles enabling MFA for the caller with a code from their
+// authenticator app. The recovery codes are only shown in this response.
+func (h *Handler) ActivateMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	
-	// Check that the Authorization header has the format "Bearer {token}"
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+	codes, err := h.service.ActivateMFA(c.Request.Context(), c.GetString("userID"), req.Code)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"message": "MFA enabled", "recovery_codes": codes})
+}
+
+// DisableMFA handles the caller turning MFA off
+func (h *Handler) DisableMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	
-	// Extract the token
-	tokenString := parts[1]
+	if err := h.service.DisableMFA(c.Request.Context(), c.GetString("userID"), req.Code); err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "MFA disabled"})
+}
+
+// RefreshToken handles the token refresh request. The refresh token is
+// spent; the response carries the next one.
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	
-	// Refresh the token
-	newToken, err := h.service.RefreshToken(tokenString)
+	tokens, err := h.service.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		credentialsError(c, err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, TokenResponse{Token: newToken})
+	c.JSON(http.StatusOK, TokenResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+}
+
+// Logout handles revoking the caller's token, and their refresh token if given
+func (h *Handler) Logout(c *gin.Context) {
+	claims, ok := c.Get("claims")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only user tokens can be logged out"})
+		return
+	}
+	
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	
+	if err := h.service.Logout(c.Request.Context(), claims.(*jwt.Claims), req.RefreshToken); err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
 
 // ValidateToken handles the token validation request
@@ -89,4 +220,61 @@ func (h *Handler) ValidateToken(c *gin.Context) {
 		"userID": userID,
 		"role":   role,
 	})
+}
+
+// JWKS serves the public keys tokens are signed with, so that partners can
+// verify our tokens themselves
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.service.JWKS())
+}
+
+// ChangePassword handles a user changing their own password
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	err := h.service.ChangePassword(c.Request.Context(), c.GetString("userID"), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		credentialsError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// credentialsError responds to a failed password check. Locked out users get
+// 423 so they know to wait rather than retry.
+func credentialsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	case errors.Is(err, auth.ErrAccountLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": "Account is temporarily locked after too many failed attempts"})
+	default:
+		log.Printf("Error checking credentials: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check credentials", "details": err.Error()})
+	}
+}
+
+// mfaError responds to a failed MFA request. Wrong codes count towards the
+// same lockout as wrong passwords.
+func mfaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrMFANotEnrolled), errors.Is(err, auth.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		credentialsError(c, err)
+	}
 }
\ No newline at end of file
@@ -1,11 +1,14 @@
 package ledger
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
 	"github.com/Cassandra-Labs-Foundation/core/internal/service/ledger"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type Handler struct {
@@ -18,39 +21,489 @@ func NewHandler(service ledger.Service) *Handler {
 	}
 }
 
-// CreateAccountHandler handles account creation.
+// CreateAccountHandler opens an account for a KYC-verified person or business.
+// A positive initial balance is funded by an opening deposit from the default
+// settlement account.
 func (h *Handler) CreateAccountHandler(c *gin.Context) {
-	// Accept initialBalance as query parameter (default to 0)
-	balanceStr := c.DefaultQuery("balance", "0")
-	balance, err := strconv.ParseInt(balanceStr, 10, 64)
+	var input ledger.CreateAccountInput
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	output, err := h.service.CreateAccount(c.Request.Context(), input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid balance parameter"})
+		if fieldError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, ledger.ErrInvalidAccount), errors.Is(err, ledger.ErrInvalidAmount),
+			errors.Is(err, ledger.ErrUnsupportedCurrency):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ledger.ErrOwnerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account owner not found"})
+		case errors.Is(err, ledger.ErrOwnerNotVerified):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account", "details": err.Error()})
+		}
 		return
 	}
+	c.JSON(http.StatusCreated, output)
+}
+
+// ListPersonAccountsHandler returns the accounts owned by a person entity.
+func (h *Handler) ListPersonAccountsHandler(c *gin.Context) {
+	h.listOwnerAccounts(c, repository.OwnerTypePerson)
+}
+
+// ListBusinessAccountsHandler returns the accounts owned by a business entity.
+func (h *Handler) ListBusinessAccountsHandler(c *gin.Context) {
+	h.listOwnerAccounts(c, repository.OwnerTypeBusiness)
+}
 
-	accountID, err := h.service.CreateAccount(c.Request.Context(), balance)
+func (h *Handler) listOwnerAccounts(c *gin.Context, ownerType string) {
+	ownerID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"account_id": accountID})
+
+	accounts, err := h.service.ListOwnerAccounts(c.Request.Context(), ownerType, ownerID)
+	if err != nil {
+		if errors.Is(err, ledger.ErrOwnerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account owner not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list accounts", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
 }
 
-// TransferHandler handles fund transfers between accounts.
+// TransferRequest represents the body of a transfer between two accounts of
+// the same currency.
+type TransferRequest struct {
+	FromAccountID string `json:"from_account_id" binding:"required,uuid"`
+	ToAccountID   string `json:"to_account_id" binding:"required,uuid"`
+	Amount        string `json:"amount" binding:"required"`
+	Currency      string `json:"currency" binding:"required"`
+	Code          uint16 `json:"code"`
+	ledger.TransferDetailsInput
+	ledger.UserDataInput
+}
+
+// TransferHandler handles fund transfers between accounts of the same currency.
 func (h *Handler) TransferHandler(c *gin.Context) {
-	from := c.Query("from")
-	to := c.Query("to")
-	amountStr := c.Query("amount")
-	amount, err := strconv.ParseInt(amountStr, 10, 64)
-	if err != nil || amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount parameter"})
+	var req TransferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	amount, err := parseMoney("", req.Amount, req.Currency)
+	if err != nil {
+		fieldError(c, err)
+		return
+	}
+	input := ledger.TransferInput{
+		FromAccountID:        req.FromAccountID,
+		ToAccountID:          req.ToAccountID,
+		Amount:               amount,
+		Code:                 req.Code,
+		TransferDetailsInput: req.TransferDetailsInput,
+		UserDataInput:        req.UserDataInput,
+	}
+
+	transferID, err := h.service.TransferFunds(c.Request.Context(), input)
+	if err != nil {
+		h.transferError(c, "Failed to transfer funds", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer successful", "transfer_id": transferID})
+}
+
+// SettlementTransferRequest represents the body of a deposit or a withdrawal.
+type SettlementTransferRequest struct {
+	AccountID         string `json:"account_id" binding:"required,uuid"`
+	Amount            string `json:"amount" binding:"required"`
+	Currency          string `json:"currency" binding:"required"`
+	SettlementAccount string `json:"settlement_account"`
+	ledger.TransferDetailsInput
+}
+
+// bindSettlementTransfer decodes the body of a deposit or a withdrawal. If it
+// is invalid, it responds with 400 and returns false.
+func bindSettlementTransfer(c *gin.Context) (ledger.SettlementTransferInput, bool) {
+	var req SettlementTransferRequest
+	if !bindJSON(c, &req) {
+		return ledger.SettlementTransferInput{}, false
+	}
+	amount, err := parseMoney("", req.Amount, req.Currency)
+	if err != nil {
+		fieldError(c, err)
+		return ledger.SettlementTransferInput{}, false
+	}
+	return ledger.SettlementTransferInput{
+		AccountID:            req.AccountID,
+		Amount:               amount,
+		SettlementAccount:    req.SettlementAccount,
+		TransferDetailsInput: req.TransferDetailsInput,
+	}, true
+}
+
+// DepositHandler credits an account with money received into a settlement account.
+func (h *Handler) DepositHandler(c *gin.Context) {
+	input, ok := bindSettlementTransfer(c)
+	if !ok {
+		return
+	}
+
+	transferID, err := h.service.Deposit(c.Request.Context(), input)
+	if err != nil {
+		h.transferError(c, "Failed to deposit funds", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Deposit successful", "transfer_id": transferID})
+}
+
+// WithdrawalHandler debits an account for money paid out of a settlement account.
+func (h *Handler) WithdrawalHandler(c *gin.Context) {
+	input, ok := bindSettlementTransfer(c)
+	if !ok {
+		return
+	}
+
+	transferID, err := h.service.Withdraw(c.Request.Context(), input)
+	if err != nil {
+		h.transferError(c, "Failed to withdraw funds", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Withdrawal successful", "transfer_id": transferID})
+}
+
+func (h *Handler) transferError(c *gin.Context, message string, err error) {
+	if fieldError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ledger.ErrInvalidAmount), errors.Is(err, ledger.ErrUnknownSettlementAccount),
+		errors.Is(err, ledger.ErrUnsupportedCurrency), errors.Is(err, ledger.ErrInvalidExchange),
+		errors.Is(err, ledger.ErrSameAccount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ledger.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	case errors.Is(err, ledger.ErrInsufficientFunds), errors.Is(err, ledger.ErrCurrencyMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": message, "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
+
+// TransferLegRequest represents one transfer of a batch.
+type TransferLegRequest struct {
+	FromAccountID string `json:"from_account_id" binding:"required,uuid"`
+	ToAccountID   string `json:"to_account_id" binding:"required,uuid"`
+	Amount        string `json:"amount" binding:"required"`
+	Currency      string `json:"currency" binding:"required"`
+	Code          uint16 `json:"code"`
+	ledger.TransferDetailsInput
+	ledger.UserDataInput
+}
+
+// BatchTransferRequest represents the body of an atomic transfer batch.
+type BatchTransferRequest struct {
+	Transfers []TransferLegRequest `json:"transfers" binding:"required,dive"`
+}
+
+// TransferBatchHandler posts an ordered list of transfers atomically:
+// either every transfer is posted or none is.
+func (h *Handler) TransferBatchHandler(c *gin.Context) {
+	var req BatchTransferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	input := ledger.BatchTransferInput{Transfers: make([]ledger.TransferLegInput, len(req.Transfers))}
+	for i, leg := range req.Transfers {
+		amount, err := parseMoney(fmt.Sprintf("transfers[%d].", i), leg.Amount, leg.Currency)
+		if err != nil {
+			fieldError(c, err)
+			return
+		}
+		input.Transfers[i] = ledger.TransferLegInput{
+			FromAccountID:        leg.FromAccountID,
+			ToAccountID:          leg.ToAccountID,
+			Amount:               amount,
+			Code:                 leg.Code,
+			TransferDetailsInput: leg.TransferDetailsInput,
+			UserDataInput:        leg.UserDataInput,
+		}
+	}
+
+	transferIDs, err := h.service.TransferBatch(c.Request.Context(), input)
+	if err != nil {
+		if fieldError(c, err) {
+			return
+		}
+		if errors.Is(err, ledger.ErrInvalidBatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var rejected *ledger.BatchRejectedError
+		if errors.As(err, &rejected) {
+			response := gin.H{
+				"error":        "Transfer batch rejected; no transfers were posted",
+				"failed_index": rejected.Index,
+				"result":       rejected.Result,
+			}
+			if rejected.Field != nil {
+				response["fields"] = []*ledger.FieldError{rejected.Field}
+			}
+			c.JSON(http.StatusUnprocessableEntity, response)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post transfer batch", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Transfer batch successful", "transfer_ids": transferIDs})
+}
+
+// ExchangeRequest represents the body of a currency exchange.
+type ExchangeRequest struct {
+	FromAccountID       string `json:"from_account_id" binding:"required,uuid"`
+	ToAccountID         string `json:"to_account_id" binding:"required,uuid"`
+	SourceAmount        string `json:"source_amount" binding:"required"`
+	SourceCurrency      string `json:"source_currency" binding:"required"`
+	DestinationAmount   string `json:"destination_amount" binding:"required"`
+	DestinationCurrency string `json:"destination_currency" binding:"required"`
+	ledger.TransferDetailsInput
+	ledger.UserDataInput
+}
+
+// ExchangeHandler moves money between accounts of different currencies at
+// the amounts given for each side. Both transfers are posted or neither is.
+func (h *Handler) ExchangeHandler(c *gin.Context) {
+	var req ExchangeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	source, err := parseMoney("source_", req.SourceAmount, req.SourceCurrency)
+	if err != nil {
+		fieldError(c, err)
+		return
+	}
+	destination, err := parseMoney("destination_", req.DestinationAmount, req.DestinationCurrency)
+	if err != nil {
+		fieldError(c, err)
+		return
+	}
+	input := ledger.ExchangeInput{
+		FromAccountID:        req.FromAccountID,
+		ToAccountID:          req.ToAccountID,
+		SourceAmount:         source,
+		DestinationAmount:    destination,
+		TransferDetailsInput: req.TransferDetailsInput,
+		UserDataInput:        req.UserDataInput,
+	}
+
+	transferIDs, err := h.service.ExchangeFunds(c.Request.Context(), input)
+	if err != nil {
+		h.transferError(c, "Failed to exchange funds", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Exchange successful", "transfer_ids": transferIDs})
+}
+
+// PendingTransferRequest represents the body of a hold.
+type PendingTransferRequest struct {
+	FromAccountID  string `json:"from_account_id" binding:"required,uuid"`
+	ToAccountID    string `json:"to_account_id" binding:"required,uuid"`
+	Amount         string `json:"amount" binding:"required"`
+	Currency       string `json:"currency" binding:"required"`
+	Code           uint16 `json:"code"`
+	TimeoutSeconds uint32 `json:"timeout_seconds"`
+	ledger.TransferDetailsInput
+	ledger.UserDataInput
+}
+
+// CreatePendingTransferHandler places a hold (the first phase of a two-phase
+// transfer). The hold is later settled or released by its transfer ID.
+func (h *Handler) CreatePendingTransferHandler(c *gin.Context) {
+	var req PendingTransferRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	amount, err := parseMoney("", req.Amount, req.Currency)
+	if err != nil {
+		fieldError(c, err)
+		return
+	}
+	input := ledger.PendingTransferInput{
+		FromAccountID:        req.FromAccountID,
+		ToAccountID:          req.ToAccountID,
+		Amount:               amount,
+		Code:                 req.Code,
+		TimeoutSeconds:       req.TimeoutSeconds,
+		TransferDetailsInput: req.TransferDetailsInput,
+		UserDataInput:        req.UserDataInput,
+	}
+	transferID, err := h.service.CreatePendingTransfer(c.Request.Context(), input)
+	if err != nil {
+		h.transferError(c, "Failed to create pending transfer", err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Pending transfer created", "transfer_id": transferID})
+}
+
+// PostPendingTransferHandler settles all or part of a pending transfer.
+func (h *Handler) PostPendingTransferHandler(c *gin.Context) {
+	pendingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	// An empty body posts the full pending amount
+	var input ledger.PostPendingTransferInput
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &input) {
+			return
+		}
+	}
+
+	transferID, err := h.service.PostPendingTransfer(c.Request.Context(), pendingID, input)
+	if err != nil {
+		h.pendingTransferError(c, "Failed to post pending transfer", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pending transfer posted", "transfer_id": transferID, "pending_id": pendingID})
+}
+
+// VoidPendingTransferHandler releases a pending transfer without moving funds.
+func (h *Handler) VoidPendingTransferHandler(c *gin.Context) {
+	pendingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	transferID, err := h.service.VoidPendingTransfer(c.Request.Context(), pendingID)
+	if err != nil {
+		h.pendingTransferError(c, "Failed to void pending transfer", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Pending transfer voided", "transfer_id": transferID, "pending_id": pendingID})
+}
+
+func (h *Handler) pendingTransferError(c *gin.Context, message string, err error) {
+	if fieldError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ledger.ErrInvalidAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ledger.ErrPendingTransferNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending transfer not found"})
+	case errors.Is(err, ledger.ErrPendingTransferClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": message, "details": err.Error()})
+	case errors.Is(err, ledger.ErrExceedsPendingAmount), errors.Is(err, ledger.ErrCurrencyMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": message, "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
+
+// GetAccountHandler returns a ledger account and its balances.
+func (h *Handler) GetAccountHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	output, err := h.service.GetAccount(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ledger.ErrAccountNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// LookupAccountsRequest represents the body of a batch account lookup.
+type LookupAccountsRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+// LookupAccountsHandler returns several ledger accounts at once.
+// IDs that do not match an account are reported in not_found.
+func (h *Handler) LookupAccountsHandler(c *gin.Context) {
+	var req LookupAccountsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	for i, idStr := range req.IDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format", "id": idStr})
+			return
+		}
+		ids[i] = id
+	}
+
+	outputs, err := h.service.GetAccounts(c.Request.Context(), ids)
+	if err != nil {
+		if errors.Is(err, ledger.ErrTooManyAccounts) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "max": ledger.MaxLookupAccounts})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up accounts", "details": err.Error()})
+		return
+	}
+
+	found := make(map[uuid.UUID]bool, len(outputs))
+	for _, output := range outputs {
+		found[output.ID] = true
+	}
+	notFound := []uuid.UUID{}
+	for _, id := range ids {
+		if !found[id] {
+			notFound = append(notFound, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": outputs, "not_found": notFound})
+}
+
+// ListTransfersHandler returns a page of an account's transaction history.
+// Pass the next_cursor of a response as cursor to fetch the following page.
+func (h *Handler) ListTransfersHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var input ledger.ListTransfersInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.service.TransferFunds(c.Request.Context(), from, to, amount)
+	page, err := h.service.ListTransfers(c.Request.Context(), id, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer funds", "details": err.Error()})
+		if errors.Is(err, ledger.ErrInvalidTransferFilter) || errors.Is(err, ledger.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ledger.ErrAccountNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list transfers", "details": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Transfer successful"})
+	c.JSON(http.StatusOK, page)
 }
\ No newline at end of file
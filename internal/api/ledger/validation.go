@@ -0,0 +1,135 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/money"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/ledger"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var registerJSONNames sync.Once
+
+// bindJSON decodes and validates the JSON body of a request into obj. If the
+// body is invalid, it responds with 400 and one error per offending field,
+// named as in the JSON body, and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	registerJSONNames.Do(func() {
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			v.RegisterTagNameFunc(jsonFieldName)
+		}
+	})
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var fields []*ledger.FieldError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, &ledger.FieldError{
+				Field:   fieldPath(fieldErr.Namespace()),
+				Message: validationMessage(fieldErr),
+			})
+		}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		fields = append(fields, &ledger.FieldError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be a %s", typeErr.Type),
+		})
+	case errors.Is(err, io.EOF):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body is required"})
+		return false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body", "details": err.Error()})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "fields": fields})
+	return false
+}
+
+// fieldError responds to an error attributed to a request field, with 422 if
+// the field names a record that does not exist and 400 otherwise. It returns
+// false if err is not attributed to a field.
+func fieldError(c *gin.Context, err error) bool {
+	var fieldErr *ledger.FieldError
+	if !errors.As(err, &fieldErr) {
+		return false
+	}
+	status := http.StatusBadRequest
+	if errors.Is(err, ledger.ErrAccountNotFound) || errors.Is(err, ledger.ErrOwnerNotFound) ||
+		errors.Is(err, ledger.ErrCurrencyMismatch) {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{"error": "Invalid request", "fields": []*ledger.FieldError{fieldErr}})
+	return true
+}
+
+// parseMoney converts the decimal amount and currency code of a request into
+// money. Errors name the request fields, prefixed with prefix.
+func parseMoney(prefix, amount, currency string) (money.Money, error) {
+	m, err := money.Parse(amount, currency)
+	switch {
+	case errors.Is(err, money.ErrUnknownCurrency):
+		err = fmt.Errorf("%w: %q", ledger.ErrUnsupportedCurrency, currency)
+		return m, &ledger.FieldError{Field: prefix + "currency", Message: err.Error(), Err: err}
+	case err != nil:
+		return m, &ledger.FieldError{Field: prefix + "amount", Message: err.Error(), Err: err}
+	}
+	return m, nil
+}
+
+// jsonFieldName names struct fields by their JSON keys in validation errors.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" && !field.Anonymous {
+		return field.Name
+	}
+	return name
+}
+
+// fieldPath turns a validator namespace such as
+// "BatchTransferInput.transfers[0].TransferDetailsInput.description" into the
+// path of the field in the JSON body, "transfers[0].description". Embedded
+// structs have no JSON name and are skipped.
+func fieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	path := parts[:0]
+	for _, part := range parts[1:] {
+		if part == "" || (part[0] >= 'A' && part[0] <= 'Z') {
+			continue
+		}
+		path = append(path, part)
+	}
+	return strings.Join(path, ".")
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "uuid":
+		return "must be a UUID"
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	}
+	return fmt.Sprintf("failed the %q check", fieldErr.Tag())
+}
@@ -1,16 +1,34 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	
 	"github.com/gin-gonic/gin"
+	"github.com/Cassandra-Labs-Foundation/core/internal/rbac"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/apikey"
 	"github.com/Cassandra-Labs-Foundation/core/internal/service/auth"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/jwt"
 )
 
-// AuthMiddleware creates a gin middleware for authentication
-func AuthMiddleware(authService auth.Service) gin.HandlerFunc {
+// APIKeyHeader carries a partner API key as an alternative to the Authorization header
+const APIKeyHeader = "X-API-Key"
+
+// PartnerRole is the role of requests authenticated with a partner API key
+const PartnerRole = "partner"
+
+// AuthMiddleware creates a gin middleware for authentication.
+// Requests authenticate with a JWT, issued at login or to an OAuth client, or
+// a partner API key, sent as a Bearer token or in the X-API-Key header.
+func AuthMiddleware(authService auth.Service, keyService apikey.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			authenticateKey(c, keyService, key)
+			return
+		}
+
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -29,20 +47,133 @@ func AuthMiddleware(authService auth.Service) gin.HandlerFunc {
 		
 		// Extract the token
 		tokenString := parts[1]
+		if apikey.IsKey(tokenString) {
+			authenticateKey(c, keyService, tokenString)
+			return
+		}
 		
 		// Validate the token
-		userID, role, err := authService.ValidateToken(tokenString)
+		claims, err := authService.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 		
-		// Set the userID and role in the context for later use
-		c.Set("userID", userID)
-		c.Set("role", role)
+		// Set the userID, role and partner in the context for later use, and
+		// the claims for logging out
+		c.Set("claims", claims)
+		c.Set("userID", claims.UserID)
+		c.Set("role", claims.Role)
+		if claims.PartnerID != "" {
+			c.Set("partnerID", claims.PartnerID)
+		}
+		// Tokens issued to OAuth clients are limited to their scopes, like API keys
+		if claims.ClientID != "" {
+			c.Set("scopes", strings.Fields(claims.Scope))
+		}
 		
 		// Continue to the next middleware/handler
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// authenticateKey authenticates a request by a partner API key. The key's
+// partner and scopes are set in the context alongside its ID as the userID.
+func authenticateKey(c *gin.Context, keyService apikey.Service, key string) {
+	output, err := keyService.Authenticate(c.Request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, apikey.ErrInvalidKey) {
+			log.Printf("Error authenticating API key: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", output.ID.String())
+	c.Set("role", PartnerRole)
+	c.Set("partnerID", output.PartnerID.String())
+	c.Set("scopes", output.Scopes)
+	c.Next()
+}
+
+// RequireScope requires requests authenticated with an API key or an OAuth
+// client's token to hold the required scope, such as "ledger:read". Requests
+// authenticated with a user's JWT are not restricted.
+func RequireScope(required string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		for _, scope := range scopes {
+			if scope == required {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Credentials lack the required scope", "required_scope": required})
+		c.Abort()
+	}
+}
+
+// RequireRevealPermission allows requests that ask with ?reveal= for data
+// that is masked by default only if their role is granted rbac.PIIReveal
+func RequireRevealPermission(policy rbac.Policy) gin.HandlerFunc {
+	require := RequirePermission(policy, rbac.PIIReveal)
+	return func(c *gin.Context) {
+		if c.Query("reveal") == "" {
+			c.Next()
+			return
+		}
+		require(c)
+	}
+}
+
+// RequirePermission allows only requests whose role is granted permission by
+// policy. Denials are logged with the caller.
+func RequirePermission(policy rbac.Policy, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if policy.Allows(role, permission) {
+			c.Next()
+			return
+		}
+		log.Printf("Permission denied: user=%s role=%s permission=%s %s %s",
+			c.GetString("userID"), role, permission, c.Request.Method, c.Request.URL.Path)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_permission": permission})
+		c.Abort()
+	}
+}
+
+// RequireMFA requires users with one of roles to have logged in with a second
+// factor. Users who haven't enabled MFA yet can still reach the auth routes to
+// enroll. Requests authenticated with an API key or an OAuth client's token
+// are not users and are not restricted.
+func RequireMFA(roles []string) gin.HandlerFunc {
+	required := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		required[role] = true
+	}
+	return func(c *gin.Context) {
+		value, ok := c.Get("claims")
+		claims, _ := value.(*jwt.Claims)
+		if !ok || claims == nil || claims.ClientID != "" || !required[claims.Role] {
+			c.Next()
+			return
+		}
+		for _, method := range claims.AMR {
+			if method == auth.AMRMFA {
+				c.Next()
+				return
+			}
+		}
+		log.Printf("MFA required: user=%s role=%s %s %s", claims.UserID, claims.Role, c.Request.Method, c.Request.URL.Path)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Multi-factor authentication required"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/idempotency"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header that carries the idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the length of an idempotency key.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyRecord is what is remembered about a request sent with an
+// idempotency key. Response is nil while the request is still in progress.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Response    *IdempotentResponse
+}
+
+// IdempotentResponse is a response stored for replay.
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore remembers requests by idempotency key.
+type IdempotencyStore interface {
+	// Reserve claims key for a request with the given fingerprint until ttl
+	// has passed. If the key is already claimed it returns the existing
+	// record and false.
+	Reserve(key, fingerprint string, ttl time.Duration) (IdempotencyRecord, bool)
+	// Complete stores the response of the request that reserved key.
+	Complete(key string, response IdempotentResponse)
+	// Release forgets key so that the request can be retried.
+	Release(key string)
+}
+
+// IdempotencyMiddleware makes mutating requests that carry an Idempotency-Key
+// header safe to retry. The first request with a key is processed and its
+// response stored for ttl; a retry with the same key and body replays that
+// response, and the same key with a different body is rejected with 409.
+// Keys are scoped to the authenticated caller and the partner acted for, so
+// it must run after AuthMiddleware and, where there is one, TenantMiddleware.
+// It should be the last middleware before the handler: whatever rejects a
+// request before it leaves the key free for the fixed retry.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		partner := ""
+		if partnerID, ok := tenant.PartnerFromContext(c.Request.Context()); ok {
+			partner = partnerID.String()
+		}
+		scopedKey := c.GetString("userID") + ":" + partner + ":" + key
+		fingerprint := requestFingerprint(c.Request, body)
+
+		record, reserved := store.Reserve(scopedKey, fingerprint, ttl)
+		if !reserved {
+			switch {
+			case record.Fingerprint != fingerprint:
+				c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key has already been used for a different request"})
+			case record.Response == nil:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.Response.Status, record.Response.ContentType, record.Response.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Request = c.Request.WithContext(idempotency.WithKey(c.Request.Context(), scopedKey))
+
+		c.Next()
+
+		// Server errors may be transient and rate limited requests are meant
+		// to be retried, so neither is replayed.
+		if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusTooManyRequests {
+			store.Release(scopedKey)
+			return
+		}
+		store.Complete(scopedKey, IdempotentResponse{
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
+
+// isMutating reports whether requests with method change state.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint identifies a request by its method, target and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// memoryIdempotencyStore is an IdempotencyStore held in process memory.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyRecord
+	// expiry holds records in the order they were reserved. The TTL is the
+	// same for every request, so that is also the order they expire in.
+	expiry []*memoryIdempotencyRecord
+}
+
+type memoryIdempotencyRecord struct {
+	IdempotencyRecord
+	key       string
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore creates an IdempotencyStore held in process
+// memory. Keys are not shared between server instances and are lost on restart.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		records: make(map[string]*memoryIdempotencyRecord),
+	}
+}
+
+// Reserve claims key. Expired records are discarded from the front of the
+// expiry order, so each request only looks at the records that expired since
+// the last one.
+func (s *memoryIdempotencyStore) Reserve(key, fingerprint string, ttl time.Duration) (IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.expiry) > 0 && now.After(s.expiry[0].expiresAt) {
+		expired := s.expiry[0]
+		s.expiry[0] = nil
+		s.expiry = s.expiry[1:]
+		// The key may have been released and reserved again since
+		if s.records[expired.key] == expired {
+			delete(s.records, expired.key)
+		}
+	}
+
+	// A record with a longer TTL than those reserved after it can hold
+	// them back from being discarded, so expiry is checked here as well
+	if record, ok := s.records[key]; ok && !now.After(record.expiresAt) {
+		return record.IdempotencyRecord, false
+	}
+	record := &memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint},
+		key:               key,
+		expiresAt:         now.Add(ttl),
+	}
+	s.records[key] = record
+	s.expiry = append(s.expiry, record)
+	return IdempotencyRecord{}, true
+}
+
+// Complete stores the response for key.
+func (s *memoryIdempotencyStore) Complete(key string, response IdempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok {
+		record.Response = &response
+	} else {
+		log.Printf("Idempotency key expired before its response was stored")
+	}
+}
+
+// Release forgets key.
+func (s *memoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+}
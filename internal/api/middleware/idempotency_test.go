@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// idempotentRequest is a request to the test router. status is the status
+// the handler responds with if it runs.
+type idempotentRequest struct {
+	method  string
+	key     string
+	user    string
+	partner string
+	body    string
+	status  int
+}
+
+// idempotentResponse is what a test request should get
+type idempotentResponse struct {
+	status   int
+	replayed bool
+}
+
+// newIdempotencyRouter routes every request through IdempotencyMiddleware to
+// a handler that counts its calls. The caller and partner come from test
+// headers, as AuthMiddleware and TenantMiddleware would set them.
+func newIdempotencyRouter(calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+		if partner := c.GetHeader("X-Test-Partner"); partner != "" {
+			c.Request = c.Request.WithContext(tenant.WithPartner(c.Request.Context(), uuid.MustParse(partner)))
+		}
+	})
+	r.Use(IdempotencyMiddleware(NewMemoryIdempotencyStore(), time.Hour))
+	r.Any("/resource", func(c *gin.Context) {
+		*calls++
+		status, _ := strconv.Atoi(c.GetHeader("X-Test-Status"))
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return r
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	partnerA, partnerB := uuid.NewString(), uuid.NewString()
+	post := func(key, body string, status int) idempotentRequest {
+		return idempotentRequest{method: http.MethodPost, key: key, user: "u1", partner: partnerA, body: body, status: status}
+	}
+	tests := []struct {
+		name      string
+		requests  []idempotentRequest
+		want      []idempotentResponse
+		wantCalls int
+	}{
+		{
+			name:      "retry is replayed",
+			requests:  []idempotentRequest{post("k", `{"a":1}`, 201), post("k", `{"a":1}`, 201)},
+			want:      []idempotentResponse{{201, false}, {201, true}},
+			wantCalls: 1,
+		},
+		{
+			name:      "different body is a conflict",
+			requests:  []idempotentRequest{post("k", `{"a":1}`, 201), post("k", `{"a":2}`, 201)},
+			want:      []idempotentResponse{{201, false}, {409, false}},
+			wantCalls: 1,
+		},
+		{
+			name:      "no key",
+			requests:  []idempotentRequest{post("", `{"a":1}`, 201), post("", `{"a":1}`, 201)},
+			want:      []idempotentResponse{{201, false}, {201, false}},
+			wantCalls: 2,
+		},
+		{
+			name:      "client error is replayed",
+			requests:  []idempotentRequest{post("k", `{}`, 422), post("k", `{}`, 201)},
+			want:      []idempotentResponse{{422, false}, {422, true}},
+			wantCalls: 1,
+		},
+		{
+			name:      "server error releases the key",
+			requests:  []idempotentRequest{post("k", `{"a":1}`, 500), post("k", `{"a":1}`, 201)},
+			want:      []idempotentResponse{{500, false}, {201, false}},
+			wantCalls: 2,
+		},
+		{
+			name:      "rate limited request releases the key",
+			requests:  []idempotentRequest{post("k", `{"a":1}`, 429), post("k", `{"a":1}`, 201)},
+			want:      []idempotentResponse{{429, false}, {201, false}},
+			wantCalls: 2,
+		},
+		{
+			name: "keys are scoped to the caller",
+			requests: []idempotentRequest{
+				post("k", `{"a":1}`, 201),
+				{method: http.MethodPost, key: "k", user: "u2", partner: partnerA, body: `{"a":1}`, status: 201},
+			},
+			want:      []idempotentResponse{{201, false}, {201, false}},
+			wantCalls: 2,
+		},
+		{
+			name: "keys are scoped to the partner",
+			requests: []idempotentRequest{
+				post("k", `{"a":1}`, 201),
+				{method: http.MethodPost, key: "k", user: "u1", partner: partnerB, body: `{"a":1}`, status: 201},
+			},
+			want:      []idempotentResponse{{201, false}, {201, false}},
+			wantCalls: 2,
+		},
+		{
+			name: "reads are not affected",
+			requests: []idempotentRequest{
+				{method: http.MethodGet, key: "k", user: "u1", status: 200},
+				{method: http.MethodGet, key: "k", user: "u1", status: 200},
+			},
+			want:      []idempotentResponse{{200, false}, {200, false}},
+			wantCalls: 2,
+		},
+		{
+			name:      "key too long",
+			requests:  []idempotentRequest{post(strings.Repeat("k", 256), `{}`, 201)},
+			want:      []idempotentResponse{{400, false}},
+			wantCalls: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			r := newIdempotencyRouter(&calls)
+			var first string
+			for i, req := range tt.requests {
+				httpReq := httptest.NewRequest(req.method, "/resource", strings.NewReader(req.body))
+				httpReq.Header.Set("X-Test-User", req.user)
+				httpReq.Header.Set("X-Test-Partner", req.partner)
+				httpReq.Header.Set("X-Test-Status", strconv.Itoa(req.status))
+				if req.key != "" {
+					httpReq.Header.Set(IdempotencyKeyHeader, req.key)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httpReq)
+
+				replayed := w.Header().Get("Idempotent-Replayed") == "true"
+				if w.Code != tt.want[i].status || replayed != tt.want[i].replayed {
+					t.Errorf("request %d: status %d, replayed %v; want %d, %v", i, w.Code, replayed, tt.want[i].status, tt.want[i].replayed)
+				}
+				if i == 0 {
+					first = w.Body.String()
+				} else if replayed && w.Body.String() != first {
+					t.Errorf("request %d: replayed body %s, want %s", i, w.Body.String(), first)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	store := NewMemoryIdempotencyStore().(*memoryIdempotencyStore)
+	store.Reserve("expires", "f", ttl)
+	store.Reserve("released", "f", ttl)
+	store.Release("released")
+	store.Reserve("released", "f", time.Hour)
+	store.Reserve("kept", "f", time.Hour)
+
+	time.Sleep(2 * ttl)
+	if _, reserved := store.Reserve("new", "f", time.Hour); !reserved {
+		t.Fatal("new key was not reserved")
+	}
+	if _, ok := store.records["expires"]; ok {
+		t.Error("expired record was not discarded")
+	}
+	if len(store.expiry) != 3 {
+		t.Errorf("%d records waiting to expire, want 3", len(store.expiry))
+	}
+	for key, want := range map[string]bool{"expires": true, "released": false, "kept": false} {
+		if _, reserved := store.Reserve(key, "f", time.Hour); reserved != want {
+			t.Errorf("reserve %q after the first TTL = %v, want %v", key, reserved, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitResult is the outcome of taking a request from a bucket.
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is how many more requests the bucket allows right now
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, if this one
+	// was not
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds the token buckets of rate limited callers.
+type RateLimitStore interface {
+	// Take takes a request from the bucket of key, which holds up to
+	// limit.Requests and refills at limit.Requests per limit.Period.
+	Take(key string, limit ratelimit.Limit) RateLimitResult
+}
+
+// RateLimitMiddleware limits the requests of each caller to a route group
+// with a token bucket, sized by the limit of the caller's partner plan in
+// policy. Callers are told by API key or user when authenticated, so it must
+// run after AuthMiddleware on protected routes, and by IP otherwise. Every
+// response carries RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers; requests over the limit get 429 with Retry-After.
+func RateLimitMiddleware(policy ratelimit.Policy, store RateLimitStore, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := policy.Limit(group, c.GetString("partnerID"))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// API keys, users and OAuth clients are all identified by userID
+		caller := "ip:" + c.ClientIP()
+		if userID := c.GetString("userID"); userID != "" {
+			caller = "caller:" + userID
+		}
+		result := store.Take(group+":"+caller, limit)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+		if !result.Allowed {
+			log.Printf("Rate limit exceeded: group=%s %s %s %s", group, caller, c.Request.Method, c.Request.URL.Path)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, as the headers need.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimitSweepInterval is how often idle buckets are discarded.
+const rateLimitSweepInterval = time.Minute
+
+// memoryRateLimitStore is a RateLimitStore held in process memory.
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled, after which it is the same
+	// as no bucket and can be discarded
+	full time.Time
+}
+
+// NewMemoryRateLimitStore creates a RateLimitStore held in process memory.
+// Buckets are not shared between server instances, so each instance allows
+// the full limit, and are lost on restart.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Take refills the bucket of key for the time since it was last used and
+// takes a request from it if it holds one.
+func (s *memoryRateLimitStore) Take(key string, limit ratelimit.Limit) RateLimitResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > rateLimitSweepInterval {
+		for k, bucket := range s.buckets {
+			if now.After(bucket.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Period / time.Duration(limit.Requests)
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.updated))/float64(perToken))
+	bucket.updated = now
+
+	result := RateLimitResult{Allowed: bucket.tokens >= 1}
+	if result.Allowed {
+		bucket.tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) * float64(perToken))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((capacity - bucket.tokens) * float64(perToken))
+	bucket.full = now.Add(result.Reset)
+	return result
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	// One request is earned back every 50ms
+	limit := ratelimit.Limit{Requests: 3, Period: 150 * time.Millisecond}
+	tests := []struct {
+		name string
+		// wait is slept before each take
+		wait          []time.Duration
+		wantAllowed   []bool
+		wantRemaining []int
+	}{
+		{
+			name:          "burst up to the limit",
+			wait:          []time.Duration{0, 0, 0, 0},
+			wantAllowed:   []bool{true, true, true, false},
+			wantRemaining: []int{2, 1, 0, 0},
+		},
+		{
+			name:          "a request is earned back",
+			wait:          []time.Duration{0, 0, 0, 0, 60 * time.Millisecond, 0},
+			wantAllowed:   []bool{true, true, true, false, true, false},
+			wantRemaining: []int{2, 1, 0, 0, 0, 0},
+		},
+		{
+			name:          "refills no further than the limit",
+			wait:          []time.Duration{0, 400 * time.Millisecond, 0, 0, 0},
+			wantAllowed:   []bool{true, true, true, true, false},
+			wantRemaining: []int{2, 2, 1, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRateLimitStore()
+			for i, wait := range tt.wait {
+				time.Sleep(wait)
+				result := store.Take("key", limit)
+				if result.Allowed != tt.wantAllowed[i] || result.Remaining != tt.wantRemaining[i] {
+					t.Fatalf("take %d: allowed %v, remaining %d; want %v, %d",
+						i, result.Allowed, result.Remaining, tt.wantAllowed[i], tt.wantRemaining[i])
+				}
+				if result.Reset <= 0 || result.Reset > limit.Period {
+					t.Errorf("take %d: reset %v, want within (0, %v]", i, result.Reset, limit.Period)
+				}
+				if !result.Allowed && (result.RetryAfter <= 0 || result.RetryAfter > limit.Period/3) {
+					t.Errorf("take %d: retry after %v, want within (0, %v]", i, result.RetryAfter, limit.Period/3)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreKeys(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	if !store.Take("a", limit).Allowed || store.Take("a", limit).Allowed {
+		t.Fatal("bucket a did not allow exactly one request")
+	}
+	if !store.Take("b", limit).Allowed {
+		t.Error("bucket b was limited by bucket a")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	policy := ratelimit.Policy{
+		Limits: map[string]map[string]ratelimit.Limit{
+			ratelimit.Ledger: {
+				ratelimit.DefaultPlan: {Requests: 1, Period: time.Minute},
+				"premium":             {Requests: 2, Period: time.Minute},
+			},
+		},
+		Plans: map[string]string{"partner-premium": "premium"},
+	}
+	// caller is the userID and partnerID of a request, or empty for an
+	// unauthenticated one
+	type caller struct{ userID, partnerID string }
+	tests := []struct {
+		name       string
+		group      string
+		callers    []caller
+		wantStatus []int
+	}{
+		{
+			name:       "default plan",
+			group:      ratelimit.Ledger,
+			callers:    []caller{{"u1", "p1"}, {"u1", "p1"}},
+			wantStatus: []int{200, 429},
+		},
+		{
+			name:       "partner plan",
+			group:      ratelimit.Ledger,
+			callers:    []caller{{"u1", "partner-premium"}, {"u1", "partner-premium"}, {"u1", "partner-premium"}},
+			wantStatus: []int{200, 200, 429},
+		},
+		{
+			name:       "callers have their own buckets",
+			group:      ratelimit.Ledger,
+			callers:    []caller{{"u1", "p1"}, {"u2", "p1"}, {"u1", "p1"}},
+			wantStatus: []int{200, 200, 429},
+		},
+		{
+			name:       "unauthenticated callers share their IP's bucket",
+			group:      ratelimit.Ledger,
+			callers:    []caller{{}, {}},
+			wantStatus: []int{200, 429},
+		},
+		{
+			name:       "group without limits",
+			group:      ratelimit.Entities,
+			callers:    []caller{{"u1", "p1"}, {"u1", "p1"}},
+			wantStatus: []int{200, 200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if userID := c.GetHeader("X-Test-User"); userID != "" {
+					c.Set("userID", userID)
+					c.Set("partnerID", c.GetHeader("X-Test-Partner"))
+				}
+			})
+			r.Use(RateLimitMiddleware(policy, NewMemoryRateLimitStore(), tt.group))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i, caller := range tt.callers {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Test-User", caller.userID)
+				req.Header.Set("X-Test-Partner", caller.partnerID)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code != tt.wantStatus[i] {
+					t.Fatalf("request %d: status %d, want %d", i, w.Code, tt.wantStatus[i])
+				}
+				limited := tt.group == ratelimit.Ledger
+				if got := w.Header().Get("RateLimit-Limit") != ""; got != limited {
+					t.Errorf("request %d: RateLimit-Limit header present = %v, want %v", i, got, limited)
+				}
+				if got := w.Header().Get("Retry-After") != ""; got != (w.Code == http.StatusTooManyRequests) {
+					t.Errorf("request %d: Retry-After header present = %v on a %d", i, got, w.Code)
+				}
+			}
+		})
+	}
+}
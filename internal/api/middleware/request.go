@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/Cassandra-Labs-Foundation/core/internal/actor"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request, chosen by the client or
+// generated, and is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of a client's request ID.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware gives every request an ID, the client's own if it sent
+// a usable one, and records it and the client's IP for the audit log.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set("requestID", requestID)
+		c.Request = c.Request.WithContext(actor.WithRequest(c.Request.Context(), requestID, c.ClientIP()))
+		c.Next()
+	}
+}
+
+// ActorMiddleware records the authenticated caller for the audit log. It must
+// run after AuthMiddleware.
+func ActorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := actor.WithCaller(c.Request.Context(), c.GetString("userID"), c.GetString("role"), c.GetString("partnerID"))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client's request ID is short and made of
+// characters that are safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
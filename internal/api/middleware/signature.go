@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/signing"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/signature"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxNonceLength bounds the length of a request signature nonce.
+const maxNonceLength = 128
+
+// NonceStore remembers the nonces of signed requests so that they can't be
+// replayed.
+type NonceStore interface {
+	// Use records nonce until ttl has passed. It returns false if nonce was
+	// already recorded.
+	Use(nonce string, ttl time.Duration) bool
+}
+
+// SignatureMiddleware verifies HMAC request signatures made with the
+// partner's signing keys, as described in package signature. Requests whose
+// timestamp is more than window away from now, or that reuse a nonce, are
+// rejected. Signing is optional except for callers of the required partners,
+// whose unsigned requests are rejected; a signature that is sent is always
+// checked. It must run after TenantMiddleware.
+func SignatureMiddleware(service signing.Service, nonces NonceStore, window time.Duration, requiredPartners []uuid.UUID) gin.HandlerFunc {
+	required := make(map[string]bool, len(requiredPartners))
+	for _, partnerID := range requiredPartners {
+		required[partnerID.String()] = true
+	}
+	return func(c *gin.Context) {
+		keyID := c.GetHeader(signature.KeyIDHeader)
+		timestampStr := c.GetHeader(signature.TimestampHeader)
+		nonce := c.GetHeader(signature.NonceHeader)
+		sig := c.GetHeader(signature.SignatureHeader)
+		if keyID == "" && timestampStr == "" && nonce == "" && sig == "" {
+			if required[c.GetString("partnerID")] {
+				signatureError(c, "Request signature required")
+				return
+			}
+			c.Next()
+			return
+		}
+		if keyID == "" || timestampStr == "" || nonce == "" || sig == "" {
+			signatureError(c, "Signed requests must set the "+signature.KeyIDHeader+", "+signature.TimestampHeader+", "+
+				signature.NonceHeader+" and "+signature.SignatureHeader+" headers")
+			return
+		}
+		if len(nonce) > maxNonceLength {
+			signatureError(c, signature.NonceHeader+" must be at most 128 characters")
+			return
+		}
+		timestamp, ok := signature.ParseTimestamp(timestampStr)
+		if !ok {
+			signatureError(c, signature.TimestampHeader+" must be a Unix time in seconds")
+			return
+		}
+		if skew := time.Since(timestamp); skew > window || skew < -window {
+			signatureError(c, "Request timestamp is outside the allowed window")
+			return
+		}
+		partnerID, ok := tenant.PartnerFromContext(c.Request.Context())
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Request is not associated with a partner"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = service.Verify(c.Request.Context(), partnerID, keyID, signature.Request{
+			Method:    c.Request.Method,
+			URI:       c.Request.URL.RequestURI(),
+			Timestamp: timestamp,
+			Nonce:     nonce,
+			Body:      body,
+		}, sig)
+		if err != nil {
+			if errors.Is(err, signing.ErrInvalidSignature) {
+				log.Printf("Invalid request signature: key=%s partner=%s %s %s", keyID, partnerID, c.Request.Method, c.Request.URL.Path)
+				signatureError(c, "Invalid request signature")
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify request signature", "details": err.Error()})
+			c.Abort()
+			return
+		}
+
+		// Only checked once the signature is known to be good, so that forged
+		// requests can't use up a partner's nonces. Timestamps older than
+		// window are rejected above, so nonces need not be kept any longer.
+		if !nonces.Use(keyID+":"+nonce, 2*window) {
+			log.Printf("Replayed request signature: key=%s partner=%s %s %s", keyID, partnerID, c.Request.Method, c.Request.URL.Path)
+			signatureError(c, "Request nonce has already been used")
+			return
+		}
+		c.Set("signingKeyID", keyID)
+		c.Next()
+	}
+}
+
+func signatureError(c *gin.Context, message string) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
+// memoryNonceStore is a NonceStore held in process memory.
+type memoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewMemoryNonceStore creates a NonceStore held in process memory. Nonces are
+// not shared between server instances and are lost on restart.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Use records nonce, discarding expired nonces first.
+func (s *memoryNonceStore) Use(nonce string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for n, expiresAt := range s.nonces {
+		if now.After(expiresAt) {
+			delete(s.nonces, n)
+		}
+	}
+
+	if _, ok := s.nonces[nonce]; ok {
+		return false
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/rbac"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PartnerHeader names the partner a staff member acts for. Callers whose
+// credentials carry a partner cannot use it to act for another one.
+const PartnerHeader = "X-Partner-ID"
+
+// TenantMiddleware limits the request to the records of the caller's partner,
+// taken from their API key or token. Callers without a partner must be granted
+// rbac.TenantsAll and name the partner they act for in the X-Partner-ID header.
+func TenantMiddleware(policy rbac.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		partner := c.GetString("partnerID")
+		if partner == "" && policy.Allows(c.GetString("role"), rbac.TenantsAll) {
+			partner = c.GetHeader(PartnerHeader)
+		}
+		if partner == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Request is not associated with a partner; staff must set the " + PartnerHeader + " header"})
+			c.Abort()
+			return
+		}
+
+		partnerID, err := uuid.Parse(partner)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner ID format"})
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.WithPartner(c.Request.Context(), partnerID))
+		c.Next()
+	}
+}
+
+// CrossTenantMiddleware lets the request reach the records of every partner,
+// or of the one given in the partner_id query parameter. It must only be used
+// behind RequirePermission(policy, rbac.TenantsAll).
+func CrossTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tenant.WithAllPartners(c.Request.Context())
+		if partner := c.Query("partner_id"); partner != "" {
+			partnerID, err := uuid.Parse(partner)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner ID format"})
+				c.Abort()
+				return
+			}
+			ctx = tenant.WithPartner(c.Request.Context(), partnerID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,183 @@
+package oauth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler provides the OAuth2 token and introspection endpoints, and HTTP
+// handlers for OAuth client management
+type Handler struct {
+	service oauth.Service
+}
+
+// NewHandler creates a new OAuth handler
+func NewHandler(service oauth.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// Token handles the token endpoint of RFC 6749. Only the client_credentials
+// grant is supported. Clients authenticate with HTTP Basic auth or with
+// client_id and client_secret form parameters.
+func (h *Handler) Token(c *gin.Context) {
+	noStore(c)
+	clientID, clientSecret, ok := clientCredentials(c)
+	if !ok {
+		tokenError(c, http.StatusBadRequest, "invalid_request", "client credentials are required")
+		return
+	}
+	if grantType := c.PostForm("grant_type"); grantType != "client_credentials" {
+		if grantType == "" {
+			tokenError(c, http.StatusBadRequest, "invalid_request", "grant_type is required")
+			return
+		}
+		tokenError(c, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+		return
+	}
+
+	output, err := h.service.IssueToken(c.Request.Context(), clientID, clientSecret, c.PostForm("scope"))
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// Introspect handles token introspection as in RFC 7662. The caller
+// authenticates like at the token endpoint.
+func (h *Handler) Introspect(c *gin.Context) {
+	noStore(c)
+	clientID, clientSecret, ok := clientCredentials(c)
+	if !ok {
+		tokenError(c, http.StatusUnauthorized, "invalid_client", "client credentials are required")
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		tokenError(c, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	output, err := h.service.Introspect(c.Request.Context(), clientID, clientSecret, token)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// Register creates an OAuth client for a partner. The client secret is only
+// included in this response.
+func (h *Handler) Register(c *gin.Context) {
+	var input oauth.RegisterClientInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.service.Register(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register OAuth client", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, output)
+}
+
+// List returns OAuth clients without their secrets, optionally filtered by
+// ?partner_id
+func (h *Handler) List(c *gin.Context) {
+	var partnerID *uuid.UUID
+	if partnerIDStr := c.Query("partner_id"); partnerIDStr != "" {
+		id, err := uuid.Parse(partnerIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner_id format"})
+			return
+		}
+		partnerID = &id
+	}
+
+	clients, err := h.service.List(c.Request.Context(), partnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list OAuth clients", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"oauth_clients": clients})
+}
+
+// Revoke stops an OAuth client from getting new tokens
+func (h *Handler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrClientNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "OAuth client not found"})
+		case errors.Is(err, oauth.ErrClientRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to revoke OAuth client", "details": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke OAuth client", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "OAuth client revoked", "id": id})
+}
+
+// clientCredentials returns the client ID and secret from the Authorization
+// header or, failing that, the form. Basic auth credentials are form-encoded
+// as RFC 6749 section 2.3.1 requires.
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		id, idErr := url.QueryUnescape(id)
+		secret, secretErr := url.QueryUnescape(secret)
+		return id, secret, idErr == nil && secretErr == nil && id != ""
+	}
+	id, secret := c.PostForm("client_id"), c.PostForm("client_secret")
+	return id, secret, id != ""
+}
+
+// oauthError maps service errors to the error responses of RFC 6749
+// section 5.2
+func oauthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		tokenError(c, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	case errors.Is(err, oauth.ErrInvalidScope):
+		tokenError(c, http.StatusBadRequest, "invalid_scope", err.Error())
+	default:
+		log.Printf("Error handling OAuth request: %v", err)
+		tokenError(c, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+func tokenError(c *gin.Context, status int, code, description string) {
+	body := gin.H{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	c.JSON(status, body)
+}
+
+// noStore keeps tokens out of caches, as RFC 6749 section 5.1 requires
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+}
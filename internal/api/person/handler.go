@@ -64,6 +64,7 @@ func (h *Handler) Create(c *gin.Context) {
 // @Tags entities
 // @Produce json
 // @Param id path string true "Person ID"
+// @Param reveal query string false "ssn to show the full SSN, which needs the pii:reveal permission"
 // @Success 200 {object} person.PersonOutput
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -76,7 +77,16 @@ func (h *Handler) Get(c *gin.Context) {
 		return
 	}
 
-	output, err := h.service.GetByID(c.Request.Context(), id)
+	var output *person.PersonOutput
+	switch c.Query("reveal") {
+	case "":
+		output, err = h.service.GetByID(c.Request.Context(), id)
+	case "ssn":
+		output, err = h.service.RevealSSN(c.Request.Context(), id)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reveal; only ssn can be revealed"})
+		return
+	}
 	if err != nil {
 		if errors.Is(err, person.ErrPersonNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Person entity not found"})
@@ -164,4 +174,40 @@ func (h *Handler) List(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, outputs)
-}
\ No newline at end of file
+}
+// LookupRequest is the body of a lookup by SSN, which is posted rather than
+// put in the URL so that it doesn't end up in access logs
+type LookupRequest struct {
+	SSN string `json:"ssn" binding:"required"`
+}
+
+// Lookup handles finding person entities by SSN
+// @Summary Look up person entities by SSN
+// @Description Find the person entities with an SSN, ignoring dashes and spaces
+// @Tags entities
+// @Accept json
+// @Produce json
+// @Param input body LookupRequest true "SSN to look up"
+// @Success 200 {array} person.PersonOutput
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/entities/person/lookup [post]
+func (h *Handler) Lookup(c *gin.Context) {
+	var req LookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	outputs, err := h.service.FindBySSN(c.Request.Context(), req.SSN)
+	if err != nil {
+		if errors.Is(err, person.ErrInvalidPerson) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up person entities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
@@ -0,0 +1,87 @@
+package signing
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/signing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler provides HTTP handlers for managing request signing keys
+type Handler struct {
+	service signing.Service
+}
+
+// NewHandler creates a new signing key handler
+func NewHandler(service signing.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// Issue creates a signing key for a partner. The secret is only included in
+// this response.
+func (h *Handler) Issue(c *gin.Context) {
+	var input signing.IssueKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.service.Issue(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, signing.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue signing key", "details": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, output)
+}
+
+// List returns signing keys without their secrets, optionally filtered by
+// ?partner_id
+func (h *Handler) List(c *gin.Context) {
+	var partnerID *uuid.UUID
+	if partnerIDStr := c.Query("partner_id"); partnerIDStr != "" {
+		id, err := uuid.Parse(partnerIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner_id format"})
+			return
+		}
+		partnerID = &id
+	}
+
+	keys, err := h.service.List(c.Request.Context(), partnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list signing keys", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"signing_keys": keys})
+}
+
+// Revoke stops a signing key from being accepted
+func (h *Handler) Revoke(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, signing.ErrKeyNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Signing key not found"})
+		case errors.Is(err, signing.ErrKeyRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to revoke signing key", "details": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke signing key", "details": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signing key revoked", "id": id})
+}
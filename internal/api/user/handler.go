@@ -0,0 +1,160 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/user"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler provides HTTP handlers for user management
+type Handler struct {
+	service user.Service
+}
+
+// NewHandler creates a new user handler
+func NewHandler(service user.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// Create handles the creation of a new user
+func (h *Handler) Create(c *gin.Context) {
+	var input user.CreateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.service.Create(c.Request.Context(), input)
+	if err != nil {
+		userError(c, "Failed to create user", err)
+		return
+	}
+	c.JSON(http.StatusCreated, output)
+}
+
+// Get handles retrieving a user by ID
+func (h *Handler) Get(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		userError(c, "Failed to get user", err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// List handles retrieving a paginated list of users
+func (h *Handler) List(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		limit = 10
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		offset = 0
+	}
+
+	outputs, err := h.service.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, outputs)
+}
+
+// Update handles changing a user's role or resetting their password
+func (h *Handler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var input user.UpdateUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.service.Update(c.Request.Context(), id, input)
+	if err != nil {
+		userError(c, "Failed to update user", err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// Delete handles removing a user
+func (h *Handler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		userError(c, "Failed to delete user", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted", "id": id})
+}
+
+// Unlock handles lifting a lockout caused by failed logins
+func (h *Handler) Unlock(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.service.Unlock(c.Request.Context(), id)
+	if err != nil {
+		userError(c, "Failed to unlock user", err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// ResetMFA handles turning MFA off for a user who can no longer pass it
+func (h *Handler) ResetMFA(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.service.ResetMFA(c.Request.Context(), id)
+	if err != nil {
+		userError(c, "Failed to reset MFA", err)
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+func parseID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func userError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, user.ErrInvalidUser):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, user.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, user.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
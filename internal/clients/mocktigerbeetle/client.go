@@ -0,0 +1,97 @@
+// Package mocktigerbeetle is a ledger backend that talks to the
+// cmd/mock-tigerbeetle server over HTTP. Several processes can share the mock's
+// ledger, and its state survives restarts of the API server.
+package mocktigerbeetle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+)
+
+// Client is a ledger backend served by the mock TigerBeetle server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new client for the mock TigerBeetle server at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// CreateAccounts creates a batch of accounts.
+// Only the accounts that failed are reported.
+func (c *Client) CreateAccounts(ctx context.Context, accounts []ledger.Account) ([]ledger.AccountEventResult, error) {
+	var results []ledger.AccountEventResult
+	err := c.post(ctx, "/accounts/create", accounts, &results)
+	return results, err
+}
+
+// CreateTransfers creates a batch of transfers.
+// Only the transfers that failed are reported.
+func (c *Client) CreateTransfers(ctx context.Context, transfers []ledger.Transfer) ([]ledger.TransferEventResult, error) {
+	var results []ledger.TransferEventResult
+	err := c.post(ctx, "/transfers/create", transfers, &results)
+	return results, err
+}
+
+// LookupAccounts returns the accounts that exist among ids.
+func (c *Client) LookupAccounts(ctx context.Context, ids []uuid.UUID) ([]ledger.Account, error) {
+	var accounts []ledger.Account
+	err := c.post(ctx, "/accounts/lookup", ids, &accounts)
+	return accounts, err
+}
+
+// LookupTransfers returns the transfers that exist among ids.
+func (c *Client) LookupTransfers(ctx context.Context, ids []uuid.UUID) ([]ledger.Transfer, error) {
+	var transfers []ledger.Transfer
+	err := c.post(ctx, "/transfers/lookup", ids, &transfers)
+	return transfers, err
+}
+
+// GetAccountTransfers returns the transfers that touched an account, subject to filter.
+func (c *Client) GetAccountTransfers(ctx context.Context, filter ledger.AccountFilter) ([]ledger.Transfer, error) {
+	var transfers []ledger.Transfer
+	err := c.post(ctx, "/accounts/transfers", filter, &transfers)
+	return transfers, err
+}
+
+// post sends body as JSON to path and decodes the JSON response into out.
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("mock tigerbeetle: %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -9,6 +9,8 @@ import (
 	"log" // Add this import
 	"net/http"
 	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/pkg/redact"
 )
 
 // Client provides methods to interact with the Supabase REST API
@@ -42,7 +44,6 @@ func (c *Client) request(ctx context.Context, method, path string, queryParams m
             return nil, fmt.Errorf("error marshaling request body: %w", err)
         }
         reqBody = bytes.NewBuffer(jsonBody)
-        log.Printf("Request body: %s", string(jsonBody))
     }
 
 	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
@@ -80,7 +81,7 @@ func (c *Client) request(ctx context.Context, method, path string, queryParams m
 
 	// Check if the response is successful
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("supabase API error: %s, status code: %d", string(respBody), resp.StatusCode)
+		return nil, fmt.Errorf("supabase API error: %s, status code: %d", redact.String(string(respBody)), resp.StatusCode)
 	}
 
 	return respBody, nil
@@ -112,6 +113,11 @@ func (c *Client) Update(ctx context.Context, table, id string, data interface{})
 	return c.request(ctx, http.MethodPatch, "/rest/v1/"+table, queryParams, data)
 }
 
+// UpdateWhere updates the records in the specified table that match queryParams
+func (c *Client) UpdateWhere(ctx context.Context, table string, queryParams map[string]string, data interface{}) ([]byte, error) {
+	return c.request(ctx, http.MethodPatch, "/rest/v1/"+table, queryParams, data)
+}
+
 // Delete deletes a record from the specified table
 func (c *Client) Delete(ctx context.Context, table, id string) ([]byte, error) {
 	queryParams := map[string]string{
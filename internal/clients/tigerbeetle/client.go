@@ -3,32 +3,139 @@ package tigerbeetle
 import (
 	"context"
 	"fmt"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+	tb "github.com/tigerbeetle/tigerbeetle-go"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
 )
 
-// Client represents a TigerBeetle client.
+// Client wraps the TigerBeetle Go SDK and translates between the ledger
+// package types and the SDK types.
 type Client struct {
-	Endpoint string
+	tb tb.Client
 }
 
-// NewClient creates a new TigerBeetle client instance.
-func NewClient(endpoint string) *Client {
+// NewClient creates a new TigerBeetle client connected to the given cluster.
+func NewClient(clusterID uint64, addresses []string) (*Client, error) {
+	client, err := tb.NewClient(types.ToUint128(clusterID), addresses)
+	if err != nil {
+		return nil, fmt.Errorf("error creating tigerbeetle client: %w", err)
+	}
 	return &Client{
-		Endpoint: endpoint,
+		tb: client,
+	}, nil
+}
+
+// Close releases the resources held by the underlying SDK client.
+func (c *Client) Close() {
+	c.tb.Close()
+}
+
+// CreateAccounts creates a batch of accounts.
+// As with TigerBeetle, only the accounts that failed are reported.
+func (c *Client) CreateAccounts(ctx context.Context, accounts []ledger.Account) ([]ledger.AccountEventResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	batch := make([]types.Account, len(accounts))
+	for i, account := range accounts {
+		batch[i] = toAccount(account)
+	}
+
+	results, err := c.tb.CreateAccounts(batch)
+	if err != nil {
+		return nil, fmt.Errorf("error creating tigerbeetle accounts: %w", err)
 	}
+
+	out := make([]ledger.AccountEventResult, len(results))
+	for i, result := range results {
+		out[i] = ledger.AccountEventResult{
+			Index:  int(result.Index),
+			Result: ledger.CreateAccountResult(result.Result),
+		}
+	}
+	return out, nil
 }
 
-// CreateAccount simulates creating a new account in TigerBeetle.
-func (c *Client) CreateAccount(ctx context.Context, accountID string, initialBalance int64) error {
-	// In a real implementation, you would send an HTTP POST to c.Endpoint+"/account"
-	// For now, we'll simulate it by printing the action and assuming success.
-	fmt.Printf("Creating TigerBeetle account: ID=%s, Balance=%d\n", accountID, initialBalance)
-	// Actual HTTP request code would go here.
-	return nil
+// CreateTransfers creates a batch of transfers.
+// As with TigerBeetle, only the transfers that failed are reported.
+func (c *Client) CreateTransfers(ctx context.Context, transfers []ledger.Transfer) ([]ledger.TransferEventResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	batch := make([]types.Transfer, len(transfers))
+	for i, transfer := range transfers {
+		batch[i] = toTransfer(transfer)
+	}
+
+	results, err := c.tb.CreateTransfers(batch)
+	if err != nil {
+		return nil, fmt.Errorf("error creating tigerbeetle transfers: %w", err)
+	}
+
+	out := make([]ledger.TransferEventResult, len(results))
+	for i, result := range results {
+		out[i] = ledger.TransferEventResult{
+			Index:  int(result.Index),
+			Result: ledger.CreateTransferResult(result.Result),
+		}
+	}
+	return out, nil
 }
 
-// Transfer simulates transferring funds between accounts.
-func (c *Client) Transfer(ctx context.Context, fromAccountID, toAccountID string, amount int64) error {
-	fmt.Printf("Transferring %d from %s to %s\n", amount, fromAccountID, toAccountID)
-	// Actual HTTP request code would go here.
-	return nil
-}
\ No newline at end of file
+// LookupAccounts returns the accounts that exist among ids.
+func (c *Client) LookupAccounts(ctx context.Context, ids []uuid.UUID) ([]ledger.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	accounts, err := c.tb.LookupAccounts(toIDs(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error looking up tigerbeetle accounts: %w", err)
+	}
+
+	out := make([]ledger.Account, len(accounts))
+	for i, account := range accounts {
+		out[i] = fromAccount(account)
+	}
+	return out, nil
+}
+
+// LookupTransfers returns the transfers that exist among ids.
+func (c *Client) LookupTransfers(ctx context.Context, ids []uuid.UUID) ([]ledger.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	transfers, err := c.tb.LookupTransfers(toIDs(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error looking up tigerbeetle transfers: %w", err)
+	}
+
+	out := make([]ledger.Transfer, len(transfers))
+	for i, transfer := range transfers {
+		out[i] = fromTransfer(transfer)
+	}
+	return out, nil
+}
+
+// GetAccountTransfers returns the transfers that touched an account, subject to filter.
+func (c *Client) GetAccountTransfers(ctx context.Context, filter ledger.AccountFilter) ([]ledger.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	transfers, err := c.tb.GetAccountTransfers(toAccountFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("error getting tigerbeetle account transfers: %w", err)
+	}
+
+	out := make([]ledger.Transfer, len(transfers))
+	for i, transfer := range transfers {
+		out[i] = fromTransfer(transfer)
+	}
+	return out, nil
+}
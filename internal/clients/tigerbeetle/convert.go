@@ -0,0 +1,137 @@
+package tigerbeetle
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+	tb "github.com/tigerbeetle/tigerbeetle-go"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+func toIDs(ids []uuid.UUID) []types.Uint128 {
+	out := make([]types.Uint128, len(ids))
+	for i, id := range ids {
+		out[i] = ToUint128(id)
+	}
+	return out
+}
+
+// toUint64 narrows a TigerBeetle amount to the 64-bit amounts used by the
+// ledger package, saturating instead of silently wrapping.
+func toUint64(value types.Uint128) uint64 {
+	b := value.Bytes()
+	if binary.LittleEndian.Uint64(b[8:]) != 0 {
+		return math.MaxUint64
+	}
+	return binary.LittleEndian.Uint64(b[:8])
+}
+
+func toAccount(a ledger.Account) types.Account {
+	return types.Account{
+		ID:             ToUint128(a.ID),
+		DebitsPending:  types.ToUint128(a.DebitsPending),
+		DebitsPosted:   types.ToUint128(a.DebitsPosted),
+		CreditsPending: types.ToUint128(a.CreditsPending),
+		CreditsPosted:  types.ToUint128(a.CreditsPosted),
+		UserData128:    ToUint128(a.UserData128),
+		UserData64:     a.UserData64,
+		UserData32:     a.UserData32,
+		Ledger:         a.Ledger,
+		Code:           a.Code,
+		Flags: types.AccountFlags{
+			DebitsMustNotExceedCredits: a.Flags.DebitsMustNotExceedCredits,
+			CreditsMustNotExceedDebits: a.Flags.CreditsMustNotExceedDebits,
+			History:                    a.Flags.History,
+		}.ToUint16(),
+		Timestamp: a.Timestamp,
+	}
+}
+
+func fromAccount(a types.Account) ledger.Account {
+	flags := a.AccountFlags()
+	return ledger.Account{
+		ID:             FromUint128(a.ID),
+		DebitsPending:  toUint64(a.DebitsPending),
+		DebitsPosted:   toUint64(a.DebitsPosted),
+		CreditsPending: toUint64(a.CreditsPending),
+		CreditsPosted:  toUint64(a.CreditsPosted),
+		UserData128:    FromUint128(a.UserData128),
+		UserData64:     a.UserData64,
+		UserData32:     a.UserData32,
+		Ledger:         a.Ledger,
+		Code:           a.Code,
+		Flags: ledger.AccountFlags{
+			DebitsMustNotExceedCredits: flags.DebitsMustNotExceedCredits,
+			CreditsMustNotExceedDebits: flags.CreditsMustNotExceedDebits,
+			History:                    flags.History,
+		},
+		Timestamp: a.Timestamp,
+	}
+}
+
+func toTransfer(t ledger.Transfer) types.Transfer {
+	amount := types.ToUint128(t.Amount)
+	if t.Amount == ledger.AmountMax {
+		amount = tb.AmountMax
+	}
+	return types.Transfer{
+		ID:              ToUint128(t.ID),
+		DebitAccountID:  ToUint128(t.DebitAccountID),
+		CreditAccountID: ToUint128(t.CreditAccountID),
+		Amount:          amount,
+		PendingID:       ToUint128(t.PendingID),
+		UserData128:     ToUint128(t.UserData128),
+		UserData64:      t.UserData64,
+		UserData32:      t.UserData32,
+		Timeout:         t.Timeout,
+		Ledger:          t.Ledger,
+		Code:            t.Code,
+		Flags: types.TransferFlags{
+			Linked:              t.Flags.Linked,
+			Pending:             t.Flags.Pending,
+			PostPendingTransfer: t.Flags.PostPendingTransfer,
+			VoidPendingTransfer: t.Flags.VoidPendingTransfer,
+		}.ToUint16(),
+		Timestamp: t.Timestamp,
+	}
+}
+
+func fromTransfer(t types.Transfer) ledger.Transfer {
+	flags := t.TransferFlags()
+	return ledger.Transfer{
+		ID:              FromUint128(t.ID),
+		DebitAccountID:  FromUint128(t.DebitAccountID),
+		CreditAccountID: FromUint128(t.CreditAccountID),
+		Amount:          toUint64(t.Amount),
+		PendingID:       FromUint128(t.PendingID),
+		UserData128:     FromUint128(t.UserData128),
+		UserData64:      t.UserData64,
+		UserData32:      t.UserData32,
+		Timeout:         t.Timeout,
+		Ledger:          t.Ledger,
+		Code:            t.Code,
+		Flags: ledger.TransferFlags{
+			Linked:              flags.Linked,
+			Pending:             flags.Pending,
+			PostPendingTransfer: flags.PostPendingTransfer,
+			VoidPendingTransfer: flags.VoidPendingTransfer,
+		},
+		Timestamp: t.Timestamp,
+	}
+}
+
+func toAccountFilter(f ledger.AccountFilter) types.AccountFilter {
+	return types.AccountFilter{
+		AccountID:    ToUint128(f.AccountID),
+		TimestampMin: f.TimestampMin,
+		TimestampMax: f.TimestampMax,
+		Limit:        uint32(f.Limit),
+		Flags: types.AccountFilterFlags{
+			Debits:   f.Debits,
+			Credits:  f.Credits,
+			Reversed: f.Reversed,
+		}.ToUint32(),
+	}
+}
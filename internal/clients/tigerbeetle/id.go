@@ -0,0 +1,27 @@
+package tigerbeetle
+
+import (
+	"github.com/google/uuid"
+	"github.com/tigerbeetle/tigerbeetle-go/pkg/types"
+)
+
+// ToUint128 maps a UUID onto a TigerBeetle 128-bit ID.
+// TigerBeetle stores IDs little-endian, so the bytes are reversed to keep the
+// hex form printed by the TigerBeetle REPL identical to the UUID without dashes.
+func ToUint128(id uuid.UUID) types.Uint128 {
+	var b [16]byte
+	for i := range id {
+		b[i] = id[len(id)-1-i]
+	}
+	return types.BytesToUint128(b)
+}
+
+// FromUint128 is the inverse of ToUint128.
+func FromUint128(value types.Uint128) uuid.UUID {
+	b := value.Bytes()
+	var id uuid.UUID
+	for i := range b {
+		id[i] = b[len(b)-1-i]
+	}
+	return id
+}
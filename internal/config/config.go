@@ -3,15 +3,24 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	JWT      JWTConfig
-	Database DatabaseConfig
-	Supabase SupabaseConfig
+	Server      ServerConfig
+	JWT         JWTConfig
+	Auth        AuthConfig
+	RBAC        RBACConfig
+	Database    DatabaseConfig
+	Supabase    SupabaseConfig
+	Ledger      LedgerConfig
+	TigerBeetle TigerBeetleConfig
+	Idempotency IdempotencyConfig
+	Signing     SigningConfig
+	RateLimit   RateLimitConfig
+	Encryption  EncryptionConfig
 }
 
 // ServerConfig holds server related configuration
@@ -19,12 +28,47 @@ type ServerConfig struct {
 	Port         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// TrustedProxies are the addresses or CIDRs of proxies whose
+	// X-Forwarded-For is believed for the client IP. By default none are, so
+	// the client IP is the address the connection came from.
+	TrustedProxies []string
 }
 
 // JWTConfig holds JWT related configuration
 type JWTConfig struct {
-	Secret       string
-	ExpiryMinutes int
+	// PrivateKeyFile is a PEM encoded RSA or P-256 private key tokens are
+	// signed with. There is no default; the server refuses to start without it.
+	PrivateKeyFile string
+	// VerificationKeyFiles are PEM encoded keys of earlier signing keys whose
+	// tokens are still accepted while keys are rotated
+	VerificationKeyFiles []string
+	ExpiryMinutes        int
+}
+
+// AuthConfig holds login related configuration
+type AuthConfig struct {
+	// MaxFailedLogins is how many failed logins in a row lock a user out; 0 disables lockout
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+	// BootstrapAdminUsername and BootstrapAdminPassword create the first admin
+	// user on startup if no user has that username. No admin is created if the
+	// password is empty.
+	BootstrapAdminUsername string
+	BootstrapAdminPassword string
+	// RefreshTokenTTL is how long a refresh token can be used after it is issued
+	RefreshTokenTTL time.Duration
+	// MFARequiredRoles are the roles that must sign in with a second factor
+	// to use anything beyond the auth routes; "none" requires it of no role
+	MFARequiredRoles []string
+	// MFAIssuer names this service in authenticator apps
+	MFAIssuer string
+}
+
+// RBACConfig holds role-based access control related configuration
+type RBACConfig struct {
+	// PolicyFile is a JSON file mapping roles to permissions; the built-in
+	// policy is used if it is empty
+	PolicyFile string
 }
 
 // DatabaseConfig holds database related configuration
@@ -43,17 +87,86 @@ type SupabaseConfig struct {
 	APIKey string
 }
 
+// LedgerConfig holds ledger related configuration
+type LedgerConfig struct {
+	Backend string // "tigerbeetle", "memory" or "mock"
+	Code    uint16
+	// Currencies maps ISO 4217 currency codes to the ledgers that hold them
+	Currencies map[string]string
+	// MockURL is the address of the cmd/mock-tigerbeetle server used by the "mock" backend
+	MockURL string
+	// SettlementAccounts maps names such as "cash" or "fbo" to the IDs of the
+	// system accounts that fund deposits and receive withdrawals
+	SettlementAccounts       map[string]string
+	DefaultSettlementAccount string
+	// LiquidityAccount is the base ID of the system accounts that currency
+	// exchanges pass through
+	LiquidityAccount string
+}
+
+// TigerBeetleConfig holds TigerBeetle cluster related configuration
+type TigerBeetleConfig struct {
+	ClusterID uint64
+	Addresses []string
+}
+
+// IdempotencyConfig holds Idempotency-Key related configuration
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+// SigningConfig holds HMAC request signing related configuration
+type SigningConfig struct {
+	// Window is how far a signed request's timestamp may be from the server's clock
+	Window time.Duration
+	// RequiredPartners are the IDs of partners whose ledger requests must be signed
+	RequiredPartners []string
+}
+
+// RateLimitConfig holds rate limiting related configuration
+type RateLimitConfig struct {
+	// PolicyFile is a JSON file of the limits of each route group and partner
+	// plan; the built-in limits are used if it is empty
+	PolicyFile string
+}
+
+// EncryptionConfig holds field encryption related configuration
+type EncryptionConfig struct {
+	// Keys are base64 256-bit key-encryption keys by version. There is no
+	// default; the server refuses to start without them.
+	Keys map[string]string
+	// CurrentKey is the version of the key new values are encrypted with
+	CurrentKey string
+	// BlindIndexKey is the base64 256-bit key of the blind indexes that
+	// encrypted fields are looked up by
+	BlindIndexKey string
+}
+
 // Load returns configuration from environment variables
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
-			WriteTimeout: time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 10)) * time.Second,
+			Port:           getEnv("SERVER_PORT", "8080"),
+			ReadTimeout:    time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 10)) * time.Second,
+			WriteTimeout:   time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 10)) * time.Second,
+			TrustedProxies: getEnvAsSlice("SERVER_TRUSTED_PROXIES", nil),
 		},
 		JWT: JWTConfig{
-			Secret:       getEnv("JWT_SECRET", "your-secret-key"),
-			ExpiryMinutes: getEnvAsInt("JWT_EXPIRY_MINUTES", 60),
+			PrivateKeyFile:       getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			VerificationKeyFiles: getEnvAsSlice("JWT_VERIFICATION_KEY_FILES", nil),
+			ExpiryMinutes:        getEnvAsInt("JWT_EXPIRY_MINUTES", 60),
+		},
+		Auth: AuthConfig{
+			MaxFailedLogins:        getEnvAsInt("AUTH_MAX_FAILED_LOGINS", 5),
+			LockoutDuration:        time.Duration(getEnvAsInt("AUTH_LOCKOUT_MINUTES", 15)) * time.Minute,
+			BootstrapAdminUsername: getEnv("AUTH_BOOTSTRAP_ADMIN_USERNAME", "admin"),
+			BootstrapAdminPassword: getEnv("AUTH_BOOTSTRAP_ADMIN_PASSWORD", ""),
+			RefreshTokenTTL:        time.Duration(getEnvAsInt("AUTH_REFRESH_TOKEN_TTL_HOURS", 720)) * time.Hour,
+			MFARequiredRoles:       getEnvAsSlice("AUTH_MFA_REQUIRED_ROLES", []string{"admin"}),
+			MFAIssuer:              getEnv("AUTH_MFA_ISSUER", "Cassandra Core"),
+		},
+		RBAC: RBACConfig{
+			PolicyFile: getEnv("RBAC_POLICY_FILE", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -67,6 +180,38 @@ func Load() *Config {
 			URL:    getEnv("SUPABASE_URL", ""),
 			APIKey: getEnv("SUPABASE_API_KEY", ""),
 		},
+		Ledger: LedgerConfig{
+			Backend: getEnv("LEDGER_BACKEND", "tigerbeetle"),
+			Code:    uint16(getEnvAsInt("LEDGER_CODE", 1)),
+			Currencies: getEnvAsMap("LEDGER_CURRENCIES", map[string]string{
+				"USD": "1",
+			}),
+			MockURL: getEnv("MOCK_TIGERBEETLE_URL", "http://localhost:9000"),
+			SettlementAccounts: getEnvAsMap("LEDGER_SETTLEMENT_ACCOUNTS", map[string]string{
+				"cash": "00000000-0000-4000-8000-000000000001",
+			}),
+			DefaultSettlementAccount: getEnv("LEDGER_DEFAULT_SETTLEMENT_ACCOUNT", "cash"),
+			LiquidityAccount:         getEnv("LEDGER_FX_LIQUIDITY_ACCOUNT", "00000000-0000-4000-8000-000000000002"),
+		},
+		TigerBeetle: TigerBeetleConfig{
+			ClusterID: uint64(getEnvAsInt("TB_CLUSTER_ID", 0)),
+			Addresses: getEnvAsSlice("TB_ADDRESSES", []string{"3000"}),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: time.Duration(getEnvAsInt("IDEMPOTENCY_TTL_MINUTES", 24*60)) * time.Minute,
+		},
+		Signing: SigningConfig{
+			Window:           time.Duration(getEnvAsInt("SIGNING_WINDOW_SECONDS", 300)) * time.Second,
+			RequiredPartners: getEnvAsSlice("SIGNING_REQUIRED_PARTNERS", nil),
+		},
+		RateLimit: RateLimitConfig{
+			PolicyFile: getEnv("RATE_LIMIT_POLICY_FILE", ""),
+		},
+		Encryption: EncryptionConfig{
+			Keys:          getEnvAsMap("FIELD_ENCRYPTION_KEYS", nil),
+			CurrentKey:    getEnv("FIELD_ENCRYPTION_CURRENT_KEY", ""),
+			BlindIndexKey: getEnv("FIELD_BLIND_INDEX_KEY", ""),
+		},
 	}
 }
 
@@ -85,4 +230,38 @@ func getEnvAsInt(key string, defaultVal int) int {
 		return value
 	}
 	return defaultVal
-}
\ No newline at end of file
+}
+
+// Helper function to read a comma-separated environment variable as a slice with a default value
+func getEnvAsSlice(key string, defaultVal []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultVal
+	}
+	var values []string
+	for _, v := range strings.Split(valueStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return defaultVal
+	}
+	return values
+}
+
+// Helper function to read a comma-separated list of name=value pairs as a map with a default value
+func getEnvAsMap(key string, defaultVal map[string]string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getEnvAsSlice(key, nil) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if len(values) == 0 {
+		return defaultVal
+	}
+	return values
+}
@@ -0,0 +1,39 @@
+// Package idempotency carries the Idempotency-Key of a request through to the
+// code that creates records, so that a retried request creates records with
+// the same IDs as the original.
+package idempotency
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// namespace scopes the UUIDs derived from idempotency keys.
+var namespace = uuid.MustParse("6f1c2a9e-4d53-4b7e-9a0f-3c8e5d2b7a14")
+
+// WithKey returns a copy of ctx that carries the idempotency key of the
+// request. The key should already be scoped to the caller that sent it.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the idempotency key carried by ctx, if any.
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(contextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// NewID returns the ID for the n-th record of the given kind created by the
+// request. When ctx carries an idempotency key the ID is derived from it, so a
+// retry produces the same ID; otherwise a random ID is returned.
+func NewID(ctx context.Context, kind string, n int) uuid.UUID {
+	key, ok := KeyFromContext(ctx)
+	if !ok {
+		return uuid.New()
+	}
+	return uuid.NewSHA1(namespace, []byte(key+"\x00"+kind+"\x00"+strconv.Itoa(n)))
+}
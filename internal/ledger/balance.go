@@ -0,0 +1,55 @@
+package ledger
+
+// NormalBalance is the side of an account on which its balance increases.
+type NormalBalance string
+
+const (
+	// NormalCredit accounts, such as customer deposits, grow with credits.
+	NormalCredit NormalBalance = "credit"
+	// NormalDebit accounts, such as settlement cash, grow with debits.
+	NormalDebit NormalBalance = "debit"
+)
+
+// NormalBalance reports the side on which the account's balance increases.
+// Accounts that may not be credited beyond their debits are debit-normal;
+// every other account is treated as credit-normal.
+func (a Account) NormalBalance() NormalBalance {
+	if a.Flags.CreditsMustNotExceedDebits {
+		return NormalDebit
+	}
+	return NormalCredit
+}
+
+// PostedBalance is the settled balance on the account's normal side.
+// It is negative when an account without overdraft protection is overdrawn.
+func (a Account) PostedBalance() int64 {
+	if a.NormalBalance() == NormalDebit {
+		return net(a.DebitsPosted, a.CreditsPosted)
+	}
+	return net(a.CreditsPosted, a.DebitsPosted)
+}
+
+// PendingBalance is the net effect of pending transfers on the normal side.
+func (a Account) PendingBalance() int64 {
+	if a.NormalBalance() == NormalDebit {
+		return net(a.DebitsPending, a.CreditsPending)
+	}
+	return net(a.CreditsPending, a.DebitsPending)
+}
+
+// AvailableBalance is the amount that can be moved out of the account:
+// the posted balance less pending outgoing transfers. Pending incoming
+// transfers are not available until they are posted.
+func (a Account) AvailableBalance() int64 {
+	if a.NormalBalance() == NormalDebit {
+		return a.PostedBalance() - int64(a.CreditsPending)
+	}
+	return a.PostedBalance() - int64(a.DebitsPending)
+}
+
+func net(increase, decrease uint64) int64 {
+	if increase >= decrease {
+		return int64(increase - decrease)
+	}
+	return -int64(decrease - increase)
+}
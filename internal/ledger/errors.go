@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// AccountError is returned when a backend rejects an account.
+type AccountError struct {
+	ID     uuid.UUID
+	Result CreateAccountResult
+}
+
+func (e *AccountError) Error() string {
+	return fmt.Sprintf("ledger rejected account %s: %s", e.ID, e.Result)
+}
+
+// Exists reports whether the account was rejected only because an identical
+// account already exists, which makes a retried create safe to ignore.
+func (e *AccountError) Exists() bool {
+	return e.Result == AccountExists
+}
+
+// TransferError is returned when a backend rejects a transfer.
+type TransferError struct {
+	ID     uuid.UUID
+	Result CreateTransferResult
+}
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("ledger rejected transfer %s: %s", e.ID, e.Result)
+}
+
+// Exists reports whether the transfer was rejected only because an identical
+// transfer already exists.
+func (e *TransferError) Exists() bool {
+	return e.Result == TransferExists
+}
+
+// ChainError is returned when a chain of linked transfers is rejected.
+// None of the transfers in the chain were applied; Index identifies the
+// transfer whose failure caused the rest of the chain to fail.
+type ChainError struct {
+	Index int
+	Cause *TransferError
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("linked transfer %d failed, no transfers were applied: %v", e.Index, e.Cause)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,127 @@
+// Package ledger defines the double-entry vocabulary shared by the ledger
+// backends: accounts, transfers and the result codes returned when an event is
+// rejected. The types mirror TigerBeetle's data model with UUID identifiers and
+// 64-bit amounts so that backends can be swapped without touching callers.
+package ledger
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// AmountMax posts the full amount of a pending transfer when used as the
+// amount of a post-pending transfer.
+const AmountMax uint64 = math.MaxUint64
+
+// AccountFlags controls the invariants enforced on an account.
+type AccountFlags struct {
+	// DebitsMustNotExceedCredits prevents the account from being overdrawn.
+	DebitsMustNotExceedCredits bool `json:"debits_must_not_exceed_credits,omitempty"`
+	// CreditsMustNotExceedDebits is the mirror image for debit-normal accounts.
+	CreditsMustNotExceedDebits bool `json:"credits_must_not_exceed_debits,omitempty"`
+	// History retains balance snapshots for every transfer.
+	History bool `json:"history,omitempty"`
+}
+
+// Account is a ledger account and its running balances.
+type Account struct {
+	ID             uuid.UUID    `json:"id"`
+	DebitsPending  uint64       `json:"debits_pending"`
+	DebitsPosted   uint64       `json:"debits_posted"`
+	CreditsPending uint64       `json:"credits_pending"`
+	CreditsPosted  uint64       `json:"credits_posted"`
+	UserData128    uuid.UUID    `json:"user_data_128"`
+	UserData64     uint64       `json:"user_data_64"`
+	UserData32     uint32       `json:"user_data_32"`
+	Ledger         uint32       `json:"ledger"`
+	Code           uint16       `json:"code"`
+	Flags          AccountFlags `json:"flags"`
+	Timestamp      uint64       `json:"timestamp"`
+}
+
+// TransferFlags selects the kind of transfer.
+// A transfer without flags is posted immediately (single-phase).
+type TransferFlags struct {
+	// Linked chains the transfer to the next one in the batch: the chain
+	// succeeds or fails as a whole. The last transfer of a chain is unlinked.
+	Linked bool `json:"linked,omitempty"`
+	// Pending reserves the amount without posting it (the first phase).
+	Pending bool `json:"pending,omitempty"`
+	// PostPendingTransfer posts some or all of the pending transfer PendingID.
+	PostPendingTransfer bool `json:"post_pending_transfer,omitempty"`
+	// VoidPendingTransfer releases the pending transfer PendingID.
+	VoidPendingTransfer bool `json:"void_pending_transfer,omitempty"`
+}
+
+// Transfer moves Amount from the debit account to the credit account.
+// Transfers are immutable once created.
+type Transfer struct {
+	ID              uuid.UUID `json:"id"`
+	DebitAccountID  uuid.UUID `json:"debit_account_id"`
+	CreditAccountID uuid.UUID `json:"credit_account_id"`
+	Amount          uint64    `json:"amount"`
+	// PendingID is the pending transfer that a post or void resolves.
+	PendingID   uuid.UUID `json:"pending_id"`
+	UserData128 uuid.UUID `json:"user_data_128"`
+	UserData64  uint64    `json:"user_data_64"`
+	UserData32  uint32    `json:"user_data_32"`
+	// Timeout is the number of seconds after which a pending transfer expires
+	// and its amount is released. Zero means it never expires.
+	Timeout   uint32        `json:"timeout"`
+	Ledger    uint32        `json:"ledger"`
+	Code      uint16        `json:"code"`
+	Flags     TransferFlags `json:"flags"`
+	Timestamp uint64        `json:"timestamp"`
+}
+
+// AccountFilter selects the transfers that touched an account.
+// Timestamps are inclusive bounds in nanoseconds; zero leaves a bound open.
+// At least one of Debits and Credits must be set.
+type AccountFilter struct {
+	AccountID    uuid.UUID `json:"account_id"`
+	TimestampMin uint64    `json:"timestamp_min"`
+	TimestampMax uint64    `json:"timestamp_max"`
+	Limit        int       `json:"limit"`
+	// Debits includes transfers where the account is the debit side.
+	Debits bool `json:"debits"`
+	// Credits includes transfers where the account is the credit side.
+	Credits bool `json:"credits"`
+	// Reversed returns the newest transfers first.
+	Reversed bool `json:"reversed"`
+}
+
+// Valid reports whether the filter would be accepted by TigerBeetle, which
+// returns no results rather than an error for an invalid filter.
+func (f AccountFilter) Valid() bool {
+	switch {
+	case f.AccountID == uuid.Nil || f.AccountID == MaxID:
+		return false
+	case f.TimestampMax != 0 && f.TimestampMin > f.TimestampMax:
+		return false
+	case f.Limit <= 0:
+		return false
+	case !f.Debits && !f.Credits:
+		return false
+	}
+	return true
+}
+
+// AccountEventResult reports the failure of the account at Index in a batch.
+type AccountEventResult struct {
+	Index  int                 `json:"index"`
+	Result CreateAccountResult `json:"result"`
+}
+
+// TransferEventResult reports the failure of the transfer at Index in a batch.
+type TransferEventResult struct {
+	Index  int                  `json:"index"`
+	Result CreateTransferResult `json:"result"`
+}
+
+// MaxID is the reserved all-ones identifier that may not be used by an
+// account or a transfer.
+var MaxID = uuid.UUID{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+}
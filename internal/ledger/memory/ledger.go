@@ -0,0 +1,609 @@
+// Package memory provides an in-process double-entry ledger that enforces the
+// same invariants as TigerBeetle. It is intended for local development and CI
+// where no TigerBeetle cluster is available.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+)
+
+// Ledger is an in-memory ledger backend.
+// It is safe for concurrent use; every batch is applied atomically with
+// respect to other batches.
+type Ledger struct {
+	mu        sync.Mutex
+	accounts  map[uuid.UUID]*ledger.Account
+	transfers map[uuid.UUID]ledger.Transfer
+	// history lists the IDs of the transfers touching each account in
+	// timestamp order.
+	history map[uuid.UUID][]uuid.UUID
+	// failed remembers transfer IDs rejected for a transient reason so that a
+	// retry with the same ID cannot succeed later, as in TigerBeetle.
+	failed map[uuid.UUID]ledger.CreateTransferResult
+	// pending tracks the lifecycle of every pending transfer.
+	pending map[uuid.UUID]*pendingTransfer
+	// undo holds the steps that roll back the chain being applied.
+	undo      []func()
+	timestamp uint64
+}
+
+type pendingStatus int
+
+const (
+	statusPending pendingStatus = iota
+	statusPosted
+	statusVoided
+	statusExpired
+)
+
+type pendingTransfer struct {
+	status pendingStatus
+	// expiresAt is the timestamp at which the transfer expires, or zero.
+	expiresAt uint64
+}
+
+// NewLedger creates an empty in-memory ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		accounts:  make(map[uuid.UUID]*ledger.Account),
+		transfers: make(map[uuid.UUID]ledger.Transfer),
+		history:   make(map[uuid.UUID][]uuid.UUID),
+		failed:    make(map[uuid.UUID]ledger.CreateTransferResult),
+		pending:   make(map[uuid.UUID]*pendingTransfer),
+	}
+}
+
+// CreateAccounts creates a batch of accounts.
+// Only the accounts that failed are reported.
+func (l *Ledger) CreateAccounts(ctx context.Context, accounts []ledger.Account) ([]ledger.AccountEventResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var results []ledger.AccountEventResult
+	for i, account := range accounts {
+		if result := l.createAccount(account); result != ledger.AccountOK {
+			results = append(results, ledger.AccountEventResult{Index: i, Result: result})
+		}
+	}
+	return results, nil
+}
+
+// CreateTransfers creates a batch of transfers.
+// Linked transfers are applied as a chain that succeeds or fails as a whole.
+// Only the transfers that failed are reported.
+func (l *Ledger) CreateTransfers(ctx context.Context, transfers []ledger.Transfer) ([]ledger.TransferEventResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expirePending()
+
+	var results []ledger.TransferEventResult
+	for start := 0; start < len(transfers); {
+		// A chain runs up to and including the first unlinked transfer.
+		end := start
+		for end < len(transfers)-1 && transfers[end].Flags.Linked {
+			end++
+		}
+		results = append(results, l.createChain(transfers[start:end+1], start)...)
+		start = end + 1
+	}
+	return results, nil
+}
+
+// createChain applies a chain of linked transfers, or a single unlinked
+// transfer, rolling back every effect of the chain if any transfer fails.
+func (l *Ledger) createChain(chain []ledger.Transfer, offset int) []ledger.TransferEventResult {
+	l.undo = l.undo[:0]
+
+	failed, result := -1, ledger.TransferOK
+	for i, t := range chain {
+		if i == len(chain)-1 && t.Flags.Linked {
+			result = ledger.TransferLinkedEventChainOpen
+		} else {
+			result = l.createTransfer(t)
+		}
+		if result != ledger.TransferOK {
+			failed = i
+			break
+		}
+	}
+	if failed < 0 {
+		return nil
+	}
+
+	for i := len(l.undo) - 1; i >= 0; i-- {
+		l.undo[i]()
+	}
+	// As in TigerBeetle, the ID of a transfer that failed transiently can't
+	// be reused, whether or not it was part of a chain
+	if transient(result) {
+		l.failed[chain[failed].ID] = result
+	}
+
+	results := make([]ledger.TransferEventResult, len(chain))
+	for i := range chain {
+		results[i] = ledger.TransferEventResult{Index: offset + i, Result: ledger.TransferLinkedEventFailed}
+	}
+	results[failed].Result = result
+	return results
+}
+
+// LookupAccounts returns the accounts that exist among ids, in the order requested.
+func (l *Ledger) LookupAccounts(ctx context.Context, ids []uuid.UUID) ([]ledger.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expirePending()
+
+	accounts := make([]ledger.Account, 0, len(ids))
+	for _, id := range ids {
+		if account, ok := l.accounts[id]; ok {
+			accounts = append(accounts, *account)
+		}
+	}
+	return accounts, nil
+}
+
+// LookupTransfers returns the transfers that exist among ids, in the order requested.
+func (l *Ledger) LookupTransfers(ctx context.Context, ids []uuid.UUID) ([]ledger.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	transfers := make([]ledger.Transfer, 0, len(ids))
+	for _, id := range ids {
+		if transfer, ok := l.transfers[id]; ok {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
+
+// GetAccountTransfers returns the transfers that touched an account, in
+// timestamp order, subject to filter. An invalid filter yields no transfers.
+func (l *Ledger) GetAccountTransfers(ctx context.Context, filter ledger.AccountFilter) ([]ledger.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !filter.Valid() {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids := l.history[filter.AccountID]
+	transfers := make([]ledger.Transfer, 0, min(filter.Limit, len(ids)))
+	for i := range ids {
+		if filter.Reversed {
+			i = len(ids) - 1 - i
+		}
+		t := l.transfers[ids[i]]
+		if filter.TimestampMin != 0 && t.Timestamp < filter.TimestampMin {
+			continue
+		}
+		if filter.TimestampMax != 0 && t.Timestamp > filter.TimestampMax {
+			continue
+		}
+		if !(filter.Debits && t.DebitAccountID == filter.AccountID) &&
+			!(filter.Credits && t.CreditAccountID == filter.AccountID) {
+			continue
+		}
+		transfers = append(transfers, t)
+		if len(transfers) == filter.Limit {
+			break
+		}
+	}
+	return transfers, nil
+}
+
+// Verify checks that the books balance: on every ledger the total of all
+// debits equals the total of all credits.
+func (l *Ledger) Verify() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type totals struct {
+		debitsPending, debitsPosted, creditsPending, creditsPosted uint64
+	}
+	byLedger := make(map[uint32]*totals)
+	for _, account := range l.accounts {
+		t, ok := byLedger[account.Ledger]
+		if !ok {
+			t = &totals{}
+			byLedger[account.Ledger] = t
+		}
+		t.debitsPending += account.DebitsPending
+		t.debitsPosted += account.DebitsPosted
+		t.creditsPending += account.CreditsPending
+		t.creditsPosted += account.CreditsPosted
+	}
+
+	for id, t := range byLedger {
+		if t.debitsPosted != t.creditsPosted {
+			return fmt.Errorf("ledger %d: posted debits %d do not equal posted credits %d", id, t.debitsPosted, t.creditsPosted)
+		}
+		if t.debitsPending != t.creditsPending {
+			return fmt.Errorf("ledger %d: pending debits %d do not equal pending credits %d", id, t.debitsPending, t.creditsPending)
+		}
+	}
+	return nil
+}
+
+// tick returns a strictly increasing nanosecond timestamp, as assigned by a
+// TigerBeetle cluster to every event it commits.
+func (l *Ledger) tick() uint64 {
+	now := uint64(time.Now().UnixNano())
+	if now <= l.timestamp {
+		now = l.timestamp + 1
+	}
+	l.timestamp = now
+	return now
+}
+
+func (l *Ledger) createAccount(a ledger.Account) ledger.CreateAccountResult {
+	switch {
+	case a.Timestamp != 0:
+		return ledger.AccountTimestampMustBeZero
+	case a.ID == uuid.Nil:
+		return ledger.AccountIDMustNotBeZero
+	case a.ID == ledger.MaxID:
+		return ledger.AccountIDMustNotBeIntMax
+	case a.Flags.DebitsMustNotExceedCredits && a.Flags.CreditsMustNotExceedDebits:
+		return ledger.AccountFlagsAreMutuallyExclusive
+	case a.DebitsPending != 0:
+		return ledger.AccountDebitsPendingMustBeZero
+	case a.DebitsPosted != 0:
+		return ledger.AccountDebitsPostedMustBeZero
+	case a.CreditsPending != 0:
+		return ledger.AccountCreditsPendingMustBeZero
+	case a.CreditsPosted != 0:
+		return ledger.AccountCreditsPostedMustBeZero
+	case a.Ledger == 0:
+		return ledger.AccountLedgerMustNotBeZero
+	case a.Code == 0:
+		return ledger.AccountCodeMustNotBeZero
+	}
+
+	if existing, ok := l.accounts[a.ID]; ok {
+		return accountExists(a, existing)
+	}
+
+	a.Timestamp = l.tick()
+	l.accounts[a.ID] = &a
+	return ledger.AccountOK
+}
+
+func accountExists(a ledger.Account, e *ledger.Account) ledger.CreateAccountResult {
+	switch {
+	case a.Flags != e.Flags:
+		return ledger.AccountExistsWithDifferentFlags
+	case a.UserData128 != e.UserData128:
+		return ledger.AccountExistsWithDifferentUserData128
+	case a.UserData64 != e.UserData64:
+		return ledger.AccountExistsWithDifferentUserData64
+	case a.UserData32 != e.UserData32:
+		return ledger.AccountExistsWithDifferentUserData32
+	case a.Ledger != e.Ledger:
+		return ledger.AccountExistsWithDifferentLedger
+	case a.Code != e.Code:
+		return ledger.AccountExistsWithDifferentCode
+	}
+	return ledger.AccountExists
+}
+
+func (l *Ledger) createTransfer(t ledger.Transfer) ledger.CreateTransferResult {
+	switch {
+	case t.Timestamp != 0:
+		return ledger.TransferTimestampMustBeZero
+	case t.ID == uuid.Nil:
+		return ledger.TransferIDMustNotBeZero
+	case t.ID == ledger.MaxID:
+		return ledger.TransferIDMustNotBeIntMax
+	}
+
+	if existing, ok := l.transfers[t.ID]; ok {
+		return transferExists(t, existing)
+	}
+	if _, ok := l.failed[t.ID]; ok {
+		return ledger.TransferIDAlreadyFailed
+	}
+
+	switch {
+	case t.Flags.Pending && (t.Flags.PostPendingTransfer || t.Flags.VoidPendingTransfer),
+		t.Flags.PostPendingTransfer && t.Flags.VoidPendingTransfer:
+		return ledger.TransferFlagsAreMutuallyExclusive
+	case t.Flags.PostPendingTransfer || t.Flags.VoidPendingTransfer:
+		return l.resolvePending(t)
+	}
+	return l.applyTransfer(t)
+}
+
+func (l *Ledger) applyTransfer(t ledger.Transfer) ledger.CreateTransferResult {
+	switch {
+	case t.DebitAccountID == uuid.Nil:
+		return ledger.TransferDebitAccountIDMustNotBeZero
+	case t.DebitAccountID == ledger.MaxID:
+		return ledger.TransferDebitAccountIDMustNotBeIntMax
+	case t.CreditAccountID == uuid.Nil:
+		return ledger.TransferCreditAccountIDMustNotBeZero
+	case t.CreditAccountID == ledger.MaxID:
+		return ledger.TransferCreditAccountIDMustNotBeIntMax
+	case t.DebitAccountID == t.CreditAccountID:
+		return ledger.TransferAccountsMustBeDifferent
+	case t.PendingID != uuid.Nil:
+		return ledger.TransferPendingIDMustBeZero
+	case !t.Flags.Pending && t.Timeout != 0:
+		return ledger.TransferTimeoutReservedForPendingTransfer
+	case t.Ledger == 0:
+		return ledger.TransferLedgerMustNotBeZero
+	case t.Code == 0:
+		return ledger.TransferCodeMustNotBeZero
+	}
+
+	dr, ok := l.accounts[t.DebitAccountID]
+	if !ok {
+		return ledger.TransferDebitAccountNotFound
+	}
+	cr, ok := l.accounts[t.CreditAccountID]
+	if !ok {
+		return ledger.TransferCreditAccountNotFound
+	}
+	if dr.Ledger != cr.Ledger {
+		return ledger.TransferAccountsMustHaveTheSameLedger
+	}
+	if t.Ledger != dr.Ledger {
+		return ledger.TransferTransferMustHaveTheSameLedgerAsAccounts
+	}
+
+	if t.Flags.Pending {
+		switch {
+		case overflows(dr.DebitsPending, t.Amount):
+			return ledger.TransferOverflowsDebitsPending
+		case overflows(cr.CreditsPending, t.Amount):
+			return ledger.TransferOverflowsCreditsPending
+		}
+	} else {
+		switch {
+		case overflows(dr.DebitsPosted, t.Amount):
+			return ledger.TransferOverflowsDebitsPosted
+		case overflows(cr.CreditsPosted, t.Amount):
+			return ledger.TransferOverflowsCreditsPosted
+		}
+	}
+	switch {
+	case overflows(dr.DebitsPending, dr.DebitsPosted, t.Amount):
+		return ledger.TransferOverflowsDebits
+	case overflows(cr.CreditsPending, cr.CreditsPosted, t.Amount):
+		return ledger.TransferOverflowsCredits
+	case overflows(uint64(time.Now().UnixNano()), uint64(t.Timeout)*uint64(time.Second)):
+		return ledger.TransferOverflowsTimeout
+	}
+
+	if dr.Flags.DebitsMustNotExceedCredits && dr.DebitsPending+dr.DebitsPosted+t.Amount > dr.CreditsPosted {
+		return ledger.TransferExceedsCredits
+	}
+	if cr.Flags.CreditsMustNotExceedDebits && cr.CreditsPending+cr.CreditsPosted+t.Amount > cr.DebitsPosted {
+		return ledger.TransferExceedsDebits
+	}
+
+	t.Timestamp = l.tick()
+	l.remember(dr, cr)
+	if t.Flags.Pending {
+		dr.DebitsPending += t.Amount
+		cr.CreditsPending += t.Amount
+		p := &pendingTransfer{status: statusPending}
+		if t.Timeout != 0 {
+			p.expiresAt = t.Timestamp + uint64(t.Timeout)*uint64(time.Second)
+		}
+		l.pending[t.ID] = p
+		l.undo = append(l.undo, func() { delete(l.pending, t.ID) })
+	} else {
+		dr.DebitsPosted += t.Amount
+		cr.CreditsPosted += t.Amount
+	}
+	l.record(t)
+	return ledger.TransferOK
+}
+
+// resolvePending posts or voids the pending transfer t.PendingID.
+// Zero debit and credit accounts, ledger and code default to those of the
+// pending transfer, as does an amount of ledger.AmountMax.
+func (l *Ledger) resolvePending(t ledger.Transfer) ledger.CreateTransferResult {
+	switch {
+	case t.PendingID == uuid.Nil:
+		return ledger.TransferPendingIDMustNotBeZero
+	case t.PendingID == ledger.MaxID:
+		return ledger.TransferPendingIDMustNotBeIntMax
+	case t.PendingID == t.ID:
+		return ledger.TransferPendingIDMustBeDifferent
+	case t.Timeout != 0:
+		return ledger.TransferTimeoutReservedForPendingTransfer
+	}
+
+	p, ok := l.transfers[t.PendingID]
+	if !ok {
+		return ledger.TransferPendingTransferNotFound
+	}
+	if !p.Flags.Pending {
+		return ledger.TransferPendingTransferNotPending
+	}
+
+	switch {
+	case t.DebitAccountID != uuid.Nil && t.DebitAccountID != p.DebitAccountID:
+		return ledger.TransferPendingTransferHasDifferentDebitAccountID
+	case t.CreditAccountID != uuid.Nil && t.CreditAccountID != p.CreditAccountID:
+		return ledger.TransferPendingTransferHasDifferentCreditAccountID
+	case t.Ledger != 0 && t.Ledger != p.Ledger:
+		return ledger.TransferPendingTransferHasDifferentLedger
+	case t.Code != 0 && t.Code != p.Code:
+		return ledger.TransferPendingTransferHasDifferentCode
+	}
+
+	amount := t.Amount
+	if t.Flags.PostPendingTransfer {
+		if amount == ledger.AmountMax {
+			amount = p.Amount
+		} else if amount > p.Amount {
+			return ledger.TransferExceedsPendingTransferAmount
+		}
+	} else {
+		if amount == 0 || amount == ledger.AmountMax {
+			amount = p.Amount
+		} else if amount != p.Amount {
+			return ledger.TransferPendingTransferHasDifferentAmount
+		}
+	}
+
+	state := l.pending[p.ID]
+	switch state.status {
+	case statusPosted:
+		return ledger.TransferPendingTransferAlreadyPosted
+	case statusVoided:
+		return ledger.TransferPendingTransferAlreadyVoided
+	case statusExpired:
+		return ledger.TransferPendingTransferExpired
+	}
+
+	dr := l.accounts[p.DebitAccountID]
+	cr := l.accounts[p.CreditAccountID]
+
+	t.DebitAccountID = p.DebitAccountID
+	t.CreditAccountID = p.CreditAccountID
+	t.Amount = amount
+	t.Ledger = p.Ledger
+	t.Code = p.Code
+	t.Timestamp = l.tick()
+	l.remember(dr, cr)
+	l.undo = append(l.undo, func() { state.status = statusPending })
+
+	dr.DebitsPending -= p.Amount
+	cr.CreditsPending -= p.Amount
+	if t.Flags.PostPendingTransfer {
+		dr.DebitsPosted += amount
+		cr.CreditsPosted += amount
+		state.status = statusPosted
+	} else {
+		state.status = statusVoided
+	}
+	l.record(t)
+	return ledger.TransferOK
+}
+
+// record stores a committed transfer and indexes it under both accounts.
+func (l *Ledger) record(t ledger.Transfer) {
+	l.transfers[t.ID] = t
+	l.history[t.DebitAccountID] = append(l.history[t.DebitAccountID], t.ID)
+	l.history[t.CreditAccountID] = append(l.history[t.CreditAccountID], t.ID)
+	l.undo = append(l.undo, func() {
+		delete(l.transfers, t.ID)
+		l.history[t.DebitAccountID] = l.history[t.DebitAccountID][:len(l.history[t.DebitAccountID])-1]
+		l.history[t.CreditAccountID] = l.history[t.CreditAccountID][:len(l.history[t.CreditAccountID])-1]
+	})
+}
+
+// remember saves the balances of accounts so that the current chain can be
+// rolled back.
+func (l *Ledger) remember(accounts ...*ledger.Account) {
+	for _, account := range accounts {
+		account, saved := account, *account
+		l.undo = append(l.undo, func() { *account = saved })
+	}
+}
+
+// expirePending releases the amounts held by pending transfers whose timeout
+// has elapsed.
+func (l *Ledger) expirePending() {
+	now := uint64(time.Now().UnixNano())
+	for id, state := range l.pending {
+		if state.status != statusPending || state.expiresAt == 0 || state.expiresAt > now {
+			continue
+		}
+		p := l.transfers[id]
+		l.accounts[p.DebitAccountID].DebitsPending -= p.Amount
+		l.accounts[p.CreditAccountID].CreditsPending -= p.Amount
+		state.status = statusExpired
+	}
+}
+
+func transferExists(t, e ledger.Transfer) ledger.CreateTransferResult {
+	resolves := t.Flags.PostPendingTransfer || t.Flags.VoidPendingTransfer
+	switch {
+	case t.Flags != e.Flags:
+		return ledger.TransferExistsWithDifferentFlags
+	case t.PendingID != e.PendingID:
+		return ledger.TransferExistsWithDifferentPendingID
+	case t.Timeout != e.Timeout:
+		return ledger.TransferExistsWithDifferentTimeout
+	case t.DebitAccountID != e.DebitAccountID && !(resolves && t.DebitAccountID == uuid.Nil):
+		return ledger.TransferExistsWithDifferentDebitAccountID
+	case t.CreditAccountID != e.CreditAccountID && !(resolves && t.CreditAccountID == uuid.Nil):
+		return ledger.TransferExistsWithDifferentCreditAccountID
+	case t.Amount != e.Amount && !(resolves && (t.Amount == ledger.AmountMax || t.Amount == 0)):
+		return ledger.TransferExistsWithDifferentAmount
+	case t.UserData128 != e.UserData128:
+		return ledger.TransferExistsWithDifferentUserData128
+	case t.UserData64 != e.UserData64:
+		return ledger.TransferExistsWithDifferentUserData64
+	case t.UserData32 != e.UserData32:
+		return ledger.TransferExistsWithDifferentUserData32
+	case t.Ledger != e.Ledger && !(resolves && t.Ledger == 0):
+		return ledger.TransferExistsWithDifferentLedger
+	case t.Code != e.Code && !(resolves && t.Code == 0):
+		return ledger.TransferExistsWithDifferentCode
+	}
+	return ledger.TransferExists
+}
+
+// transient reports whether a result depends on the state of the ledger at the
+// time the transfer was submitted rather than on the transfer itself.
+func transient(result ledger.CreateTransferResult) bool {
+	switch result {
+	case ledger.TransferDebitAccountNotFound,
+		ledger.TransferCreditAccountNotFound,
+		ledger.TransferPendingTransferNotFound,
+		ledger.TransferExceedsCredits,
+		ledger.TransferExceedsDebits,
+		ledger.TransferOverflowsDebitsPending,
+		ledger.TransferOverflowsCreditsPending,
+		ledger.TransferOverflowsDebitsPosted,
+		ledger.TransferOverflowsCreditsPosted,
+		ledger.TransferOverflowsDebits,
+		ledger.TransferOverflowsCredits,
+		ledger.TransferOverflowsTimeout:
+		return true
+	}
+	return false
+}
+
+// overflows reports whether the sum of values does not fit in 64 bits.
+func overflows(values ...uint64) bool {
+	var sum uint64
+	for _, v := range values {
+		if v > math.MaxUint64-sum {
+			return true
+		}
+		sum += v
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+)
+
+func TestCreateTransfersTransientFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		// linked transfers before the one that fails
+		before int
+	}{
+		{name: "unlinked", before: 0},
+		{name: "chain of two", before: 1},
+		{name: "chain of three", before: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLedger()
+			ctx := context.Background()
+			from, to := uuid.New(), uuid.New()
+			_, err := l.CreateAccounts(ctx, []ledger.Account{
+				{ID: from, Ledger: 1, Code: 1, Flags: ledger.AccountFlags{DebitsMustNotExceedCredits: true}},
+				{ID: to, Ledger: 1, Code: 1},
+			})
+			if err != nil {
+				t.Fatalf("CreateAccounts: %v", err)
+			}
+
+			// from has no credits, so any debit of it exceeds them
+			var chain []ledger.Transfer
+			for i := 0; i < tt.before; i++ {
+				chain = append(chain, ledger.Transfer{
+					ID: uuid.New(), DebitAccountID: to, CreditAccountID: from, Amount: 1, Ledger: 1, Code: 1,
+					Flags: ledger.TransferFlags{Linked: true},
+				})
+			}
+			failing := ledger.Transfer{ID: uuid.New(), DebitAccountID: from, CreditAccountID: to, Amount: 10, Ledger: 1, Code: 1}
+			chain = append(chain, failing)
+			results, err := l.CreateTransfers(ctx, chain)
+			if err != nil {
+				t.Fatalf("CreateTransfers: %v", err)
+			}
+			if len(results) != len(chain) || results[tt.before].Result != ledger.TransferExceedsCredits {
+				t.Fatalf("results = %v, want %v at %d", results, ledger.TransferExceedsCredits, tt.before)
+			}
+
+			// Once from is funded the transfer would succeed, but its ID is spent
+			funding := ledger.Transfer{ID: uuid.New(), DebitAccountID: to, CreditAccountID: from, Amount: 100, Ledger: 1, Code: 1}
+			if results, _ := l.CreateTransfers(ctx, []ledger.Transfer{funding}); len(results) != 0 {
+				t.Fatalf("funding: %v", results)
+			}
+			results, err = l.CreateTransfers(ctx, []ledger.Transfer{failing})
+			if err != nil {
+				t.Fatalf("CreateTransfers: %v", err)
+			}
+			if len(results) != 1 || results[0].Result != ledger.TransferIDAlreadyFailed {
+				t.Errorf("retry results = %v, want %v", results, ledger.TransferIDAlreadyFailed)
+			}
+			if err := l.Verify(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
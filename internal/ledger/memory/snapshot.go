@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/google/uuid"
+)
+
+// snapshot is the JSON form of the state of a Ledger.
+type snapshot struct {
+	Accounts []ledger.Account `json:"accounts"`
+	// Transfers are listed in timestamp order.
+	Transfers []ledger.Transfer `json:"transfers"`
+	Pending   []pendingSnapshot `json:"pending"`
+	Failed    []failedSnapshot  `json:"failed"`
+	Timestamp uint64            `json:"timestamp"`
+}
+
+type pendingSnapshot struct {
+	ID        uuid.UUID `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresAt uint64    `json:"expires_at,omitempty"`
+}
+
+type failedSnapshot struct {
+	ID     uuid.UUID                   `json:"id"`
+	Result ledger.CreateTransferResult `json:"result"`
+}
+
+var statusNames = map[pendingStatus]string{
+	statusPending: "pending",
+	statusPosted:  "posted",
+	statusVoided:  "voided",
+	statusExpired: "expired",
+}
+
+// Save writes the state of the ledger to w as JSON.
+func (l *Ledger) Save(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expirePending()
+
+	s := snapshot{
+		Accounts:  make([]ledger.Account, 0, len(l.accounts)),
+		Transfers: make([]ledger.Transfer, 0, len(l.transfers)),
+		Pending:   make([]pendingSnapshot, 0, len(l.pending)),
+		Failed:    make([]failedSnapshot, 0, len(l.failed)),
+		Timestamp: l.timestamp,
+	}
+	for _, account := range l.accounts {
+		s.Accounts = append(s.Accounts, *account)
+	}
+	for _, transfer := range l.transfers {
+		s.Transfers = append(s.Transfers, transfer)
+	}
+	for id, state := range l.pending {
+		s.Pending = append(s.Pending, pendingSnapshot{ID: id, Status: statusNames[state.status], ExpiresAt: state.expiresAt})
+	}
+	for id, result := range l.failed {
+		s.Failed = append(s.Failed, failedSnapshot{ID: id, Result: result})
+	}
+
+	sort.Slice(s.Accounts, func(i, j int) bool { return s.Accounts[i].Timestamp < s.Accounts[j].Timestamp })
+	sort.Slice(s.Transfers, func(i, j int) bool { return s.Transfers[i].Timestamp < s.Transfers[j].Timestamp })
+	sort.Slice(s.Pending, func(i, j int) bool { return s.Pending[i].ID.String() < s.Pending[j].ID.String() })
+	sort.Slice(s.Failed, func(i, j int) bool { return s.Failed[i].ID.String() < s.Failed[j].ID.String() })
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Load replaces the state of the ledger with a snapshot written by Save.
+// The ledger is left unchanged if the snapshot is invalid.
+func (l *Ledger) Load(r io.Reader) error {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("decoding ledger snapshot: %w", err)
+	}
+
+	loaded := NewLedger()
+	loaded.timestamp = s.Timestamp
+	for _, account := range s.Accounts {
+		if _, ok := loaded.accounts[account.ID]; ok {
+			return fmt.Errorf("ledger snapshot: duplicate account %s", account.ID)
+		}
+		account := account
+		loaded.accounts[account.ID] = &account
+		loaded.timestamp = max(loaded.timestamp, account.Timestamp)
+	}
+
+	sort.SliceStable(s.Transfers, func(i, j int) bool { return s.Transfers[i].Timestamp < s.Transfers[j].Timestamp })
+	for _, transfer := range s.Transfers {
+		if _, ok := loaded.transfers[transfer.ID]; ok {
+			return fmt.Errorf("ledger snapshot: duplicate transfer %s", transfer.ID)
+		}
+		if _, ok := loaded.accounts[transfer.DebitAccountID]; !ok {
+			return fmt.Errorf("ledger snapshot: transfer %s debits unknown account %s", transfer.ID, transfer.DebitAccountID)
+		}
+		if _, ok := loaded.accounts[transfer.CreditAccountID]; !ok {
+			return fmt.Errorf("ledger snapshot: transfer %s credits unknown account %s", transfer.ID, transfer.CreditAccountID)
+		}
+		loaded.record(transfer)
+		loaded.timestamp = max(loaded.timestamp, transfer.Timestamp)
+	}
+	loaded.undo = nil
+
+	for _, p := range s.Pending {
+		if transfer, ok := loaded.transfers[p.ID]; !ok || !transfer.Flags.Pending {
+			return fmt.Errorf("ledger snapshot: %s is not a pending transfer", p.ID)
+		}
+		state := &pendingTransfer{expiresAt: p.ExpiresAt, status: -1}
+		for status, name := range statusNames {
+			if name == p.Status {
+				state.status = status
+			}
+		}
+		if state.status < 0 {
+			return fmt.Errorf("ledger snapshot: pending transfer %s has unknown status %q", p.ID, p.Status)
+		}
+		loaded.pending[p.ID] = state
+	}
+	for _, f := range s.Failed {
+		loaded.failed[f.ID] = f.Result
+	}
+
+	if err := loaded.Verify(); err != nil {
+		return fmt.Errorf("ledger snapshot: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.accounts = loaded.accounts
+	l.transfers = loaded.transfers
+	l.history = loaded.history
+	l.failed = loaded.failed
+	l.pending = loaded.pending
+	l.timestamp = max(l.timestamp, loaded.timestamp)
+	return nil
+}
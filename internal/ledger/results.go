@@ -0,0 +1,228 @@
+package ledger
+
+import "strconv"
+
+// The result codes below mirror the values returned by TigerBeetle so that a
+// result from the SDK can be converted with a plain type conversion.
+
+// CreateAccountResult is the outcome of creating a single account.
+type CreateAccountResult uint32
+
+const (
+	AccountOK                                   CreateAccountResult = 0
+	AccountLinkedEventFailed                    CreateAccountResult = 1
+	AccountLinkedEventChainOpen                 CreateAccountResult = 2
+	AccountImportedEventExpected                CreateAccountResult = 22
+	AccountImportedEventNotExpected             CreateAccountResult = 23
+	AccountTimestampMustBeZero                  CreateAccountResult = 3
+	AccountImportedEventTimestampOutOfRange     CreateAccountResult = 24
+	AccountImportedEventTimestampMustNotAdvance CreateAccountResult = 25
+	AccountReservedField                        CreateAccountResult = 4
+	AccountReservedFlag                         CreateAccountResult = 5
+	AccountIDMustNotBeZero                      CreateAccountResult = 6
+	AccountIDMustNotBeIntMax                    CreateAccountResult = 7
+	AccountExistsWithDifferentFlags             CreateAccountResult = 15
+	AccountExistsWithDifferentUserData128       CreateAccountResult = 16
+	AccountExistsWithDifferentUserData64        CreateAccountResult = 17
+	AccountExistsWithDifferentUserData32        CreateAccountResult = 18
+	AccountExistsWithDifferentLedger            CreateAccountResult = 19
+	AccountExistsWithDifferentCode              CreateAccountResult = 20
+	AccountExists                               CreateAccountResult = 21
+	AccountFlagsAreMutuallyExclusive            CreateAccountResult = 8
+	AccountDebitsPendingMustBeZero              CreateAccountResult = 9
+	AccountDebitsPostedMustBeZero               CreateAccountResult = 10
+	AccountCreditsPendingMustBeZero             CreateAccountResult = 11
+	AccountCreditsPostedMustBeZero              CreateAccountResult = 12
+	AccountLedgerMustNotBeZero                  CreateAccountResult = 13
+	AccountCodeMustNotBeZero                    CreateAccountResult = 14
+	AccountImportedEventTimestampMustNotRegress CreateAccountResult = 26
+)
+
+var accountResultNames = map[CreateAccountResult]string{
+	AccountOK:                                   "AccountOK",
+	AccountLinkedEventFailed:                    "AccountLinkedEventFailed",
+	AccountLinkedEventChainOpen:                 "AccountLinkedEventChainOpen",
+	AccountImportedEventExpected:                "AccountImportedEventExpected",
+	AccountImportedEventNotExpected:             "AccountImportedEventNotExpected",
+	AccountTimestampMustBeZero:                  "AccountTimestampMustBeZero",
+	AccountImportedEventTimestampOutOfRange:     "AccountImportedEventTimestampOutOfRange",
+	AccountImportedEventTimestampMustNotAdvance: "AccountImportedEventTimestampMustNotAdvance",
+	AccountReservedField:                        "AccountReservedField",
+	AccountReservedFlag:                         "AccountReservedFlag",
+	AccountIDMustNotBeZero:                      "AccountIDMustNotBeZero",
+	AccountIDMustNotBeIntMax:                    "AccountIDMustNotBeIntMax",
+	AccountExistsWithDifferentFlags:             "AccountExistsWithDifferentFlags",
+	AccountExistsWithDifferentUserData128:       "AccountExistsWithDifferentUserData128",
+	AccountExistsWithDifferentUserData64:        "AccountExistsWithDifferentUserData64",
+	AccountExistsWithDifferentUserData32:        "AccountExistsWithDifferentUserData32",
+	AccountExistsWithDifferentLedger:            "AccountExistsWithDifferentLedger",
+	AccountExistsWithDifferentCode:              "AccountExistsWithDifferentCode",
+	AccountExists:                               "AccountExists",
+	AccountFlagsAreMutuallyExclusive:            "AccountFlagsAreMutuallyExclusive",
+	AccountDebitsPendingMustBeZero:              "AccountDebitsPendingMustBeZero",
+	AccountDebitsPostedMustBeZero:               "AccountDebitsPostedMustBeZero",
+	AccountCreditsPendingMustBeZero:             "AccountCreditsPendingMustBeZero",
+	AccountCreditsPostedMustBeZero:              "AccountCreditsPostedMustBeZero",
+	AccountLedgerMustNotBeZero:                  "AccountLedgerMustNotBeZero",
+	AccountCodeMustNotBeZero:                    "AccountCodeMustNotBeZero",
+	AccountImportedEventTimestampMustNotRegress: "AccountImportedEventTimestampMustNotRegress",
+}
+
+func (r CreateAccountResult) String() string {
+	if name, ok := accountResultNames[r]; ok {
+		return name
+	}
+	return "CreateAccountResult(" + strconv.FormatUint(uint64(r), 10) + ")"
+}
+
+// CreateTransferResult is the outcome of creating a single transfer.
+type CreateTransferResult uint32
+
+const (
+	TransferOK                                              CreateTransferResult = 0
+	TransferLinkedEventFailed                               CreateTransferResult = 1
+	TransferLinkedEventChainOpen                            CreateTransferResult = 2
+	TransferImportedEventExpected                           CreateTransferResult = 56
+	TransferImportedEventNotExpected                        CreateTransferResult = 57
+	TransferTimestampMustBeZero                             CreateTransferResult = 3
+	TransferImportedEventTimestampOutOfRange                CreateTransferResult = 58
+	TransferImportedEventTimestampMustNotAdvance            CreateTransferResult = 59
+	TransferReservedFlag                                    CreateTransferResult = 4
+	TransferIDMustNotBeZero                                 CreateTransferResult = 5
+	TransferIDMustNotBeIntMax                               CreateTransferResult = 6
+	TransferExistsWithDifferentFlags                        CreateTransferResult = 36
+	TransferExistsWithDifferentPendingID                    CreateTransferResult = 40
+	TransferExistsWithDifferentTimeout                      CreateTransferResult = 44
+	TransferExistsWithDifferentDebitAccountID               CreateTransferResult = 37
+	TransferExistsWithDifferentCreditAccountID              CreateTransferResult = 38
+	TransferExistsWithDifferentAmount                       CreateTransferResult = 39
+	TransferExistsWithDifferentUserData128                  CreateTransferResult = 41
+	TransferExistsWithDifferentUserData64                   CreateTransferResult = 42
+	TransferExistsWithDifferentUserData32                   CreateTransferResult = 43
+	TransferExistsWithDifferentLedger                       CreateTransferResult = 67
+	TransferExistsWithDifferentCode                         CreateTransferResult = 45
+	TransferExists                                          CreateTransferResult = 46
+	TransferIDAlreadyFailed                                 CreateTransferResult = 68
+	TransferFlagsAreMutuallyExclusive                       CreateTransferResult = 7
+	TransferDebitAccountIDMustNotBeZero                     CreateTransferResult = 8
+	TransferDebitAccountIDMustNotBeIntMax                   CreateTransferResult = 9
+	TransferCreditAccountIDMustNotBeZero                    CreateTransferResult = 10
+	TransferCreditAccountIDMustNotBeIntMax                  CreateTransferResult = 11
+	TransferAccountsMustBeDifferent                         CreateTransferResult = 12
+	TransferPendingIDMustBeZero                             CreateTransferResult = 13
+	TransferPendingIDMustNotBeZero                          CreateTransferResult = 14
+	TransferPendingIDMustNotBeIntMax                        CreateTransferResult = 15
+	TransferPendingIDMustBeDifferent                        CreateTransferResult = 16
+	TransferTimeoutReservedForPendingTransfer               CreateTransferResult = 17
+	TransferClosingTransferMustBePending                    CreateTransferResult = 64
+	TransferLedgerMustNotBeZero                             CreateTransferResult = 19
+	TransferCodeMustNotBeZero                               CreateTransferResult = 20
+	TransferDebitAccountNotFound                            CreateTransferResult = 21
+	TransferCreditAccountNotFound                           CreateTransferResult = 22
+	TransferAccountsMustHaveTheSameLedger                   CreateTransferResult = 23
+	TransferTransferMustHaveTheSameLedgerAsAccounts         CreateTransferResult = 24
+	TransferPendingTransferNotFound                         CreateTransferResult = 25
+	TransferPendingTransferNotPending                       CreateTransferResult = 26
+	TransferPendingTransferHasDifferentDebitAccountID       CreateTransferResult = 27
+	TransferPendingTransferHasDifferentCreditAccountID      CreateTransferResult = 28
+	TransferPendingTransferHasDifferentLedger               CreateTransferResult = 29
+	TransferPendingTransferHasDifferentCode                 CreateTransferResult = 30
+	TransferExceedsPendingTransferAmount                    CreateTransferResult = 31
+	TransferPendingTransferHasDifferentAmount               CreateTransferResult = 32
+	TransferPendingTransferAlreadyPosted                    CreateTransferResult = 33
+	TransferPendingTransferAlreadyVoided                    CreateTransferResult = 34
+	TransferPendingTransferExpired                          CreateTransferResult = 35
+	TransferImportedEventTimestampMustNotRegress            CreateTransferResult = 60
+	TransferImportedEventTimestampMustPostdateDebitAccount  CreateTransferResult = 61
+	TransferImportedEventTimestampMustPostdateCreditAccount CreateTransferResult = 62
+	TransferImportedEventTimeoutMustBeZero                  CreateTransferResult = 63
+	TransferDebitAccountAlreadyClosed                       CreateTransferResult = 65
+	TransferCreditAccountAlreadyClosed                      CreateTransferResult = 66
+	TransferOverflowsDebitsPending                          CreateTransferResult = 47
+	TransferOverflowsCreditsPending                         CreateTransferResult = 48
+	TransferOverflowsDebitsPosted                           CreateTransferResult = 49
+	TransferOverflowsCreditsPosted                          CreateTransferResult = 50
+	TransferOverflowsDebits                                 CreateTransferResult = 51
+	TransferOverflowsCredits                                CreateTransferResult = 52
+	TransferOverflowsTimeout                                CreateTransferResult = 53
+	TransferExceedsCredits                                  CreateTransferResult = 54
+	TransferExceedsDebits                                   CreateTransferResult = 55
+)
+
+var transferResultNames = map[CreateTransferResult]string{
+	TransferOK:                                              "TransferOK",
+	TransferLinkedEventFailed:                               "TransferLinkedEventFailed",
+	TransferLinkedEventChainOpen:                            "TransferLinkedEventChainOpen",
+	TransferImportedEventExpected:                           "TransferImportedEventExpected",
+	TransferImportedEventNotExpected:                        "TransferImportedEventNotExpected",
+	TransferTimestampMustBeZero:                             "TransferTimestampMustBeZero",
+	TransferImportedEventTimestampOutOfRange:                "TransferImportedEventTimestampOutOfRange",
+	TransferImportedEventTimestampMustNotAdvance:            "TransferImportedEventTimestampMustNotAdvance",
+	TransferReservedFlag:                                    "TransferReservedFlag",
+	TransferIDMustNotBeZero:                                 "TransferIDMustNotBeZero",
+	TransferIDMustNotBeIntMax:                               "TransferIDMustNotBeIntMax",
+	TransferExistsWithDifferentFlags:                        "TransferExistsWithDifferentFlags",
+	TransferExistsWithDifferentPendingID:                    "TransferExistsWithDifferentPendingID",
+	TransferExistsWithDifferentTimeout:                      "TransferExistsWithDifferentTimeout",
+	TransferExistsWithDifferentDebitAccountID:               "TransferExistsWithDifferentDebitAccountID",
+	TransferExistsWithDifferentCreditAccountID:              "TransferExistsWithDifferentCreditAccountID",
+	TransferExistsWithDifferentAmount:                       "TransferExistsWithDifferentAmount",
+	TransferExistsWithDifferentUserData128:                  "TransferExistsWithDifferentUserData128",
+	TransferExistsWithDifferentUserData64:                   "TransferExistsWithDifferentUserData64",
+	TransferExistsWithDifferentUserData32:                   "TransferExistsWithDifferentUserData32",
+	TransferExistsWithDifferentLedger:                       "TransferExistsWithDifferentLedger",
+	TransferExistsWithDifferentCode:                         "TransferExistsWithDifferentCode",
+	TransferExists:                                          "TransferExists",
+	TransferIDAlreadyFailed:                                 "TransferIDAlreadyFailed",
+	TransferFlagsAreMutuallyExclusive:                       "TransferFlagsAreMutuallyExclusive",
+	TransferDebitAccountIDMustNotBeZero:                     "TransferDebitAccountIDMustNotBeZero",
+	TransferDebitAccountIDMustNotBeIntMax:                   "TransferDebitAccountIDMustNotBeIntMax",
+	TransferCreditAccountIDMustNotBeZero:                    "TransferCreditAccountIDMustNotBeZero",
+	TransferCreditAccountIDMustNotBeIntMax:                  "TransferCreditAccountIDMustNotBeIntMax",
+	TransferAccountsMustBeDifferent:                         "TransferAccountsMustBeDifferent",
+	TransferPendingIDMustBeZero:                             "TransferPendingIDMustBeZero",
+	TransferPendingIDMustNotBeZero:                          "TransferPendingIDMustNotBeZero",
+	TransferPendingIDMustNotBeIntMax:                        "TransferPendingIDMustNotBeIntMax",
+	TransferPendingIDMustBeDifferent:                        "TransferPendingIDMustBeDifferent",
+	TransferTimeoutReservedForPendingTransfer:               "TransferTimeoutReservedForPendingTransfer",
+	TransferClosingTransferMustBePending:                    "TransferClosingTransferMustBePending",
+	TransferLedgerMustNotBeZero:                             "TransferLedgerMustNotBeZero",
+	TransferCodeMustNotBeZero:                               "TransferCodeMustNotBeZero",
+	TransferDebitAccountNotFound:                            "TransferDebitAccountNotFound",
+	TransferCreditAccountNotFound:                           "TransferCreditAccountNotFound",
+	TransferAccountsMustHaveTheSameLedger:                   "TransferAccountsMustHaveTheSameLedger",
+	TransferTransferMustHaveTheSameLedgerAsAccounts:         "TransferTransferMustHaveTheSameLedgerAsAccounts",
+	TransferPendingTransferNotFound:                         "TransferPendingTransferNotFound",
+	TransferPendingTransferNotPending:                       "TransferPendingTransferNotPending",
+	TransferPendingTransferHasDifferentDebitAccountID:       "TransferPendingTransferHasDifferentDebitAccountID",
+	TransferPendingTransferHasDifferentCreditAccountID:      "TransferPendingTransferHasDifferentCreditAccountID",
+	TransferPendingTransferHasDifferentLedger:               "TransferPendingTransferHasDifferentLedger",
+	TransferPendingTransferHasDifferentCode:                 "TransferPendingTransferHasDifferentCode",
+	TransferExceedsPendingTransferAmount:                    "TransferExceedsPendingTransferAmount",
+	TransferPendingTransferHasDifferentAmount:               "TransferPendingTransferHasDifferentAmount",
+	TransferPendingTransferAlreadyPosted:                    "TransferPendingTransferAlreadyPosted",
+	TransferPendingTransferAlreadyVoided:                    "TransferPendingTransferAlreadyVoided",
+	TransferPendingTransferExpired:                          "TransferPendingTransferExpired",
+	TransferImportedEventTimestampMustNotRegress:            "TransferImportedEventTimestampMustNotRegress",
+	TransferImportedEventTimestampMustPostdateDebitAccount:  "TransferImportedEventTimestampMustPostdateDebitAccount",
+	TransferImportedEventTimestampMustPostdateCreditAccount: "TransferImportedEventTimestampMustPostdateCreditAccount",
+	TransferImportedEventTimeoutMustBeZero:                  "TransferImportedEventTimeoutMustBeZero",
+	TransferDebitAccountAlreadyClosed:                       "TransferDebitAccountAlreadyClosed",
+	TransferCreditAccountAlreadyClosed:                      "TransferCreditAccountAlreadyClosed",
+	TransferOverflowsDebitsPending:                          "TransferOverflowsDebitsPending",
+	TransferOverflowsCreditsPending:                         "TransferOverflowsCreditsPending",
+	TransferOverflowsDebitsPosted:                           "TransferOverflowsDebitsPosted",
+	TransferOverflowsCreditsPosted:                          "TransferOverflowsCreditsPosted",
+	TransferOverflowsDebits:                                 "TransferOverflowsDebits",
+	TransferOverflowsCredits:                                "TransferOverflowsCredits",
+	TransferOverflowsTimeout:                                "TransferOverflowsTimeout",
+	TransferExceedsCredits:                                  "TransferExceedsCredits",
+	TransferExceedsDebits:                                   "TransferExceedsDebits",
+}
+
+func (r CreateTransferResult) String() string {
+	if name, ok := transferResultNames[r]; ok {
+		return name
+	}
+	return "CreateTransferResult(" + strconv.FormatUint(uint64(r), 10) + ")"
+}
@@ -0,0 +1,159 @@
+// Package money represents monetary amounts as integer minor units of an
+// ISO 4217 currency, and converts them to and from decimal strings such as
+// "12.34". Amounts are never held in floating point.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrInvalidAmount   = errors.New("invalid amount")
+)
+
+// Currency is an ISO 4217 currency. Exponent is the number of digits after
+// the decimal point in its minor unit, such as 2 for cents.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// currencies lists the supported currencies by code.
+var currencies = map[string]Currency{
+	"AUD": {"AUD", 2},
+	"BHD": {"BHD", 3},
+	"BRL": {"BRL", 2},
+	"CAD": {"CAD", 2},
+	"CHF": {"CHF", 2},
+	"CLP": {"CLP", 0},
+	"CNY": {"CNY", 2},
+	"DKK": {"DKK", 2},
+	"EUR": {"EUR", 2},
+	"GBP": {"GBP", 2},
+	"HKD": {"HKD", 2},
+	"INR": {"INR", 2},
+	"ISK": {"ISK", 0},
+	"JOD": {"JOD", 3},
+	"JPY": {"JPY", 0},
+	"KRW": {"KRW", 0},
+	"KWD": {"KWD", 3},
+	"MXN": {"MXN", 2},
+	"NOK": {"NOK", 2},
+	"NZD": {"NZD", 2},
+	"OMR": {"OMR", 3},
+	"PLN": {"PLN", 2},
+	"SEK": {"SEK", 2},
+	"SGD": {"SGD", 2},
+	"TND": {"TND", 3},
+	"USD": {"USD", 2},
+	"VND": {"VND", 0},
+	"ZAR": {"ZAR", 2},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code.
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return currency, nil
+}
+
+// Parse converts a non-negative decimal string such as "12.34" into minor
+// units. It rejects amounts with more decimal places than the currency has.
+func (c Currency) Parse(amount string) (int64, error) {
+	whole, frac, hasPoint := strings.Cut(amount, ".")
+	switch {
+	case whole == "" || !isDigits(whole) || !isDigits(frac):
+		return 0, fmt.Errorf("%w: %q is not a decimal amount", ErrInvalidAmount, amount)
+	case hasPoint && frac == "":
+		return 0, fmt.Errorf("%w: %q is not a decimal amount", ErrInvalidAmount, amount)
+	case len(frac) > c.Exponent:
+		return 0, fmt.Errorf("%w: %s amounts have at most %d decimal places", ErrInvalidAmount, c.Code, c.Exponent)
+	}
+
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", c.Exponent-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, amount)
+	}
+	return minor, nil
+}
+
+// Format converts minor units into a decimal string with exactly the
+// currency's number of decimal places.
+func (c Currency) Format(minor int64) string {
+	if minor < 0 {
+		if minor == math.MinInt64 {
+			return "-" + c.format(strconv.FormatUint(uint64(math.MaxInt64)+1, 10))
+		}
+		return "-" + c.format(strconv.FormatInt(-minor, 10))
+	}
+	return c.format(strconv.FormatInt(minor, 10))
+}
+
+// FormatUint is Format for unsigned amounts, such as ledger balances.
+func (c Currency) FormatUint(minor uint64) string {
+	return c.format(strconv.FormatUint(minor, 10))
+}
+
+func (c Currency) format(digits string) string {
+	if c.Exponent == 0 {
+		return digits
+	}
+	if len(digits) <= c.Exponent {
+		digits = strings.Repeat("0", c.Exponent-len(digits)+1) + digits
+	}
+	point := len(digits) - c.Exponent
+	return digits[:point] + "." + digits[point:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Money is an amount in minor units of a currency.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// Parse converts a decimal amount and an ISO 4217 currency code into Money.
+func Parse(amount, currencyCode string) (Money, error) {
+	currency, err := LookupCurrency(currencyCode)
+	if err != nil {
+		return Money{}, err
+	}
+	minor, err := currency.Parse(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Decimal returns the amount as a decimal string, such as "12.34".
+func (m Money) Decimal() string {
+	return m.Currency.Format(m.Amount)
+}
+
+// String returns the amount and its currency, such as "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency.Code
+}
+
+// MarshalJSON encodes the amount as a decimal string, such as "12.34". The
+// currency is left to the enclosing object, which names it once for all of
+// its amounts.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Decimal())
+}
@@ -0,0 +1,147 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	tests := []struct {
+		code         string
+		wantExponent int
+		wantErr      error
+	}{
+		{"USD", 2, nil},
+		{"JPY", 0, nil},
+		{"KWD", 3, nil},
+		{"usd", 0, ErrUnknownCurrency},
+		{"XYZ", 0, ErrUnknownCurrency},
+		{"", 0, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		currency, err := LookupCurrency(tt.code)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("LookupCurrency(%q) error = %v, want %v", tt.code, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (currency.Code != tt.code || currency.Exponent != tt.wantExponent) {
+			t.Errorf("LookupCurrency(%q) = %+v, want exponent %d", tt.code, currency, tt.wantExponent)
+		}
+	}
+}
+
+func TestCurrencyParse(t *testing.T) {
+	tests := []struct {
+		currency string
+		amount   string
+		want     int64
+		wantErr  bool
+	}{
+		{"USD", "12.34", 1234, false},
+		{"USD", "12.3", 1230, false},
+		{"USD", "12", 1200, false},
+		{"USD", "0.01", 1, false},
+		{"USD", "007.50", 750, false},
+		{"USD", "0", 0, false},
+		{"USD", "12.345", 0, true},
+		{"USD", "12.", 0, true},
+		{"USD", ".5", 0, true},
+		{"USD", "-1.00", 0, true},
+		{"USD", "+1.00", 0, true},
+		{"USD", "1,000.00", 0, true},
+		{"USD", "1e3", 0, true},
+		{"USD", " 1.00", 0, true},
+		{"USD", "", 0, true},
+		{"USD", "92233720368547758.07", math.MaxInt64, false},
+		{"USD", "92233720368547758.08", 0, true},
+		{"JPY", "500", 500, false},
+		{"JPY", "500.0", 0, true},
+		{"KWD", "1.234", 1234, false},
+		{"KWD", "1.2345", 0, true},
+	}
+	for _, tt := range tests {
+		currency, err := LookupCurrency(tt.currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := currency.Parse(tt.amount)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("%s Parse(%q) = %d, %v; want ErrInvalidAmount", tt.currency, tt.amount, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s Parse(%q) = %d, %v; want %d", tt.currency, tt.amount, got, err, tt.want)
+		}
+	}
+}
+
+func TestCurrencyFormat(t *testing.T) {
+	tests := []struct {
+		currency string
+		minor    int64
+		want     string
+	}{
+		{"USD", 1234, "12.34"},
+		{"USD", 5, "0.05"},
+		{"USD", 0, "0.00"},
+		{"USD", -1234, "-12.34"},
+		{"USD", math.MinInt64, "-92233720368547758.08"},
+		{"JPY", 500, "500"},
+		{"KWD", 1, "0.001"},
+	}
+	for _, tt := range tests {
+		currency, err := LookupCurrency(tt.currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := currency.Format(tt.minor); got != tt.want {
+			t.Errorf("%s Format(%d) = %q, want %q", tt.currency, tt.minor, got, tt.want)
+		}
+		if tt.minor >= 0 {
+			if got, err := currency.Parse(tt.want); err != nil || got != tt.minor {
+				t.Errorf("%s Parse(Format(%d)) = %d, %v", tt.currency, tt.minor, got, err)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             string
+		wantErr          error
+	}{
+		{"12.34", "USD", "12.34 USD", nil},
+		{"500", "JPY", "500 JPY", nil},
+		{"1.5", "KWD", "1.500 KWD", nil},
+		{"12.345", "USD", "", ErrInvalidAmount},
+		{"12.34", "XYZ", "", ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.amount, tt.currency)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.amount, tt.currency, err, tt.wantErr)
+			continue
+		}
+		if err == nil && m.String() != tt.want {
+			t.Errorf("Parse(%q, %q) = %s, want %s", tt.amount, tt.currency, m, tt.want)
+		}
+	}
+}
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	m, err := Parse("12.3", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(struct {
+		Balance Money `json:"balance"`
+	}{m})
+	if err != nil || string(data) != `{"balance":"12.30"}` {
+		t.Errorf("json.Marshal = %s, %v", data, err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package ratelimit holds the request rate limits of each route group and the
+// partner plans they vary by. Like the RBAC policy, the limits are data,
+// loaded from a JSON file, so that they can be changed without a release.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Route groups that are rate limited
+const (
+	// Auth is the unauthenticated login and token endpoints, limited by IP
+	Auth = "auth"
+	// Entities is the person and business routes
+	Entities = "entities"
+	// Ledger is every ledger route
+	Ledger = "ledger"
+	// Transfers is the ledger routes that move money, limited on top of Ledger
+	Transfers = "transfers"
+)
+
+// DefaultPlan is the plan of partners who aren't given one, and of callers
+// without a partner
+const DefaultPlan = "default"
+
+// Limit allows Requests requests per Period, in bursts of up to Requests.
+// Callers earn back one request every Period/Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// limitJSON is a Limit in a policy file, with the period in Go duration
+// syntax such as "1m"
+type limitJSON struct {
+	Requests int    `json:"requests"`
+	Period   string `json:"period"`
+}
+
+// UnmarshalJSON parses a limit of the form {"requests": 100, "period": "1m"}
+func (l *Limit) UnmarshalJSON(data []byte) error {
+	var raw limitJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	period, err := time.ParseDuration(raw.Period)
+	if err != nil {
+		return fmt.Errorf("invalid period %q: %w", raw.Period, err)
+	}
+	if raw.Requests <= 0 || period <= 0 {
+		return fmt.Errorf("requests and period must be positive")
+	}
+	l.Requests, l.Period = raw.Requests, period
+	return nil
+}
+
+// Policy sets the limit of each route group for each plan, and the plan of
+// each partner
+type Policy struct {
+	// Limits maps each route group to the limit of each plan
+	Limits map[string]map[string]Limit `json:"limits"`
+	// Plans maps partner IDs to the name of their plan
+	Plans map[string]string `json:"plans"`
+}
+
+// DefaultPolicy is used when no policy file is configured. Every caller is on
+// the default plan.
+var DefaultPolicy = Policy{
+	Limits: map[string]map[string]Limit{
+		Auth:      {DefaultPlan: {Requests: 30, Period: time.Minute}},
+		Entities:  {DefaultPlan: {Requests: 600, Period: time.Minute}},
+		Ledger:    {DefaultPlan: {Requests: 600, Period: time.Minute}},
+		Transfers: {DefaultPlan: {Requests: 120, Period: time.Minute}},
+	},
+}
+
+// LoadPolicy reads a policy from a JSON file of the form
+// {"limits": {"group": {"plan": {"requests": 100, "period": "1m"}}},
+// "plans": {"partner ID": "plan"}}
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("reading rate limit policy: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return Policy{}, fmt.Errorf("parsing rate limit policy %s: %w", path, err)
+	}
+	for partner, plan := range policy.Plans {
+		found := false
+		for _, limits := range policy.Limits {
+			_, ok := limits[plan]
+			found = found || ok
+		}
+		if !found {
+			return Policy{}, fmt.Errorf("rate limit policy %s: partner %s has plan %q, which sets no limits", path, partner, plan)
+		}
+	}
+	return policy, nil
+}
+
+// Limit returns the limit of group for the plan of partnerID, falling back to
+// the default plan. Requests are not limited if neither sets one.
+func (p Policy) Limit(group, partnerID string) (Limit, bool) {
+	limits := p.Limits[group]
+	if plan, ok := p.Plans[partnerID]; ok {
+		if limit, ok := limits[plan]; ok {
+			return limit, true
+		}
+	}
+	limit, ok := limits[DefaultPlan]
+	return limit, ok
+}
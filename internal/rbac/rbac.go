@@ -0,0 +1,81 @@
+// Package rbac maps roles to the permissions they grant. The mapping is data,
+// loaded from a JSON file, so that permissions can be changed without a
+// release.
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Permissions guarding the API's routes.
+const (
+	EntitiesRead       = "entities:read"
+	EntitiesWrite      = "entities:write"
+	LedgerRead         = "ledger:read"
+	LedgerWrite        = "ledger:write"
+	LedgerTransfer     = "ledger:transfer"
+	UsersManage        = "users:manage"
+	APIKeysManage      = "api_keys:manage"
+	OAuthClientsManage = "oauth_clients:manage"
+	SigningKeysManage  = "signing_keys:manage"
+	PasswordChange     = "auth:password"
+	AuditRead          = "audit:read"
+	// PIIReveal lets a caller see personal data that responses mask by
+	// default, such as full SSNs
+	PIIReveal = "pii:reveal"
+	// TenantsAll lets a caller act for any partner and use the cross-tenant
+	// admin view
+	TenantsAll = "tenants:all"
+)
+
+// Policy maps each role to the permissions it grants. A permission of "*"
+// grants everything, and one of "<resource>:*" grants every permission on the
+// resource.
+type Policy map[string][]string
+
+// DefaultPolicy is used when no policy file is configured. Admins can do
+// anything, users can only look, and partners can onboard customers and move
+// money within the scopes of their API key.
+var DefaultPolicy = Policy{
+	"admin": {"*"},
+	"user":  {EntitiesRead, LedgerRead, PasswordChange},
+	"partner": {
+		EntitiesRead, EntitiesWrite,
+		LedgerRead, LedgerWrite, LedgerTransfer,
+	},
+}
+
+// LoadPolicy reads a policy from a JSON file of the form
+// {"role": ["permission", ...]}
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading RBAC policy: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("parsing RBAC policy %s: %w", path, err)
+	}
+	for role, permissions := range policy {
+		for _, permission := range permissions {
+			if permission == "" {
+				return nil, fmt.Errorf("RBAC policy %s: empty permission for role %q", path, role)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// Allows reports whether role grants permission
+func (p Policy) Allows(role, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+	for _, granted := range p[role] {
+		if granted == "*" || granted == permission || granted == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
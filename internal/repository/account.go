@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// Owner types of an account.
+const (
+	OwnerTypePerson   = "person"
+	OwnerTypeBusiness = "business"
+)
+
+// Statuses of an account.
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// AccountEntity represents an entry in the account registry, which links a
+// ledger account to the person or business entity that owns it and the
+// partner it was opened through
+type AccountEntity struct {
+	ID              uuid.UUID `json:"id,omitempty"`
+	PartnerID       uuid.UUID `json:"partner_id"`
+	OwnerType       string    `json:"owner_type"`
+	OwnerID         uuid.UUID `json:"owner_id"`
+	ProductType     string    `json:"product_type"`
+	Currency        string    `json:"currency"`
+	Status          string    `json:"status"`
+	LedgerAccountID uuid.UUID `json:"ledger_account_id"`
+	CreatedAt       time.Time `json:"created_at,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at,omitempty"`
+}
+
+// AccountRepository provides methods to interact with the account registry
+type AccountRepository interface {
+	Create(ctx context.Context, account *AccountEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*AccountEntity, error)
+	GetByLedgerAccountID(ctx context.Context, ledgerAccountID uuid.UUID) (*AccountEntity, error)
+	ListByOwner(ctx context.Context, ownerType string, ownerID uuid.UUID) ([]*AccountEntity, error)
+	ListByLedgerAccountIDs(ctx context.Context, ledgerAccountIDs []uuid.UUID) ([]*AccountEntity, error)
+}
+
+type accountRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewAccountRestRepository creates a new account registry repository using Supabase REST API
+func NewAccountRestRepository(client *supabase.Client) AccountRepository {
+	return &accountRestRepository{
+		client: client,
+		table:  "accounts",
+	}
+}
+
+// Create inserts a new account into the registry
+func (r *accountRestRepository) Create(ctx context.Context, account *AccountEntity) error {
+	if account.Status == "" {
+		account.Status = AccountStatusActive
+	}
+	partnerID, err := partnerOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"owner_type":        account.OwnerType,
+		"owner_id":          account.OwnerID,
+		"product_type":      account.ProductType,
+		"currency":          account.Currency,
+		"status":            account.Status,
+		"ledger_account_id": account.LedgerAccountID,
+		"partner_id":        partnerID,
+	}
+	if account.ID != uuid.Nil {
+		payload["id"] = account.ID
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*AccountEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no account was created")
+	}
+
+	account.ID = created[0].ID
+	account.PartnerID = partnerID
+	account.CreatedAt = created[0].CreatedAt
+	account.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByID retrieves an account of the request's partner by its registry ID
+func (r *accountRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*AccountEntity, error) {
+	queryParams, err := partnerQuery(ctx, byID(id))
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstAccount(respBody)
+}
+
+// GetByLedgerAccountID retrieves the account registered for a ledger account,
+// if it belongs to the request's partner
+func (r *accountRestRepository) GetByLedgerAccountID(ctx context.Context, ledgerAccountID uuid.UUID) (*AccountEntity, error) {
+	queryParams, err := partnerQuery(ctx, map[string]string{
+		"ledger_account_id": fmt.Sprintf("eq.%s", ledgerAccountID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstAccount(respBody)
+}
+
+// ListByOwner retrieves the accounts of the request's partner owned by an
+// entity, oldest first
+func (r *accountRestRepository) ListByOwner(ctx context.Context, ownerType string, ownerID uuid.UUID) ([]*AccountEntity, error) {
+	queryParams, err := partnerQuery(ctx, map[string]string{
+		"owner_type": fmt.Sprintf("eq.%s", ownerType),
+		"owner_id":   fmt.Sprintf("eq.%s", ownerID),
+		"order":      "created_at.asc",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.list(ctx, queryParams)
+}
+
+// ListByLedgerAccountIDs retrieves the accounts of the request's partner that
+// are registered for any of the given ledger accounts
+func (r *accountRestRepository) ListByLedgerAccountIDs(ctx context.Context, ledgerAccountIDs []uuid.UUID) ([]*AccountEntity, error) {
+	if len(ledgerAccountIDs) == 0 {
+		return nil, nil
+	}
+	idStrs := make([]string, len(ledgerAccountIDs))
+	for i, id := range ledgerAccountIDs {
+		idStrs[i] = id.String()
+	}
+	queryParams, err := partnerQuery(ctx, map[string]string{
+		"ledger_account_id": fmt.Sprintf("in.(%s)", strings.Join(idStrs, ",")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.list(ctx, queryParams)
+}
+
+func (r *accountRestRepository) list(ctx context.Context, queryParams map[string]string) ([]*AccountEntity, error) {
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []*AccountEntity
+	if err := json.Unmarshal(respBody, &accounts); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return accounts, nil
+}
+
+func firstAccount(respBody []byte) (*AccountEntity, error) {
+	var accounts []*AccountEntity
+	if err := json.Unmarshal(respBody, &accounts); err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil // Not found
+	}
+	return accounts[0], nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// APIKeyEntity represents an API key issued to a fintech partner. Only the
+// hash of the secret key is stored; Prefix is the public part of the key that
+// it is looked up by.
+type APIKeyEntity struct {
+	ID         uuid.UUID  `json:"id,omitempty"`
+	PartnerID  uuid.UUID  `json:"partner_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"key_hash"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// RotatedFromID is the key this one replaced, if it was issued by a rotation.
+	RotatedFromID *uuid.UUID `json:"rotated_from_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at,omitempty"`
+}
+
+// APIKeyRepository provides methods to interact with API key storage
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKeyEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*APIKeyEntity, error)
+	GetByPrefix(ctx context.Context, prefix string) (*APIKeyEntity, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*APIKeyEntity, error)
+	SetExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type apiKeyRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewAPIKeyRestRepository creates a new API key repository using Supabase REST API
+func NewAPIKeyRestRepository(client *supabase.Client) APIKeyRepository {
+	return &apiKeyRestRepository{
+		client: client,
+		table:  "api_keys",
+	}
+}
+
+// Create inserts a new API key
+func (r *apiKeyRestRepository) Create(ctx context.Context, key *APIKeyEntity) error {
+	payload := map[string]interface{}{
+		"partner_id": key.PartnerID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"key_hash":   key.KeyHash,
+		"scopes":     key.Scopes,
+		"expires_at": key.ExpiresAt,
+	}
+	if key.RotatedFromID != nil {
+		payload["rotated_from_id"] = key.RotatedFromID
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*APIKeyEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no API key was created")
+	}
+
+	key.ID = created[0].ID
+	key.CreatedAt = created[0].CreatedAt
+	key.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByID retrieves an API key by its ID
+func (r *apiKeyRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*APIKeyEntity, error) {
+	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return firstAPIKey(respBody)
+}
+
+// GetByPrefix retrieves the API key with the given public prefix
+func (r *apiKeyRestRepository) GetByPrefix(ctx context.Context, prefix string) (*APIKeyEntity, error) {
+	queryParams := map[string]string{
+		"prefix": fmt.Sprintf("eq.%s", prefix),
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstAPIKey(respBody)
+}
+
+// List retrieves API keys, newest first, optionally only those of one partner
+func (r *apiKeyRestRepository) List(ctx context.Context, partnerID *uuid.UUID) ([]*APIKeyEntity, error) {
+	queryParams := map[string]string{
+		"order": "created_at.desc",
+	}
+	if partnerID != nil {
+		queryParams["partner_id"] = fmt.Sprintf("eq.%s", partnerID)
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*APIKeyEntity
+	if err := json.Unmarshal(respBody, &keys); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return keys, nil
+}
+
+// SetExpiry sets the time after which an API key is no longer accepted
+func (r *apiKeyRestRepository) SetExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"expires_at": expiresAt,
+	})
+	return err
+}
+
+// Revoke marks an API key as revoked
+func (r *apiKeyRestRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"revoked_at": revokedAt,
+	})
+	return err
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *apiKeyRestRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"last_used_at": usedAt,
+	})
+	return err
+}
+
+func firstAPIKey(respBody []byte) (*APIKeyEntity, error) {
+	var keys []*APIKeyEntity
+	if err := json.Unmarshal(respBody, &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil // Not found
+	}
+	return keys[0], nil
+}
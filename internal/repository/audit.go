@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// AuditEntryEntity represents an entry of the audit log. Entries are only ever
+// appended. Each one holds the hash of the one before it, so that changing or
+// removing an entry breaks the chain.
+type AuditEntryEntity struct {
+	ID         uuid.UUID              `json:"id,omitempty"`
+	Sequence   int64                  `json:"sequence"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	ActorID    string                 `json:"actor_id"`
+	ActorRole  string                 `json:"actor_role"`
+	PartnerID  *uuid.UUID             `json:"partner_id"`
+	RequestID  string                 `json:"request_id"`
+	IP         string                 `json:"ip"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	Changes    map[string]AuditChange `json:"changes"`
+	PrevHash   string                 `json:"prev_hash"`
+	Hash       string                 `json:"hash"`
+}
+
+// AuditChange is the value of a field before and after an action
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter selects audit entries. Empty fields match every entry. Entries
+// come newest first, or oldest first if Ascending is set.
+type AuditFilter struct {
+	PartnerID  *uuid.UUID
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	Since      *time.Time
+	Until      *time.Time
+	// BeforeSequence or AfterSequence pages through entries when non-zero;
+	// BeforeSequence wins if both are set
+	BeforeSequence int64
+	AfterSequence  int64
+	Limit          int
+	Ascending      bool
+}
+
+// AuditRepository provides methods to interact with audit log storage. There
+// is deliberately no way to change or delete an entry.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *AuditEntryEntity) error
+	// Last returns the newest entry, or nil if the log is empty
+	Last(ctx context.Context) (*AuditEntryEntity, error)
+	List(ctx context.Context, filter AuditFilter) ([]*AuditEntryEntity, error)
+}
+
+type auditRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewAuditRestRepository creates a new audit log repository using Supabase REST API
+func NewAuditRestRepository(client *supabase.Client) AuditRepository {
+	return &auditRestRepository{
+		client: client,
+		table:  "audit_log",
+	}
+}
+
+// Append inserts a new entry. The table's unique sequence makes an entry
+// that doesn't extend the newest one fail rather than fork the chain.
+func (r *auditRestRepository) Append(ctx context.Context, entry *AuditEntryEntity) error {
+	payload := map[string]interface{}{
+		"sequence":    entry.Sequence,
+		"occurred_at": entry.OccurredAt,
+		"actor_id":    entry.ActorID,
+		"actor_role":  entry.ActorRole,
+		"partner_id":  entry.PartnerID,
+		"request_id":  entry.RequestID,
+		"ip":          entry.IP,
+		"action":      entry.Action,
+		"entity_type": entry.EntityType,
+		"entity_id":   entry.EntityID,
+		"changes":     entry.Changes,
+		"prev_hash":   entry.PrevHash,
+		"hash":        entry.Hash,
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*AuditEntryEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no audit entry was created")
+	}
+	entry.ID = created[0].ID
+	return nil
+}
+
+// Last retrieves the entry with the highest sequence
+func (r *auditRestRepository) Last(ctx context.Context) (*AuditEntryEntity, error) {
+	entries, err := r.List(ctx, AuditFilter{Limit: 1})
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// List retrieves the entries matching filter
+func (r *auditRestRepository) List(ctx context.Context, filter AuditFilter) ([]*AuditEntryEntity, error) {
+	queryParams := map[string]string{
+		"order": "sequence.desc",
+		"limit": strconv.Itoa(filter.Limit),
+	}
+	if filter.Ascending {
+		queryParams["order"] = "sequence.asc"
+	}
+	if filter.PartnerID != nil {
+		queryParams["partner_id"] = fmt.Sprintf("eq.%s", filter.PartnerID)
+	}
+	for column, value := range map[string]string{
+		"actor_id":    filter.ActorID,
+		"action":      filter.Action,
+		"entity_type": filter.EntityType,
+		"entity_id":   filter.EntityID,
+	} {
+		if value != "" {
+			queryParams[column] = fmt.Sprintf("eq.%s", value)
+		}
+	}
+	// Each column can only be filtered once, so ranges on one column are
+	// combined with and=
+	switch {
+	case filter.Since != nil && filter.Until != nil:
+		queryParams["and"] = fmt.Sprintf("(occurred_at.gte.%s,occurred_at.lt.%s)",
+			filter.Since.UTC().Format(time.RFC3339Nano), filter.Until.UTC().Format(time.RFC3339Nano))
+	case filter.Since != nil:
+		queryParams["occurred_at"] = fmt.Sprintf("gte.%s", filter.Since.UTC().Format(time.RFC3339Nano))
+	case filter.Until != nil:
+		queryParams["occurred_at"] = fmt.Sprintf("lt.%s", filter.Until.UTC().Format(time.RFC3339Nano))
+	}
+	if filter.BeforeSequence != 0 {
+		queryParams["sequence"] = fmt.Sprintf("lt.%d", filter.BeforeSequence)
+	} else if filter.AfterSequence != 0 {
+		queryParams["sequence"] = fmt.Sprintf("gt.%d", filter.AfterSequence)
+	}
+
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*AuditEntryEntity
+	if err := json.Unmarshal(respBody, &entries); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return entries, nil
+}
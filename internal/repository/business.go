@@ -9,12 +9,14 @@ import (
 	"time"
 
 	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
 	"github.com/google/uuid"
 )
 
 // BusinessEntity represents a business entity in the database
 type BusinessEntity struct {
 	ID                 uuid.UUID  `json:"id,omitempty"`
+	PartnerID          uuid.UUID  `json:"partner_id"`
 	Name               string     `json:"name"`
 	RegistrationNumber string     `json:"registration_number"`
 	Address            string     `json:"address"`
@@ -35,18 +37,24 @@ type BusinessRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*BusinessEntity, error)
 	Update(ctx context.Context, business *BusinessEntity) error
 	List(ctx context.Context, limit, offset int) ([]*BusinessEntity, error)
+	// Reencrypt encrypts the tax IDs of up to limit business entities after
+	// the given ID that are plaintext or under an old key
+	Reencrypt(ctx context.Context, after uuid.UUID, limit int) (*ReencryptPage, error)
 }
 
 type businessRestRepository struct {
 	client *supabase.Client
 	table  string
+	keys   *fieldcrypt.Keyring
 }
 
-// NewBusinessRestRepository creates a new business repository using Supabase REST API
-func NewBusinessRestRepository(client *supabase.Client) BusinessRepository {
+// NewBusinessRestRepository creates a new business repository using Supabase REST API.
+// Tax IDs are encrypted with keys.
+func NewBusinessRestRepository(client *supabase.Client, keys *fieldcrypt.Keyring) BusinessRepository {
 	return &businessRestRepository{
 		client: client,
 		table:  "business_entities",
+		keys:   keys,
 	}
 }
 
@@ -55,6 +63,15 @@ func (r *businessRestRepository) Create(ctx context.Context, business *BusinessE
 		business.KYCStatus = "pending"
 	}
 
+	partnerID, err := partnerOf(ctx)
+	if err != nil {
+		return err
+	}
+	taxID, err := encryptField(r.keys, business.TaxID, r.table+".tax_id")
+	if err != nil {
+		return err
+	}
+
 	payload := map[string]interface{}{
 		"name":                business.Name,
 		"registration_number": business.RegistrationNumber,
@@ -62,6 +79,8 @@ func (r *businessRestRepository) Create(ctx context.Context, business *BusinessE
 		"country":             business.Country,
 		"kyc_status":          business.KYCStatus,
 		"kyc_verified_at":     business.KYCVerifiedAt,
+		"tax_id":              taxID,
+		"partner_id":          partnerID,
 	}
 
 	// Only include the ID if already set (non-zero)
@@ -84,6 +103,7 @@ func (r *businessRestRepository) Create(ctx context.Context, business *BusinessE
 
 	created := createdBusinesses[0]
 	business.ID = created.ID
+	business.PartnerID = partnerID
 	business.CreatedAt = created.CreatedAt
 	business.UpdatedAt = created.UpdatedAt
 
@@ -91,7 +111,11 @@ func (r *businessRestRepository) Create(ctx context.Context, business *BusinessE
 }
 
 func (r *businessRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*BusinessEntity, error) {
-	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	queryParams, err := partnerQuery(ctx, byID(id))
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +126,9 @@ func (r *businessRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*Bu
 	if len(businesses) == 0 {
 		return nil, nil
 	}
+	if err := r.decrypt(businesses[0]); err != nil {
+		return nil, err
+	}
 	return businesses[0], nil
 }
 
@@ -109,6 +136,10 @@ func (r *businessRestRepository) Update(ctx context.Context, business *BusinessE
 	if business.ID == uuid.Nil {
 		return errors.New("business ID is required for update")
 	}
+	taxID, err := encryptField(r.keys, business.TaxID, r.table+".tax_id")
+	if err != nil {
+		return err
+	}
 
 	payload := map[string]interface{}{
 		"name":                business.Name,
@@ -117,9 +148,14 @@ func (r *businessRestRepository) Update(ctx context.Context, business *BusinessE
 		"country":             business.Country,
 		"kyc_status":          business.KYCStatus,
 		"kyc_verified_at":     business.KYCVerifiedAt,
+		"tax_id":              taxID,
 	}
 
-	respBody, err := r.client.Update(ctx, r.table, business.ID.String(), payload)
+	queryParams, err := partnerQuery(ctx, byID(business.ID))
+	if err != nil {
+		return err
+	}
+	respBody, err := r.client.UpdateWhere(ctx, r.table, queryParams, payload)
 	if err != nil {
 		return err
 	}
@@ -147,10 +183,13 @@ func (r *businessRestRepository) List(ctx context.Context, limit, offset int) ([
 	if offset < 0 {
 		offset = 0
 	}
-	queryParams := map[string]string{
+	queryParams, err := partnerQuery(ctx, map[string]string{
 		"limit":  strconv.Itoa(limit),
 		"offset": strconv.Itoa(offset),
 		"order":  "created_at.desc",
+	})
+	if err != nil {
+		return nil, err
 	}
 	respBody, err := r.client.Select(ctx, r.table, queryParams)
 	if err != nil {
@@ -160,5 +199,63 @@ func (r *businessRestRepository) List(ctx context.Context, limit, offset int) ([
 	if err := json.Unmarshal(respBody, &businesses); err != nil {
 		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
+	for _, business := range businesses {
+		if err := r.decrypt(business); err != nil {
+			return nil, err
+		}
+	}
 	return businesses, nil
+}
+
+// Reencrypt encrypts the stored tax IDs of a page of business entities again
+// where they are plaintext or under an old key. Other columns are left alone,
+// and so are records whose tax ID changed after it was read.
+func (r *businessRestRepository) Reencrypt(ctx context.Context, after uuid.UUID, limit int) (*ReencryptPage, error) {
+	queryParams, err := reencryptQuery(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID    uuid.UUID `json:"id"`
+		TaxID *string   `json:"tax_id"`
+	}
+	if err := json.Unmarshal(respBody, &rows); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	page := &ReencryptPage{Scanned: len(rows), Last: after}
+	for _, row := range rows {
+		page.Last = row.ID
+		taxID, stale, err := reencryptField(r.keys, row.TaxID, r.table+".tax_id")
+		if err != nil {
+			return nil, fmt.Errorf("re-encrypting tax_id of business %s: %w", row.ID, err)
+		}
+		if !stale {
+			continue
+		}
+		queryParams := byID(row.ID)
+		whereUnchanged(queryParams, "tax_id", row.TaxID)
+		updated, err := updateIfUnchanged(ctx, r.client, r.table, queryParams, map[string]interface{}{"tax_id": taxID})
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			page.Updated++
+		} else {
+			page.Skipped++
+		}
+	}
+	return page, nil
+}
+
+// decrypt decrypts a stored business's tax ID in place
+func (r *businessRestRepository) decrypt(business *BusinessEntity) error {
+	if err := decryptField(r.keys, business.TaxID, r.table+".tax_id"); err != nil {
+		return fmt.Errorf("decrypting tax_id of business %s: %w", business.ID, err)
+	}
+	return nil
 }
\ No newline at end of file
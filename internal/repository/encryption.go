@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
+	"github.com/google/uuid"
+)
+
+// encryptField encrypts an optional field for storage, leaving it null if it
+// is unset. field names the column, such as "person_entities.ssn".
+func encryptField(keys *fieldcrypt.Keyring, value *string, field string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return keys.Encrypt(*value, field)
+}
+
+// decryptField decrypts an optional field read from storage in place
+func decryptField(keys *fieldcrypt.Keyring, value *string, field string) error {
+	if value == nil {
+		return nil
+	}
+	plaintext, err := keys.Decrypt(*value, field)
+	if err != nil {
+		return err
+	}
+	*value = plaintext
+	return nil
+}
+
+// ReencryptPage reports what a Reencrypt call did with one page of records
+type ReencryptPage struct {
+	// Scanned is the number of records read; fewer than the limit means
+	// there are no more
+	Scanned int
+	// Updated is the number of records that were written back
+	Updated int
+	// Skipped is the number of records that changed after they were read and
+	// were left alone. The change was written with the current key, so they
+	// need nothing more.
+	Skipped int
+	// Last is the ID of the last record read, to pass to the next call
+	Last uuid.UUID
+}
+
+// reencryptField returns a stored field encrypted again with the current key
+// and true if it is plaintext or under an old key, or false if it is up to
+// date or unset
+func reencryptField(keys *fieldcrypt.Keyring, value *string, field string) (interface{}, bool, error) {
+	if value == nil || !keys.Stale(*value) {
+		return nil, false, nil
+	}
+	plaintext, err := keys.Decrypt(*value, field)
+	if err != nil {
+		return nil, false, err
+	}
+	encrypted, err := keys.Encrypt(plaintext, field)
+	if err != nil {
+		return nil, false, err
+	}
+	return encrypted, true, nil
+}
+
+// whereUnchanged adds to an update query the condition that a field still
+// holds the value that was read, so that the update doesn't overwrite a
+// concurrent one
+func whereUnchanged(queryParams map[string]string, column string, value *string) {
+	if value == nil {
+		queryParams[column] = "is.null"
+		return
+	}
+	queryParams[column] = "eq." + *value
+}
+
+// updateIfUnchanged applies an update made with whereUnchanged conditions and
+// reports whether the record still matched them
+func updateIfUnchanged(ctx context.Context, client *supabase.Client, table string, queryParams map[string]string, data interface{}) (bool, error) {
+	respBody, err := client.UpdateWhere(ctx, table, queryParams, data)
+	if err != nil {
+		return false, err
+	}
+	var updated []json.RawMessage
+	if err := json.Unmarshal(respBody, &updated); err != nil {
+		return false, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return len(updated) > 0, nil
+}
+
+// reencryptQuery is the query for a page of records in ID order after the
+// given one
+func reencryptQuery(ctx context.Context, after uuid.UUID, limit int) (map[string]string, error) {
+	queryParams := map[string]string{
+		"limit": strconv.Itoa(limit),
+		"order": "id.asc",
+	}
+	if after != uuid.Nil {
+		queryParams["id"] = fmt.Sprintf("gt.%s", after)
+	}
+	return partnerQuery(ctx, queryParams)
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
+	"github.com/google/uuid"
+)
+
+// fakeTable serves one PostgREST table from memory. Selects return every row
+// on the first page and none after it; updates apply eq and is.null filters.
+// afterSelect runs once a select has been answered, to change rows before
+// the caller writes back what it read.
+type fakeTable struct {
+	mu          sync.Mutex
+	rows        []map[string]interface{}
+	afterSelect func(rows []map[string]interface{})
+}
+
+func (f *fakeTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet:
+		rows := f.rows
+		if strings.HasPrefix(query.Get("id"), "gt.") {
+			rows = nil
+		}
+		json.NewEncoder(w).Encode(rows)
+		if f.afterSelect != nil && rows != nil {
+			f.afterSelect(f.rows)
+		}
+	case http.MethodPatch:
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		updated := []map[string]interface{}{}
+		for _, row := range f.rows {
+			if matches(row, query) {
+				for column, value := range data {
+					row[column] = value
+				}
+				updated = append(updated, row)
+			}
+		}
+		json.NewEncoder(w).Encode(updated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func matches(row map[string]interface{}, query map[string][]string) bool {
+	for column, values := range query {
+		value, _ := row[column].(string)
+		switch filter := values[0]; {
+		case filter == "is.null":
+			if row[column] != nil {
+				return false
+			}
+		case strings.HasPrefix(filter, "eq."):
+			if row[column] == nil || value != strings.TrimPrefix(filter, "eq.") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newTestKeys(t *testing.T, current string) *fieldcrypt.Keyring {
+	t.Helper()
+	keys, err := fieldcrypt.NewKeyring(map[string][]byte{
+		"1": bytes.Repeat([]byte{1}, fieldcrypt.KeySize),
+		"2": bytes.Repeat([]byte{2}, fieldcrypt.KeySize),
+	}, current, bytes.Repeat([]byte{9}, fieldcrypt.KeySize))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return keys
+}
+
+func TestReencrypt(t *testing.T) {
+	old, keys := newTestKeys(t, "1"), newTestKeys(t, "2")
+	encrypt := func(k *fieldcrypt.Keyring, value, field string) string {
+		encrypted, err := k.Encrypt(value, field)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		return encrypted
+	}
+	const id = "6f1c2f5e-0000-4000-8000-000000000001"
+
+	tests := []struct {
+		name        string
+		table       string
+		row         map[string]interface{}
+		afterSelect func(row map[string]interface{})
+		wantUpdated int
+		wantSkipped int
+		// want is the plaintext each field should hold afterwards
+		want map[string]string
+	}{
+		{
+			name:  "person under an old key",
+			table: "person_entities",
+			row: map[string]interface{}{
+				"id":            id,
+				"ssn":           encrypt(old, "123-45-6789", "person_entities.ssn"),
+				"government_id": "D1234567",
+			},
+			wantUpdated: 1,
+			want:        map[string]string{"ssn": "123-45-6789", "government_id": "D1234567"},
+		},
+		{
+			name:  "person whose SSN the server changed meanwhile",
+			table: "person_entities",
+			row: map[string]interface{}{
+				"id":            id,
+				"ssn":           encrypt(old, "123-45-6789", "person_entities.ssn"),
+				"government_id": nil,
+			},
+			afterSelect: func(row map[string]interface{}) {
+				row["ssn"] = encrypt(keys, "987-65-4321", "person_entities.ssn")
+				row["ssn_index"] = keys.BlindIndex("987654321", "person_entities.ssn")
+			},
+			wantSkipped: 1,
+			want:        map[string]string{"ssn": "987-65-4321"},
+		},
+		{
+			name:  "person whose government ID the server set meanwhile",
+			table: "person_entities",
+			row: map[string]interface{}{
+				"id":            id,
+				"ssn":           nil,
+				"government_id": "D1234567",
+			},
+			afterSelect: func(row map[string]interface{}) {
+				row["government_id"] = encrypt(keys, "D7654321", "person_entities.government_id")
+			},
+			wantSkipped: 1,
+			want:        map[string]string{"government_id": "D7654321"},
+		},
+		{
+			name:        "business under an old key",
+			table:       "business_entities",
+			row:         map[string]interface{}{"id": id, "tax_id": encrypt(old, "12-3456789", "business_entities.tax_id")},
+			wantUpdated: 1,
+			want:        map[string]string{"tax_id": "12-3456789"},
+		},
+		{
+			name:  "business whose tax ID the server changed meanwhile",
+			table: "business_entities",
+			row:   map[string]interface{}{"id": id, "tax_id": "12-3456789"},
+			afterSelect: func(row map[string]interface{}) {
+				row["tax_id"] = encrypt(keys, "98-7654321", "business_entities.tax_id")
+			},
+			wantSkipped: 1,
+			want:        map[string]string{"tax_id": "98-7654321"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeTable{rows: []map[string]interface{}{tt.row}}
+			if tt.afterSelect != nil {
+				table.afterSelect = func(rows []map[string]interface{}) { tt.afterSelect(rows[0]) }
+			}
+			server := httptest.NewServer(table)
+			defer server.Close()
+			client := supabase.NewClient(server.URL, "key")
+
+			reencrypt := NewPersonRestRepository(client, keys).Reencrypt
+			if tt.table == "business_entities" {
+				reencrypt = NewBusinessRestRepository(client, keys).Reencrypt
+			}
+			page, err := reencrypt(tenant.WithAllPartners(context.Background()), uuid.Nil, 10)
+			if err != nil {
+				t.Fatalf("Reencrypt: %v", err)
+			}
+			if page.Scanned != 1 || page.Updated != tt.wantUpdated || page.Skipped != tt.wantSkipped {
+				t.Errorf("Reencrypt = %+v, want %d updated and %d skipped", page, tt.wantUpdated, tt.wantSkipped)
+			}
+
+			row := table.rows[0]
+			for field, want := range tt.want {
+				stored, _ := row[field].(string)
+				if keys.Stale(stored) {
+					t.Errorf("%s is not under the current key: %q", field, stored)
+				}
+				if got, err := keys.Decrypt(stored, tt.table+"."+field); err != nil || got != want {
+					t.Errorf("%s = %q, %v; want %q", field, got, err, want)
+				}
+			}
+			if ssn, ok := tt.want["ssn"]; ok {
+				digits := strings.ReplaceAll(ssn, "-", "")
+				if row["ssn_index"] != keys.BlindIndex(digits, "person_entities.ssn") {
+					t.Errorf("ssn_index = %v, want the index of %s", row["ssn_index"], ssn)
+				}
+			}
+		})
+	}
+}
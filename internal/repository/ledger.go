@@ -3,43 +3,447 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
-	"github.com/Cassandra-Labs-Foundation/core/internal/clients/tigerbeetle"
+	"github.com/Cassandra-Labs-Foundation/core/internal/idempotency"
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
 	"github.com/google/uuid"
 )
 
+// Transfer codes record why money moved. Ordinary transfers between accounts
+// carry the code the repository is configured with.
+const (
+	DepositCode        uint16 = 1001
+	WithdrawalCode     uint16 = 1002
+	OpeningDepositCode uint16 = 1003
+	ExchangeCode       uint16 = 1004
+)
+
+// transferScanPageSize is the number of transfers fetched per backend call
+// when transfers have to be filtered after they are read, such as by amount.
+const transferScanPageSize = 1000
+
+// LedgerBackend is implemented by the stores that can hold the ledger, such as
+// the TigerBeetle client and the in-memory ledger. As with TigerBeetle, batch
+// creates only report the events that failed.
+type LedgerBackend interface {
+	CreateAccounts(ctx context.Context, accounts []ledger.Account) ([]ledger.AccountEventResult, error)
+	CreateTransfers(ctx context.Context, transfers []ledger.Transfer) ([]ledger.TransferEventResult, error)
+	LookupAccounts(ctx context.Context, ids []uuid.UUID) ([]ledger.Account, error)
+	LookupTransfers(ctx context.Context, ids []uuid.UUID) ([]ledger.Transfer, error)
+	GetAccountTransfers(ctx context.Context, filter ledger.AccountFilter) ([]ledger.Transfer, error)
+}
+
 // LedgerRepository defines methods for ledger operations.
 type LedgerRepository interface {
-	CreateAccount(ctx context.Context, initialBalance int64) (string, error)
-	Transfer(ctx context.Context, fromAccountID, toAccountID string, amount int64) error
+	CreateAccount(ctx context.Context, ledgerID uint32) (string, error)
+	CreateSettlementAccount(ctx context.Context, id uuid.UUID, ledgerID uint32) error
+	CreateLiquidityAccount(ctx context.Context, id uuid.UUID, ledgerID uint32) error
+	Deposit(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error)
+	OpeningDeposit(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error)
+	Withdraw(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error)
+	Transfer(ctx context.Context, leg TransferLeg) (string, error)
+	TransferBatch(ctx context.Context, legs []TransferLeg) ([]string, error)
+	CreatePendingTransfer(ctx context.Context, leg TransferLeg, timeout uint32) (string, error)
+	PostPendingTransfer(ctx context.Context, pendingID uuid.UUID, amount int64) (string, error)
+	VoidPendingTransfer(ctx context.Context, pendingID uuid.UUID) (string, error)
+	GetAccount(ctx context.Context, id uuid.UUID) (*ledger.Account, error)
+	GetAccounts(ctx context.Context, ids []uuid.UUID) ([]*ledger.Account, error)
+	GetTransfer(ctx context.Context, id uuid.UUID) (*ledger.Transfer, error)
+	ListTransfers(ctx context.Context, filter TransferFilter) ([]ledger.Transfer, bool, error)
+}
+
+// TransferLeg describes a transfer between two accounts, on its own or as one
+// posting of an atomic batch. A zero Code uses the repository's code. The user
+// data fields are stored on the transfer as given.
+type TransferLeg struct {
+	Ledger        uint32
+	FromAccountID string
+	ToAccountID   string
+	Amount        int64
+	Code          uint16
+	UserData128   uuid.UUID
+	UserData64    uint64
+	UserData32    uint32
+}
+
+// TransferFilter narrows the transfers returned by ListTransfers.
+// Bounds are inclusive and a zero value leaves a bound open.
+type TransferFilter struct {
+	AccountID    uuid.UUID
+	TimestampMin uint64
+	TimestampMax uint64
+	// Debits and Credits select the side the account is on; at least one must be set.
+	Debits    bool
+	Credits   bool
+	MinAmount uint64
+	MaxAmount uint64
+	// Reversed returns the newest transfers first.
+	Reversed bool
+	Limit    int
 }
 
 type ledgerRepository struct {
-	client *tigerbeetle.Client
+	backend LedgerBackend
+	code    uint16
 }
 
 // NewLedgerRepository creates a new LedgerRepository.
-func NewLedgerRepository(client *tigerbeetle.Client) LedgerRepository {
+// Accounts and ordinary transfers are tagged with code. Each currency is
+// held on its own ledger, which callers choose per operation.
+func NewLedgerRepository(backend LedgerBackend, code uint16) LedgerRepository {
 	return &ledgerRepository{
-		client: client,
+		backend: backend,
+		code:    code,
+	}
+}
+
+// CreateAccount creates a new, empty customer account in the ledger. The
+// account cannot be overdrawn: its debits may never exceed its credits.
+// The account ID is derived from the request's idempotency key when it has
+// one, so a retried create returns the account created the first time.
+func (r *ledgerRepository) CreateAccount(ctx context.Context, ledgerID uint32) (string, error) {
+	account := ledger.Account{
+		ID:     idempotency.NewID(ctx, "account", 0),
+		Ledger: ledgerID,
+		Code:   r.code,
+		Flags:  ledger.AccountFlags{DebitsMustNotExceedCredits: true},
+	}
+	if err := r.createAccount(ctx, account); err != nil {
+		return "", err
+	}
+	return account.ID.String(), nil
+}
+
+// CreateSettlementAccount creates the system account id that deposits are
+// funded from and withdrawals are paid into, such as cash held at a bank or an
+// FBO account. Settlement accounts are debit-normal: they hold the money owed
+// to customers, and cannot pay out more than they have taken in. Creating an
+// account that already exists is not an error.
+func (r *ledgerRepository) CreateSettlementAccount(ctx context.Context, id uuid.UUID, ledgerID uint32) error {
+	return r.createAccount(ctx, ledger.Account{
+		ID:     id,
+		Ledger: ledgerID,
+		Code:   r.code,
+		Flags:  ledger.AccountFlags{CreditsMustNotExceedDebits: true},
+	})
+}
+
+// CreateLiquidityAccount creates the system account id through which
+// currencies are exchanged: an exchange pays into the liquidity account on the
+// source currency's ledger and out of the one on the destination currency's
+// ledger. Liquidity accounts have no balance limits. Creating an account that
+// already exists is not an error.
+func (r *ledgerRepository) CreateLiquidityAccount(ctx context.Context, id uuid.UUID, ledgerID uint32) error {
+	return r.createAccount(ctx, ledger.Account{
+		ID:     id,
+		Ledger: ledgerID,
+		Code:   r.code,
+	})
+}
+
+// createAccount submits a single account and surfaces its result as an error.
+// An account that already exists unchanged is a retry and is not an error.
+func (r *ledgerRepository) createAccount(ctx context.Context, account ledger.Account) error {
+	results, err := r.backend.CreateAccounts(ctx, []ledger.Account{account})
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 {
+		if err := (&ledger.AccountError{ID: account.ID, Result: results[0].Result}); !err.Exists() {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deposit credits amount to an account, funded by a settlement account.
+func (r *ledgerRepository) Deposit(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error) {
+	return r.settle(ctx, ledgerID, settlementAccountID.String(), accountID, amount, DepositCode)
+}
+
+// OpeningDeposit funds the initial balance of a new account from a settlement account.
+func (r *ledgerRepository) OpeningDeposit(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error) {
+	return r.settle(ctx, ledgerID, settlementAccountID.String(), accountID, amount, OpeningDepositCode)
+}
+
+// Withdraw debits amount from an account and pays it into a settlement account.
+func (r *ledgerRepository) Withdraw(ctx context.Context, ledgerID uint32, settlementAccountID uuid.UUID, accountID string, amount int64) (string, error) {
+	return r.settle(ctx, ledgerID, accountID, settlementAccountID.String(), amount, WithdrawalCode)
+}
+
+// settle posts a transfer between a settlement account and a customer account.
+func (r *ledgerRepository) settle(ctx context.Context, ledgerID uint32, fromAccountID, toAccountID string, amount int64, code uint16) (string, error) {
+	return r.Transfer(ctx, TransferLeg{
+		Ledger:        ledgerID,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Code:          code,
+	})
+}
+
+// Transfer executes a fund transfer between two accounts and returns its ID.
+func (r *ledgerRepository) Transfer(ctx context.Context, leg TransferLeg) (string, error) {
+	transfer, err := r.newTransfer(ctx, 0, leg)
+	if err != nil {
+		return "", err
+	}
+	if err := r.createTransfer(ctx, transfer); err != nil {
+		return "", err
+	}
+	return transfer.ID.String(), nil
+}
+
+// TransferBatch posts legs atomically, in order, as a chain of linked
+// transfers: either every leg is posted or none is. It returns the IDs of the
+// transfers created for each leg.
+func (r *ledgerRepository) TransferBatch(ctx context.Context, legs []TransferLeg) ([]string, error) {
+	if len(legs) == 0 {
+		return nil, errors.New("a batch must contain at least one transfer")
+	}
+
+	transfers := make([]ledger.Transfer, len(legs))
+	for i, leg := range legs {
+		transfer, err := r.newTransfer(ctx, i, leg)
+		if err != nil {
+			return nil, fmt.Errorf("transfer %d: %w", i, err)
+		}
+		// Every leg but the last is linked to the next one.
+		transfer.Flags.Linked = i < len(legs)-1
+		transfers[i] = transfer
+	}
+
+	results, err := r.backend.CreateTransfers(ctx, transfers)
+	if err != nil {
+		return nil, err
 	}
+	for _, result := range results {
+		if result.Result == ledger.TransferExists {
+			// A retry of a batch that was already posted: the chain fails on
+			// the first leg, so confirm that every leg exists.
+			posted, err := r.backend.LookupTransfers(ctx, transferIDs(transfers))
+			if err != nil {
+				return nil, err
+			}
+			if len(posted) == len(transfers) {
+				break
+			}
+		}
+		if result.Result != ledger.TransferLinkedEventFailed {
+			return nil, &ledger.ChainError{
+				Index: result.Index,
+				Cause: &ledger.TransferError{ID: transfers[result.Index].ID, Result: result.Result},
+			}
+		}
+	}
+	if len(results) > 0 && results[0].Result != ledger.TransferExists {
+		return nil, errors.New("linked transfers failed without a cause")
+	}
+
+	ids := make([]string, len(transfers))
+	for i, transfer := range transfers {
+		ids[i] = transfer.ID.String()
+	}
+	return ids, nil
 }
 
-// CreateAccount creates a new account in TigerBeetle.
-// It generates a new UUID for the account.
-func (r *ledgerRepository) CreateAccount(ctx context.Context, initialBalance int64) (string, error) {
-	accountID := uuid.New().String()
-	err := r.client.CreateAccount(ctx, accountID, initialBalance)
+// CreatePendingTransfer reserves amount on both accounts without posting it.
+// The hold is released automatically after timeout seconds unless it is
+// posted or voided first; a zero timeout never expires.
+func (r *ledgerRepository) CreatePendingTransfer(ctx context.Context, leg TransferLeg, timeout uint32) (string, error) {
+	transfer, err := r.newTransfer(ctx, 0, leg)
 	if err != nil {
 		return "", err
 	}
-	return accountID, nil
+	transfer.Flags.Pending = true
+	transfer.Timeout = timeout
+	if err := r.createTransfer(ctx, transfer); err != nil {
+		return "", err
+	}
+	return transfer.ID.String(), nil
 }
 
-// Transfer executes a fund transfer between two accounts.
-func (r *ledgerRepository) Transfer(ctx context.Context, fromAccountID, toAccountID string, amount int64) error {
-	if amount <= 0 {
-		return errors.New("transfer amount must be positive")
+// PostPendingTransfer posts amount of a pending transfer and releases the
+// remainder of the hold. An amount of zero posts the full pending amount.
+func (r *ledgerRepository) PostPendingTransfer(ctx context.Context, pendingID uuid.UUID, amount int64) (string, error) {
+	if amount < 0 {
+		return "", errors.New("post amount must not be negative")
 	}
-	return r.client.Transfer(ctx, fromAccountID, toAccountID, amount)
-}
\ No newline at end of file
+	transfer := ledger.Transfer{
+		ID:        idempotency.NewID(ctx, "transfer", 0),
+		PendingID: pendingID,
+		Amount:    uint64(amount),
+		Flags:     ledger.TransferFlags{PostPendingTransfer: true},
+	}
+	if amount == 0 {
+		transfer.Amount = ledger.AmountMax
+	}
+	if err := r.createTransfer(ctx, transfer); err != nil {
+		return "", err
+	}
+	return transfer.ID.String(), nil
+}
+
+// VoidPendingTransfer releases the full amount of a pending transfer.
+func (r *ledgerRepository) VoidPendingTransfer(ctx context.Context, pendingID uuid.UUID) (string, error) {
+	transfer := ledger.Transfer{
+		ID:        idempotency.NewID(ctx, "transfer", 0),
+		PendingID: pendingID,
+		Flags:     ledger.TransferFlags{VoidPendingTransfer: true},
+	}
+	if err := r.createTransfer(ctx, transfer); err != nil {
+		return "", err
+	}
+	return transfer.ID.String(), nil
+}
+
+// newTransfer validates the parties and amount of the n-th transfer of a
+// request and assigns it an ID.
+func (r *ledgerRepository) newTransfer(ctx context.Context, n int, leg TransferLeg) (ledger.Transfer, error) {
+	if leg.Amount <= 0 {
+		return ledger.Transfer{}, errors.New("transfer amount must be positive")
+	}
+	from, err := uuid.Parse(leg.FromAccountID)
+	if err != nil {
+		return ledger.Transfer{}, errors.New("invalid debit account ID")
+	}
+	to, err := uuid.Parse(leg.ToAccountID)
+	if err != nil {
+		return ledger.Transfer{}, errors.New("invalid credit account ID")
+	}
+	code := leg.Code
+	if code == 0 {
+		code = r.code
+	}
+	return ledger.Transfer{
+		ID:              idempotency.NewID(ctx, "transfer", n),
+		DebitAccountID:  from,
+		CreditAccountID: to,
+		Amount:          uint64(leg.Amount),
+		UserData128:     leg.UserData128,
+		UserData64:      leg.UserData64,
+		UserData32:      leg.UserData32,
+		Ledger:          leg.Ledger,
+		Code:            code,
+	}, nil
+}
+
+// createTransfer submits a single transfer and surfaces its result as an error.
+// A transfer that already exists unchanged is a retry and is not an error.
+func (r *ledgerRepository) createTransfer(ctx context.Context, transfer ledger.Transfer) error {
+	results, err := r.backend.CreateTransfers(ctx, []ledger.Transfer{transfer})
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 {
+		if err := (&ledger.TransferError{ID: transfer.ID, Result: results[0].Result}); !err.Exists() {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferIDs returns the IDs of transfers.
+func transferIDs(transfers []ledger.Transfer) []uuid.UUID {
+	ids := make([]uuid.UUID, len(transfers))
+	for i, transfer := range transfers {
+		ids[i] = transfer.ID
+	}
+	return ids
+}
+
+// GetAccount retrieves a ledger account and its balances by ID.
+// It returns nil when the account does not exist.
+func (r *ledgerRepository) GetAccount(ctx context.Context, id uuid.UUID) (*ledger.Account, error) {
+	accounts, err := r.GetAccounts(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, nil // Not found
+	}
+	return accounts[0], nil
+}
+
+// GetAccounts retrieves the ledger accounts that exist among ids.
+// Accounts that do not exist are omitted from the result.
+func (r *ledgerRepository) GetAccounts(ctx context.Context, ids []uuid.UUID) ([]*ledger.Account, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	accounts, err := r.backend.LookupAccounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*ledger.Account, len(accounts))
+	for i := range accounts {
+		out[i] = &accounts[i]
+	}
+	return out, nil
+}
+
+// GetTransfer retrieves a transfer by ID.
+// It returns nil when the transfer does not exist.
+func (r *ledgerRepository) GetTransfer(ctx context.Context, id uuid.UUID) (*ledger.Transfer, error) {
+	transfers, err := r.backend.LookupTransfers(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, nil // Not found
+	}
+	return &transfers[0], nil
+}
+
+// ListTransfers returns up to filter.Limit transfers that touched an account
+// in timestamp order, and whether further transfers match the filter.
+func (r *ledgerRepository) ListTransfers(ctx context.Context, filter TransferFilter) ([]ledger.Transfer, bool, error) {
+	accountFilter := ledger.AccountFilter{
+		AccountID:    filter.AccountID,
+		TimestampMin: filter.TimestampMin,
+		TimestampMax: filter.TimestampMax,
+		Debits:       filter.Debits,
+		Credits:      filter.Credits,
+		Reversed:     filter.Reversed,
+		// Ask for one extra transfer to learn whether there is another page.
+		Limit: filter.Limit + 1,
+	}
+	amountFiltered := filter.MinAmount != 0 || filter.MaxAmount != 0
+	if amountFiltered {
+		accountFilter.Limit = transferScanPageSize
+	}
+
+	var transfers []ledger.Transfer
+	for {
+		page, err := r.backend.GetAccountTransfers(ctx, accountFilter)
+		if err != nil {
+			return nil, false, err
+		}
+
+		for _, transfer := range page {
+			if filter.MinAmount != 0 && transfer.Amount < filter.MinAmount {
+				continue
+			}
+			if filter.MaxAmount != 0 && transfer.Amount > filter.MaxAmount {
+				continue
+			}
+			transfers = append(transfers, transfer)
+			if len(transfers) > filter.Limit {
+				return transfers[:filter.Limit], true, nil
+			}
+		}
+
+		if len(page) < accountFilter.Limit {
+			return transfers, false, nil
+		}
+
+		// Continue the scan after the last transfer of this page.
+		last := page[len(page)-1].Timestamp
+		if accountFilter.Reversed {
+			accountFilter.TimestampMax = last - 1
+		} else {
+			accountFilter.TimestampMin = last + 1
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// OAuthClientEntity represents an OAuth2 client registered by a fintech
+// partner for the client credentials grant. Only the hash of the client secret
+// is stored.
+type OAuthClientEntity struct {
+	ID         uuid.UUID  `json:"id,omitempty"`
+	ClientID   string     `json:"client_id"`
+	PartnerID  uuid.UUID  `json:"partner_id"`
+	Name       string     `json:"name"`
+	SecretHash string     `json:"secret_hash"`
+	Scopes     []string   `json:"scopes"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at,omitempty"`
+}
+
+// OAuthClientRepository provides methods to interact with OAuth client storage
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *OAuthClientEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*OAuthClientEntity, error)
+	GetByClientID(ctx context.Context, clientID string) (*OAuthClientEntity, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*OAuthClientEntity, error)
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+}
+
+type oauthClientRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewOAuthClientRestRepository creates a new OAuth client repository using Supabase REST API
+func NewOAuthClientRestRepository(client *supabase.Client) OAuthClientRepository {
+	return &oauthClientRestRepository{
+		client: client,
+		table:  "oauth_clients",
+	}
+}
+
+// Create inserts a new OAuth client
+func (r *oauthClientRestRepository) Create(ctx context.Context, client *OAuthClientEntity) error {
+	payload := map[string]interface{}{
+		"client_id":   client.ClientID,
+		"partner_id":  client.PartnerID,
+		"name":        client.Name,
+		"secret_hash": client.SecretHash,
+		"scopes":      client.Scopes,
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*OAuthClientEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no OAuth client was created")
+	}
+
+	client.ID = created[0].ID
+	client.CreatedAt = created[0].CreatedAt
+	client.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByID retrieves an OAuth client by its ID
+func (r *oauthClientRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*OAuthClientEntity, error) {
+	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return firstOAuthClient(respBody)
+}
+
+// GetByClientID retrieves the OAuth client with the given public client ID
+func (r *oauthClientRestRepository) GetByClientID(ctx context.Context, clientID string) (*OAuthClientEntity, error) {
+	queryParams := map[string]string{
+		"client_id": fmt.Sprintf("eq.%s", clientID),
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstOAuthClient(respBody)
+}
+
+// List retrieves OAuth clients, newest first, optionally only those of one partner
+func (r *oauthClientRestRepository) List(ctx context.Context, partnerID *uuid.UUID) ([]*OAuthClientEntity, error) {
+	queryParams := map[string]string{
+		"order": "created_at.desc",
+	}
+	if partnerID != nil {
+		queryParams["partner_id"] = fmt.Sprintf("eq.%s", partnerID)
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []*OAuthClientEntity
+	if err := json.Unmarshal(respBody, &clients); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return clients, nil
+}
+
+// Revoke marks an OAuth client as revoked
+func (r *oauthClientRestRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"revoked_at": revokedAt,
+	})
+	return err
+}
+
+func firstOAuthClient(respBody []byte) (*OAuthClientEntity, error) {
+	var clients []*OAuthClientEntity
+	if err := json.Unmarshal(respBody, &clients); err != nil {
+		return nil, err
+	}
+	if len(clients) == 0 {
+		return nil, nil // Not found
+	}
+	return clients[0], nil
+}
@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/fieldcrypt"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +18,7 @@ import (
 // PersonEntity represents a person entity in the database
 type PersonEntity struct {
 	ID              uuid.UUID  `json:"id,omitempty"`
+	PartnerID       uuid.UUID  `json:"partner_id"`
 	FirstName       string     `json:"first_name"`
 	LastName        string     `json:"last_name"`
 	DateOfBirth     time.Time  `json:"date_of_birth"`
@@ -44,18 +47,28 @@ type PersonRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*PersonEntity, error)
 	Update(ctx context.Context, person *PersonEntity) error
 	List(ctx context.Context, limit, offset int) ([]*PersonEntity, error)
+	// FindBySSN retrieves the request's partner's person entities with an SSN
+	FindBySSN(ctx context.Context, ssn string) ([]*PersonEntity, error)
+	// Reencrypt encrypts the SSNs and government IDs of up to limit person
+	// entities after the given ID that are plaintext or under an old key, and
+	// fills in missing SSN indexes
+	Reencrypt(ctx context.Context, after uuid.UUID, limit int) (*ReencryptPage, error)
 }
 
 type personRestRepository struct {
 	client *supabase.Client
 	table  string
+	keys   *fieldcrypt.Keyring
 }
 
-// NewPersonRestRepository creates a new person repository using Supabase REST API
-func NewPersonRestRepository(client *supabase.Client) PersonRepository {
+// NewPersonRestRepository creates a new person repository using Supabase REST API.
+// SSNs and government IDs are encrypted with keys, and SSNs are looked up by
+// their blind index.
+func NewPersonRestRepository(client *supabase.Client, keys *fieldcrypt.Keyring) PersonRepository {
 	return &personRestRepository{
 		client: client,
 		table:  "person_entities",
+		keys:   keys,
 	}
 }
 
@@ -66,13 +79,24 @@ func (r *personRestRepository) Create(ctx context.Context, person *PersonEntity)
 		person.KYCStatus = "pending"
 	}
 
+	partnerID, err := partnerOf(ctx)
+	if err != nil {
+		return err
+	}
+	ssn, governmentID, err := r.encrypt(person)
+	if err != nil {
+		return err
+	}
+
 	// Build the payload map.
 	// Format date_of_birth as "YYYY-MM-DD" to match the database type (date)
 	payload := map[string]interface{}{
 		"first_name":     person.FirstName,
 		"last_name":      person.LastName,
 		"date_of_birth":  person.DateOfBirth.Format("2006-01-02"),
-		"ssn":            person.SSN,
+		"ssn":            ssn,
+		"ssn_index":      r.ssnIndex(person.SSN),
+		"government_id":  governmentID,
 		"email":          person.Email,
 		"phone_number":   person.PhoneNumber,
 		"street1":        person.Street1,
@@ -83,6 +107,7 @@ func (r *personRestRepository) Create(ctx context.Context, person *PersonEntity)
 		"country":        person.Country,
 		"kyc_status":     person.KYCStatus,
 		"kyc_verified_at": person.KYCVerifiedAt,
+		"partner_id":     partnerID,
 	}
 
 	// Only include the id if it is not zero.
@@ -109,15 +134,20 @@ func (r *personRestRepository) Create(ctx context.Context, person *PersonEntity)
 	// Update the input entity with the created entity's data
 	createdPerson := createdPersons[0]
 	person.ID = createdPerson.ID
+	person.PartnerID = partnerID
 	person.CreatedAt = createdPerson.CreatedAt
 	person.UpdatedAt = createdPerson.UpdatedAt
 
 	return nil
 }
 
-// GetByID retrieves a person entity by its ID
+// GetByID retrieves a person entity of the request's partner by its ID
 func (r *personRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*PersonEntity, error) {
-	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	queryParams, err := partnerQuery(ctx, byID(id))
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +160,9 @@ func (r *personRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*Pers
 	if len(persons) == 0 {
 		return nil, nil // Not found
 	}
+	if err := r.decrypt(persons[0]); err != nil {
+		return nil, err
+	}
 
 	return persons[0], nil
 }
@@ -139,13 +172,19 @@ func (r *personRestRepository) Update(ctx context.Context, person *PersonEntity)
 	if person.ID == uuid.Nil {
 		return errors.New("person ID is required for update")
 	}
+	ssn, governmentID, err := r.encrypt(person)
+	if err != nil {
+		return err
+	}
 
 	// Build the update payload.
 	updateData := map[string]interface{}{
 		"first_name":      person.FirstName,
 		"last_name":       person.LastName,
 		"date_of_birth":   person.DateOfBirth,
-		"ssn":             person.SSN,
+		"ssn":             ssn,
+		"ssn_index":       r.ssnIndex(person.SSN),
+		"government_id":   governmentID,
 		"email":           person.Email,
 		"phone_number":    person.PhoneNumber,
 		"street1":         person.Street1,
@@ -158,8 +197,12 @@ func (r *personRestRepository) Update(ctx context.Context, person *PersonEntity)
 		"kyc_verified_at": person.KYCVerifiedAt,
 	}
 
-	// Update the person entity
-	respBody, err := r.client.Update(ctx, r.table, person.ID.String(), updateData)
+	// Update the person entity, if it belongs to the request's partner
+	queryParams, err := partnerQuery(ctx, byID(person.ID))
+	if err != nil {
+		return err
+	}
+	respBody, err := r.client.UpdateWhere(ctx, r.table, queryParams, updateData)
 	if err != nil {
 		return err
 	}
@@ -181,7 +224,7 @@ func (r *personRestRepository) Update(ctx context.Context, person *PersonEntity)
 	return nil
 }
 
-// List retrieves a paginated list of person entities
+// List retrieves a paginated list of the request's partner's person entities
 func (r *personRestRepository) List(ctx context.Context, limit, offset int) ([]*PersonEntity, error) {
 	// Apply sensible defaults
 	if limit <= 0 {
@@ -195,10 +238,13 @@ func (r *personRestRepository) List(ctx context.Context, limit, offset int) ([]*
 	}
 
 	// Prepare query parameters
-	queryParams := map[string]string{
+	queryParams, err := partnerQuery(ctx, map[string]string{
 		"limit":  strconv.Itoa(limit),
 		"offset": strconv.Itoa(offset),
 		"order":  "created_at.desc",
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Fetch the person entities
@@ -212,6 +258,150 @@ func (r *personRestRepository) List(ctx context.Context, limit, offset int) ([]*
 	if err := json.Unmarshal(respBody, &persons); err != nil {
 		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
+	for _, person := range persons {
+		if err := r.decrypt(person); err != nil {
+			return nil, err
+		}
+	}
+
+	return persons, nil
+}
+
+// FindBySSN retrieves the request's partner's person entities whose SSN
+// matches, ignoring dashes and spaces, by its blind index
+func (r *personRestRepository) FindBySSN(ctx context.Context, ssn string) ([]*PersonEntity, error) {
+	queryParams, err := partnerQuery(ctx, map[string]string{
+		"ssn_index": fmt.Sprintf("eq.%s", r.ssnIndex(&ssn)),
+		"order":     "created_at.desc",
+	})
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
 
+	var persons []*PersonEntity
+	if err := json.Unmarshal(respBody, &persons); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	for _, person := range persons {
+		if err := r.decrypt(person); err != nil {
+			return nil, err
+		}
+	}
 	return persons, nil
+}
+
+// Reencrypt encrypts the stored SSNs and government IDs of a page of person
+// entities again where they are plaintext or under an old key, and fills in
+// missing SSN indexes. Other columns are left alone, and so are records whose
+// values changed after they were read.
+func (r *personRestRepository) Reencrypt(ctx context.Context, after uuid.UUID, limit int) (*ReencryptPage, error) {
+	queryParams, err := reencryptQuery(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID           uuid.UUID `json:"id"`
+		SSN          *string   `json:"ssn"`
+		SSNIndex     *string   `json:"ssn_index"`
+		GovernmentID *string   `json:"government_id"`
+	}
+	if err := json.Unmarshal(respBody, &rows); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	page := &ReencryptPage{Scanned: len(rows), Last: after}
+	for _, row := range rows {
+		page.Last = row.ID
+		updateData := map[string]interface{}{}
+		if row.SSN != nil {
+			ssn, err := r.keys.Decrypt(*row.SSN, r.table+".ssn")
+			if err != nil {
+				return nil, fmt.Errorf("decrypting ssn of person %s: %w", row.ID, err)
+			}
+			if index := r.ssnIndex(&ssn); row.SSNIndex == nil || *row.SSNIndex != index {
+				updateData["ssn_index"] = index
+			}
+		}
+		if ssn, stale, err := reencryptField(r.keys, row.SSN, r.table+".ssn"); err != nil {
+			return nil, fmt.Errorf("re-encrypting ssn of person %s: %w", row.ID, err)
+		} else if stale {
+			updateData["ssn"] = ssn
+		}
+		if governmentID, stale, err := reencryptField(r.keys, row.GovernmentID, r.table+".government_id"); err != nil {
+			return nil, fmt.Errorf("re-encrypting government_id of person %s: %w", row.ID, err)
+		} else if stale {
+			updateData["government_id"] = governmentID
+		}
+		if len(updateData) == 0 {
+			continue
+		}
+		// The SSN index is derived from the SSN, so both are written only
+		// if the SSN is still the one read
+		queryParams := byID(row.ID)
+		_, ssn := updateData["ssn"]
+		_, ssnIndex := updateData["ssn_index"]
+		if ssn || ssnIndex {
+			whereUnchanged(queryParams, "ssn", row.SSN)
+		}
+		if _, ok := updateData["government_id"]; ok {
+			whereUnchanged(queryParams, "government_id", row.GovernmentID)
+		}
+		updated, err := updateIfUnchanged(ctx, r.client, r.table, queryParams, updateData)
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			page.Updated++
+		} else {
+			page.Skipped++
+		}
+	}
+	return page, nil
+}
+
+// encrypt returns a person's SSN and government ID encrypted for storage
+func (r *personRestRepository) encrypt(person *PersonEntity) (interface{}, interface{}, error) {
+	ssn, err := encryptField(r.keys, person.SSN, r.table+".ssn")
+	if err != nil {
+		return nil, nil, err
+	}
+	governmentID, err := encryptField(r.keys, person.GovernmentID, r.table+".government_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	return ssn, governmentID, nil
+}
+
+// decrypt decrypts a stored person's SSN and government ID in place
+func (r *personRestRepository) decrypt(person *PersonEntity) error {
+	if err := decryptField(r.keys, person.SSN, r.table+".ssn"); err != nil {
+		return fmt.Errorf("decrypting ssn of person %s: %w", person.ID, err)
+	}
+	if err := decryptField(r.keys, person.GovernmentID, r.table+".government_id"); err != nil {
+		return fmt.Errorf("decrypting government_id of person %s: %w", person.ID, err)
+	}
+	return nil
+}
+
+// ssnIndex returns the blind index of an SSN, or nil if it is unset. Only
+// the SSN's digits count, so formatting doesn't change the index.
+func (r *personRestRepository) ssnIndex(ssn *string) interface{} {
+	if ssn == nil {
+		return nil
+	}
+	digits := strings.Map(func(c rune) rune {
+		if c >= '0' && c <= '9' {
+			return c
+		}
+		return -1
+	}, *ssn)
+	return r.keys.BlindIndex(digits, r.table+".ssn")
 }
\ No newline at end of file
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// RefreshTokenEntity represents an opaque refresh token issued to a user. Only
+// the hash of the token is stored. Every token is used once and replaced by a
+// new one in the same family, which starts at login.
+type RefreshTokenEntity struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	FamilyID  uuid.UUID `json:"family_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	// AMR is how the user authenticated at the login the family started with
+	AMR       []string   `json:"amr"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+}
+
+// RefreshTokenRepository provides methods to interact with refresh token storage
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshTokenEntity) error
+	GetByHash(ctx context.Context, tokenHash string) (*RefreshTokenEntity, error)
+	// MarkUsed marks a token as used unless it already was or has been
+	// revoked, and reports whether it did
+	MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, revokedAt time.Time) error
+	RevokeUser(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error
+}
+
+type refreshTokenRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewRefreshTokenRestRepository creates a new refresh token repository using Supabase REST API
+func NewRefreshTokenRestRepository(client *supabase.Client) RefreshTokenRepository {
+	return &refreshTokenRestRepository{
+		client: client,
+		table:  "refresh_tokens",
+	}
+}
+
+// Create inserts a new refresh token
+func (r *refreshTokenRestRepository) Create(ctx context.Context, token *RefreshTokenEntity) error {
+	payload := map[string]interface{}{
+		"family_id":  token.FamilyID,
+		"user_id":    token.UserID,
+		"token_hash": token.TokenHash,
+		"amr":        token.AMR,
+		"expires_at": token.ExpiresAt,
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*RefreshTokenEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no refresh token was created")
+	}
+
+	token.ID = created[0].ID
+	token.CreatedAt = created[0].CreatedAt
+	token.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByHash retrieves the refresh token with the given hash
+func (r *refreshTokenRestRepository) GetByHash(ctx context.Context, tokenHash string) (*RefreshTokenEntity, error) {
+	queryParams := map[string]string{
+		"token_hash": fmt.Sprintf("eq.%s", tokenHash),
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*RefreshTokenEntity
+	if err := json.Unmarshal(respBody, &tokens); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil // Not found
+	}
+	return tokens[0], nil
+}
+
+// MarkUsed marks a refresh token as used. The update only matches unused,
+// unrevoked tokens, so of two concurrent uses only one succeeds.
+func (r *refreshTokenRestRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) (bool, error) {
+	queryParams := map[string]string{
+		"id":         fmt.Sprintf("eq.%s", id),
+		"used_at":    "is.null",
+		"revoked_at": "is.null",
+	}
+	respBody, err := r.client.UpdateWhere(ctx, r.table, queryParams, map[string]interface{}{
+		"used_at": usedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var updated []*RefreshTokenEntity
+	if err := json.Unmarshal(respBody, &updated); err != nil {
+		return false, err
+	}
+	return len(updated) > 0, nil
+}
+
+// RevokeFamily revokes every token of a family
+func (r *refreshTokenRestRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, revokedAt time.Time) error {
+	return r.revoke(ctx, "family_id", familyID, revokedAt)
+}
+
+// RevokeUser revokes every token of a user
+func (r *refreshTokenRestRepository) RevokeUser(ctx context.Context, userID uuid.UUID, revokedAt time.Time) error {
+	return r.revoke(ctx, "user_id", userID, revokedAt)
+}
+
+func (r *refreshTokenRestRepository) revoke(ctx context.Context, column string, id uuid.UUID, revokedAt time.Time) error {
+	queryParams := map[string]string{
+		column:       fmt.Sprintf("eq.%s", id),
+		"revoked_at": "is.null",
+	}
+	_, err := r.client.UpdateWhere(ctx, r.table, queryParams, map[string]interface{}{
+		"revoked_at": revokedAt,
+	})
+	return err
+}
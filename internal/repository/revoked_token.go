@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+)
+
+// RevokedTokenRepository is the denylist of access tokens revoked before they
+// expired, keyed by their jti claim. Entries are only needed until ExpiresAt,
+// after which the token is rejected anyway.
+type RevokedTokenRepository interface {
+	Add(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type revokedTokenRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewRevokedTokenRestRepository creates a new token denylist using Supabase REST API
+func NewRevokedTokenRestRepository(client *supabase.Client) RevokedTokenRepository {
+	return &revokedTokenRestRepository{
+		client: client,
+		table:  "revoked_tokens",
+	}
+}
+
+// Add puts a token on the denylist
+func (r *revokedTokenRestRepository) Add(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.client.Insert(ctx, r.table, map[string]interface{}{
+		"jti":        jti,
+		"expires_at": expiresAt,
+	})
+	return err
+}
+
+// IsRevoked reports whether a token is on the denylist
+func (r *revokedTokenRestRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	queryParams := map[string]string{
+		"jti":    fmt.Sprintf("eq.%s", jti),
+		"select": "jti",
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return false, err
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(respBody, &rows); err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
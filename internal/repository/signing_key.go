@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// SigningKeyEntity represents a key a fintech partner signs requests with.
+// HMAC needs the secret itself to check a signature, so unlike API keys the
+// secret is stored rather than its hash.
+type SigningKeyEntity struct {
+	ID        uuid.UUID  `json:"id,omitempty"`
+	KeyID     string     `json:"key_id"`
+	PartnerID uuid.UUID  `json:"partner_id"`
+	Name      string     `json:"name"`
+	Secret    string     `json:"secret"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+}
+
+// SigningKeyRepository provides methods to interact with signing key storage
+type SigningKeyRepository interface {
+	Create(ctx context.Context, key *SigningKeyEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*SigningKeyEntity, error)
+	GetByKeyID(ctx context.Context, keyID string) (*SigningKeyEntity, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*SigningKeyEntity, error)
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+}
+
+type signingKeyRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewSigningKeyRestRepository creates a new signing key repository using Supabase REST API
+func NewSigningKeyRestRepository(client *supabase.Client) SigningKeyRepository {
+	return &signingKeyRestRepository{
+		client: client,
+		table:  "signing_keys",
+	}
+}
+
+// Create inserts a new signing key
+func (r *signingKeyRestRepository) Create(ctx context.Context, key *SigningKeyEntity) error {
+	payload := map[string]interface{}{
+		"key_id":     key.KeyID,
+		"partner_id": key.PartnerID,
+		"name":       key.Name,
+		"secret":     key.Secret,
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*SigningKeyEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no signing key was created")
+	}
+
+	key.ID = created[0].ID
+	key.CreatedAt = created[0].CreatedAt
+	key.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByID retrieves a signing key by its ID
+func (r *signingKeyRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*SigningKeyEntity, error) {
+	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return firstSigningKey(respBody)
+}
+
+// GetByKeyID retrieves the signing key with the given public key ID
+func (r *signingKeyRestRepository) GetByKeyID(ctx context.Context, keyID string) (*SigningKeyEntity, error) {
+	queryParams := map[string]string{
+		"key_id": fmt.Sprintf("eq.%s", keyID),
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstSigningKey(respBody)
+}
+
+// List retrieves signing keys, newest first, optionally only those of one partner
+func (r *signingKeyRestRepository) List(ctx context.Context, partnerID *uuid.UUID) ([]*SigningKeyEntity, error) {
+	queryParams := map[string]string{
+		"order": "created_at.desc",
+	}
+	if partnerID != nil {
+		queryParams["partner_id"] = fmt.Sprintf("eq.%s", partnerID)
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*SigningKeyEntity
+	if err := json.Unmarshal(respBody, &keys); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke marks a signing key as revoked
+func (r *signingKeyRestRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"revoked_at": revokedAt,
+	})
+	return err
+}
+
+func firstSigningKey(respBody []byte) (*SigningKeyEntity, error) {
+	var keys []*SigningKeyEntity
+	if err := json.Unmarshal(respBody, &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil // Not found
+	}
+	return keys[0], nil
+}
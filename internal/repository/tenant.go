@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// partnerQuery limits a query to the records of the partner the request acts
+// for. It fails if the request acts for no partner and is not the cross-tenant
+// admin view.
+func partnerQuery(ctx context.Context, queryParams map[string]string) (map[string]string, error) {
+	partnerID, err := tenant.Filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if queryParams == nil {
+		queryParams = make(map[string]string)
+	}
+	if partnerID != uuid.Nil {
+		queryParams["partner_id"] = fmt.Sprintf("eq.%s", partnerID)
+	}
+	return queryParams, nil
+}
+
+// partnerOf returns the partner that records created by the request belong to
+func partnerOf(ctx context.Context) (uuid.UUID, error) {
+	partnerID, ok := tenant.PartnerFromContext(ctx)
+	if !ok {
+		return uuid.Nil, tenant.ErrNoPartner
+	}
+	return partnerID, nil
+}
+
+// byID is the query for the record with the given ID
+func byID(id uuid.UUID) map[string]string {
+	return map[string]string{
+		"id": fmt.Sprintf("eq.%s", id),
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// TransferDetailsEntity holds the partner and descriptive fields of a ledger
+// transfer, which the ledger itself has no room for
+type TransferDetailsEntity struct {
+	TransferID        uuid.UUID `json:"transfer_id"`
+	PartnerID         uuid.UUID `json:"partner_id"`
+	Description       string    `json:"description,omitempty"`
+	ExternalReference string    `json:"external_reference,omitempty"`
+	CreatedAt         time.Time `json:"created_at,omitempty"`
+}
+
+// TransferDetailsRepository provides methods to store and read the details of transfers
+type TransferDetailsRepository interface {
+	Save(ctx context.Context, details *TransferDetailsEntity) error
+	ListByTransferIDs(ctx context.Context, ids []uuid.UUID) ([]*TransferDetailsEntity, error)
+}
+
+type transferDetailsRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewTransferDetailsRestRepository creates a new transfer details repository using Supabase REST API
+func NewTransferDetailsRestRepository(client *supabase.Client) TransferDetailsRepository {
+	return &transferDetailsRestRepository{
+		client: client,
+		table:  "transfer_details",
+	}
+}
+
+// Save stores the details of a transfer made by the request's partner.
+// Details that were already saved for the transfer, such as by an earlier
+// attempt of the same request, are kept.
+func (r *transferDetailsRestRepository) Save(ctx context.Context, details *TransferDetailsEntity) error {
+	partnerID, err := partnerOf(ctx)
+	if err != nil {
+		return err
+	}
+	existing, err := r.ListByTransferIDs(ctx, []uuid.UUID{details.TransferID})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"transfer_id":        details.TransferID,
+		"description":        details.Description,
+		"external_reference": details.ExternalReference,
+		"partner_id":         partnerID,
+	}
+	if _, err := r.client.Insert(ctx, r.table, payload); err != nil {
+		return err
+	}
+	details.PartnerID = partnerID
+	return nil
+}
+
+// ListByTransferIDs retrieves the details saved for any of the given
+// transfers that were made by the request's partner
+func (r *transferDetailsRestRepository) ListByTransferIDs(ctx context.Context, ids []uuid.UUID) ([]*TransferDetailsEntity, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	idStrs := make([]string, len(ids))
+	for i, id := range ids {
+		idStrs[i] = id.String()
+	}
+	queryParams, err := partnerQuery(ctx, map[string]string{
+		"transfer_id": fmt.Sprintf("in.(%s)", strings.Join(idStrs, ",")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var details []*TransferDetailsEntity
+	if err := json.Unmarshal(respBody, &details); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return details, nil
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/clients/supabase"
+	"github.com/google/uuid"
+)
+
+// UserEntity represents an operator who logs in with a username and password.
+// Only the bcrypt hash of the password is stored.
+type UserEntity struct {
+	ID           uuid.UUID `json:"id,omitempty"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Role         string    `json:"role"`
+	// PartnerID is the fintech partner the user acts for; staff have none
+	PartnerID *uuid.UUID `json:"partner_id,omitempty"`
+	// FailedLoginAttempts counts failed logins since the last successful one
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt         *time.Time `json:"last_login_at,omitempty"`
+	PasswordChangedAt   *time.Time `json:"password_changed_at,omitempty"`
+	// MFASecret is the TOTP secret of the user, set at enrollment; MFA is only
+	// required once MFAEnabledAt is set too
+	MFASecret    *string    `json:"mfa_secret,omitempty"`
+	MFAEnabledAt *time.Time `json:"mfa_enabled_at,omitempty"`
+	// MFARecoveryCodes are SHA-256 hashes of the unused recovery codes
+	MFARecoveryCodes []string `json:"mfa_recovery_codes,omitempty"`
+	// MFALastStep is the TOTP time step of the last accepted code
+	MFALastStep int64     `json:"mfa_last_step"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// UserRepository provides methods to interact with users in the database
+type UserRepository interface {
+	Create(ctx context.Context, user *UserEntity) error
+	GetByID(ctx context.Context, id uuid.UUID) (*UserEntity, error)
+	GetByUsername(ctx context.Context, username string) (*UserEntity, error)
+	List(ctx context.Context, limit, offset int) ([]*UserEntity, error)
+	UpdateRole(ctx context.Context, id uuid.UUID, role string) error
+	SetPassword(ctx context.Context, id uuid.UUID, passwordHash string, changedAt time.Time) error
+	SetLockout(ctx context.Context, id uuid.UUID, failedAttempts int, lockedUntil *time.Time) error
+	RecordLogin(ctx context.Context, id uuid.UUID, at time.Time) error
+	SetMFA(ctx context.Context, id uuid.UUID, secret *string, enabledAt *time.Time, recoveryCodes []string) error
+	// UseMFARecoveryCode removes hash from the recovery codes of a user
+	// unless they are no longer recoveryCodes, and reports whether it did
+	UseMFARecoveryCode(ctx context.Context, id uuid.UUID, recoveryCodes []string, hash string) (bool, error)
+	// AdvanceMFAStep records step as the last accepted TOTP step unless a
+	// later or equal one already is, and reports whether it did
+	AdvanceMFAStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type userRestRepository struct {
+	client *supabase.Client
+	table  string
+}
+
+// NewUserRestRepository creates a new user repository using Supabase REST API
+func NewUserRestRepository(client *supabase.Client) UserRepository {
+	return &userRestRepository{
+		client: client,
+		table:  "users",
+	}
+}
+
+// Create inserts a new user
+func (r *userRestRepository) Create(ctx context.Context, user *UserEntity) error {
+	payload := map[string]interface{}{
+		"username":            user.Username,
+		"password_hash":       user.PasswordHash,
+		"role":                user.Role,
+		"partner_id":          user.PartnerID,
+		"password_changed_at": user.PasswordChangedAt,
+	}
+
+	respBody, err := r.client.Insert(ctx, r.table, payload)
+	if err != nil {
+		return err
+	}
+
+	var created []*UserEntity
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		return errors.New("no user was created")
+	}
+
+	user.ID = created[0].ID
+	user.CreatedAt = created[0].CreatedAt
+	user.UpdatedAt = created[0].UpdatedAt
+	return nil
+}
+
+// GetByID retrieves a user by their ID
+func (r *userRestRepository) GetByID(ctx context.Context, id uuid.UUID) (*UserEntity, error) {
+	respBody, err := r.client.SelectById(ctx, r.table, id.String())
+	if err != nil {
+		return nil, err
+	}
+	return firstUser(respBody)
+}
+
+// GetByUsername retrieves the user with the given username
+func (r *userRestRepository) GetByUsername(ctx context.Context, username string) (*UserEntity, error) {
+	queryParams := map[string]string{
+		"username": fmt.Sprintf("eq.%s", username),
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	return firstUser(respBody)
+}
+
+// List retrieves a paginated list of users, newest first
+func (r *userRestRepository) List(ctx context.Context, limit, offset int) ([]*UserEntity, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	queryParams := map[string]string{
+		"limit":  strconv.Itoa(limit),
+		"offset": strconv.Itoa(offset),
+		"order":  "created_at.desc",
+	}
+	respBody, err := r.client.Select(ctx, r.table, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []*UserEntity
+	if err := json.Unmarshal(respBody, &users); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return users, nil
+}
+
+// UpdateRole changes the role of a user
+func (r *userRestRepository) UpdateRole(ctx context.Context, id uuid.UUID, role string) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"role": role,
+	})
+	return err
+}
+
+// SetPassword replaces the password hash of a user
+func (r *userRestRepository) SetPassword(ctx context.Context, id uuid.UUID, passwordHash string, changedAt time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"password_hash":       passwordHash,
+		"password_changed_at": changedAt,
+	})
+	return err
+}
+
+// SetLockout records the failed login attempts of a user and until when they
+// are locked out, if at all
+func (r *userRestRepository) SetLockout(ctx context.Context, id uuid.UUID, failedAttempts int, lockedUntil *time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"failed_login_attempts": failedAttempts,
+		"locked_until":          lockedUntil,
+	})
+	return err
+}
+
+// RecordLogin records a successful login and clears any failed attempts
+func (r *userRestRepository) RecordLogin(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+		"last_login_at":         at,
+	})
+	return err
+}
+
+// SetMFA replaces the MFA enrollment of a user; a nil secret removes it
+func (r *userRestRepository) SetMFA(ctx context.Context, id uuid.UUID, secret *string, enabledAt *time.Time, recoveryCodes []string) error {
+	if recoveryCodes == nil {
+		recoveryCodes = []string{}
+	}
+	_, err := r.client.Update(ctx, r.table, id.String(), map[string]interface{}{
+		"mfa_secret":         secret,
+		"mfa_enabled_at":     enabledAt,
+		"mfa_recovery_codes": recoveryCodes,
+		"mfa_last_step":      0,
+	})
+	return err
+}
+
+// UseMFARecoveryCode removes a used recovery code. The update only matches
+// the codes that were read, so a code can't be used twice, even concurrently,
+// and a code used at the same time isn't put back.
+func (r *userRestRepository) UseMFARecoveryCode(ctx context.Context, id uuid.UUID, recoveryCodes []string, hash string) (bool, error) {
+	remaining := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		if code != hash {
+			remaining = append(remaining, code)
+		}
+	}
+	queryParams := map[string]string{
+		"id":                 fmt.Sprintf("eq.%s", id),
+		"mfa_recovery_codes": fmt.Sprintf("eq.{%s}", strings.Join(recoveryCodes, ",")),
+	}
+	respBody, err := r.client.UpdateWhere(ctx, r.table, queryParams, map[string]interface{}{
+		"mfa_recovery_codes": remaining,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var updated []*UserEntity
+	if err := json.Unmarshal(respBody, &updated); err != nil {
+		return false, err
+	}
+	return len(updated) > 0, nil
+}
+
+// AdvanceMFAStep records the TOTP step of an accepted code. The update only
+// matches earlier steps, so a code can't be used twice, even concurrently.
+func (r *userRestRepository) AdvanceMFAStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	queryParams := map[string]string{
+		"id":            fmt.Sprintf("eq.%s", id),
+		"mfa_last_step": fmt.Sprintf("lt.%d", step),
+	}
+	respBody, err := r.client.UpdateWhere(ctx, r.table, queryParams, map[string]interface{}{
+		"mfa_last_step": step,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var updated []*UserEntity
+	if err := json.Unmarshal(respBody, &updated); err != nil {
+		return false, err
+	}
+	return len(updated) > 0, nil
+}
+
+// Delete removes a user
+func (r *userRestRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.client.Delete(ctx, r.table, id.String())
+	return err
+}
+
+func firstUser(respBody []byte) (*UserEntity, error) {
+	var users []*UserEntity
+	if err := json.Unmarshal(respBody, &users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil // Not found
+	}
+	return users[0], nil
+}
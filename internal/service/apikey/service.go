@@ -0,0 +1,325 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/google/uuid"
+)
+
+// KeyPrefix starts every API key, so that keys can be told apart from JWTs and
+// found by secret scanners.
+const KeyPrefix = "cbk_"
+
+// lastUsedInterval is how stale the recorded last use of a key may get before
+// it is updated again.
+const lastUsedInterval = time.Minute
+
+var (
+	ErrKeyNotFound  = errors.New("API key not found")
+	ErrInvalidKey   = errors.New("invalid API key")
+	ErrInvalidInput = errors.New("invalid API key data")
+	ErrKeyInactive  = errors.New("API key is revoked or expired")
+)
+
+// Scopes are the permissions an API key can be granted. A ":read" scope
+// allows reads of a resource and a ":write" scope allows changes.
+var Scopes = map[string]bool{
+	"entities:read":  true,
+	"entities:write": true,
+	"ledger:read":    true,
+	"ledger:write":   true,
+}
+
+// Service manages the API keys of fintech partners
+type Service interface {
+	Issue(ctx context.Context, input IssueKeyInput) (*IssuedKeyOutput, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*KeyOutput, error)
+	Rotate(ctx context.Context, id uuid.UUID, input RotateKeyInput) (*IssuedKeyOutput, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	Authenticate(ctx context.Context, key string) (*KeyOutput, error)
+}
+
+// IssueKeyInput represents the input for issuing an API key.
+// A zero ExpiresInDays issues a key that does not expire.
+type IssueKeyInput struct {
+	PartnerID     string   `json:"partner_id" binding:"required,uuid"`
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+}
+
+// RotateKeyInput represents the input for rotating an API key. The old key
+// keeps working for GracePeriodSeconds so that callers can switch over; zero
+// revokes it at once.
+type RotateKeyInput struct {
+	GracePeriodSeconds int `json:"grace_period_seconds" binding:"min=0"`
+}
+
+// KeyOutput represents an API key without its secret
+type KeyOutput struct {
+	ID            uuid.UUID  `json:"id"`
+	PartnerID     uuid.UUID  `json:"partner_id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Scopes        []string   `json:"scopes"`
+	Status        string     `json:"status"` // "active", "expired" or "revoked"
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	RotatedFromID *uuid.UUID `json:"rotated_from_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// IssuedKeyOutput represents a newly issued API key. Key is the secret and is
+// never shown again.
+type IssuedKeyOutput struct {
+	KeyOutput
+	Key string `json:"key"`
+}
+
+type service struct {
+	repo repository.APIKeyRepository
+}
+
+// NewService creates a new API key service
+func NewService(repo repository.APIKeyRepository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Issue creates an API key for a partner
+func (s *service) Issue(ctx context.Context, input IssueKeyInput) (*IssuedKeyOutput, error) {
+	partnerID, err := uuid.Parse(input.PartnerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid partner ID", ErrInvalidInput)
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if input.ExpiresInDays < 0 {
+		return nil, fmt.Errorf("%w: expires_in_days must not be negative", ErrInvalidInput)
+	}
+
+	entity := &repository.APIKeyEntity{
+		PartnerID: partnerID,
+		Name:      input.Name,
+		Scopes:    scopes,
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, input.ExpiresInDays)
+		entity.ExpiresAt = &expiresAt
+	}
+	return s.create(ctx, entity)
+}
+
+// create generates the secret of a key and stores the key
+func (s *service) create(ctx context.Context, entity *repository.APIKeyEntity) (*IssuedKeyOutput, error) {
+	key, prefix, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	entity.Prefix = prefix
+	entity.KeyHash = hashKey(key)
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return nil, err
+	}
+	return &IssuedKeyOutput{KeyOutput: *toOutput(entity), Key: key}, nil
+}
+
+// List retrieves API keys, optionally only those of one partner
+func (s *service) List(ctx context.Context, partnerID *uuid.UUID) ([]*KeyOutput, error) {
+	keys, err := s.repo.List(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*KeyOutput, len(keys))
+	for i, key := range keys {
+		outputs[i] = toOutput(key)
+	}
+	return outputs, nil
+}
+
+// Rotate issues a replacement for an API key with the same partner, name,
+// scopes and expiry, and retires the old key after the grace period
+func (s *service) Rotate(ctx context.Context, id uuid.UUID, input RotateKeyInput) (*IssuedKeyOutput, error) {
+	if input.GracePeriodSeconds < 0 {
+		return nil, fmt.Errorf("%w: grace_period_seconds must not be negative", ErrInvalidInput)
+	}
+	old, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if old == nil {
+		return nil, ErrKeyNotFound
+	}
+	now := time.Now().UTC()
+	if keyStatus(old, now) != "active" {
+		return nil, ErrKeyInactive
+	}
+
+	issued, err := s.create(ctx, &repository.APIKeyEntity{
+		PartnerID:     old.PartnerID,
+		Name:          old.Name,
+		Scopes:        old.Scopes,
+		ExpiresAt:     old.ExpiresAt,
+		RotatedFromID: &old.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if input.GracePeriodSeconds == 0 {
+		err = s.repo.Revoke(ctx, old.ID, now)
+	} else {
+		retireAt := now.Add(time.Duration(input.GracePeriodSeconds) * time.Second)
+		if old.ExpiresAt == nil || retireAt.Before(*old.ExpiresAt) {
+			err = s.repo.SetExpiry(ctx, old.ID, retireAt)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("retiring rotated API key %s: %w", old.ID, err)
+	}
+	return issued, nil
+}
+
+// Revoke stops an API key from being accepted
+func (s *service) Revoke(ctx context.Context, id uuid.UUID) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return ErrKeyInactive
+	}
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+// Authenticate returns the API key that key is the secret of, if the key is
+// active
+func (s *service) Authenticate(ctx context.Context, key string) (*KeyOutput, error) {
+	prefix, ok := keyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	entity, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil || subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(entity.KeyHash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	now := time.Now().UTC()
+	if keyStatus(entity, now) != "active" {
+		return nil, ErrInvalidKey
+	}
+
+	// Recording every use would write on every request.
+	if entity.LastUsedAt == nil || now.Sub(*entity.LastUsedAt) > lastUsedInterval {
+		if err := s.repo.TouchLastUsed(ctx, entity.ID, now); err == nil {
+			entity.LastUsedAt = &now
+		}
+	}
+	return toOutput(entity), nil
+}
+
+// IsKey reports whether token looks like an API key rather than a JWT
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, KeyPrefix)
+}
+
+// generateKey returns a new secret key and its public prefix. Keys have the
+// form cbk_<prefix>_<secret>.
+func generateKey() (string, string, error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	return KeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes), prefix, nil
+}
+
+// keyPrefix extracts the public prefix of a key
+func keyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, KeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 12 || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// hashKey hashes a key for storage. Keys are long and random, so a single
+// round of SHA-256 is enough.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates scopes and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !Scopes[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func keyStatus(key *repository.APIKeyEntity, now time.Time) string {
+	switch {
+	case key.RevokedAt != nil:
+		return "revoked"
+	case key.ExpiresAt != nil && !now.Before(*key.ExpiresAt):
+		return "expired"
+	}
+	return "active"
+}
+
+func toOutput(key *repository.APIKeyEntity) *KeyOutput {
+	return &KeyOutput{
+		ID:            key.ID,
+		PartnerID:     key.PartnerID,
+		Name:          key.Name,
+		Prefix:        key.Prefix,
+		Scopes:        key.Scopes,
+		Status:        keyStatus(key, time.Now().UTC()),
+		ExpiresAt:     key.ExpiresAt,
+		RevokedAt:     key.RevokedAt,
+		LastUsedAt:    key.LastUsedAt,
+		RotatedFromID: key.RotatedFromID,
+		CreatedAt:     key.CreatedAt,
+	}
+}
@@ -0,0 +1,430 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/actor"
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// Entity types of audit entries
+const (
+	EntityPerson   = "person"
+	EntityBusiness = "business"
+	EntityAccount  = "account"
+	EntityTransfer = "transfer"
+	EntityUser     = "user"
+)
+
+// Masked replaces the values of sensitive fields in audit entries
+const Masked = "[masked]"
+
+// genesisHash is the previous hash of the first entry
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// sensitiveFields are never written to the audit log. A change to one is
+// recorded with both values masked.
+var sensitiveFields = map[string]bool{
+	"ssn":           true,
+	"tax_id":        true,
+	"government_id": true,
+	"date_of_birth": true,
+	"password":      true,
+	"password_hash": true,
+	"secret":        true,
+}
+
+// ignoredFields change on every update and are left out of diffs
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// maxAppendAttempts bounds the retries of an append that lost the race for
+// the next sequence to another instance
+const maxAppendAttempts = 5
+
+var ErrInvalidInput = errors.New("invalid audit query")
+
+// writeFailures counts the entries Record could not write, published with
+// expvar so that gaps in the log can be alerted on
+var writeFailures = expvar.NewInt("audit_write_failures")
+
+// Event is an action to record. Before and After are the entity before and
+// after the action, either of which may be nil; they are diffed field by
+// field as JSON.
+type Event struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+}
+
+// Recorder records events in the audit log
+type Recorder interface {
+	// Record appends an entry for event, attributed to the actor of ctx. By
+	// the time it is called the action has happened, so failures are counted
+	// in audit_write_failures and logged rather than returned.
+	Record(ctx context.Context, event Event)
+	// Write appends an entry for event like Record, but returns an error if
+	// it couldn't, for actions that must not happen unrecorded
+	Write(ctx context.Context, event Event) error
+}
+
+// Service records events in the audit log, and reads and verifies it
+type Service interface {
+	Recorder
+	List(ctx context.Context, input ListInput) (*EntryPageOutput, error)
+	Verify(ctx context.Context) (*VerifyOutput, error)
+}
+
+// ListInput represents the filters of an audit log query
+type ListInput struct {
+	PartnerID  *uuid.UUID
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	Since      *time.Time
+	Until      *time.Time
+	// Before is the sequence to continue a listing before, from NextBefore
+	Before int64
+	Limit  int
+}
+
+// EntryOutput represents an audit log entry
+type EntryOutput struct {
+	Sequence   int64                             `json:"sequence"`
+	OccurredAt time.Time                         `json:"occurred_at"`
+	ActorID    string                            `json:"actor_id"`
+	ActorRole  string                            `json:"actor_role"`
+	PartnerID  *uuid.UUID                        `json:"partner_id,omitempty"`
+	RequestID  string                            `json:"request_id"`
+	IP         string                            `json:"ip"`
+	Action     string                            `json:"action"`
+	EntityType string                            `json:"entity_type"`
+	EntityID   string                            `json:"entity_id"`
+	Changes    map[string]repository.AuditChange `json:"changes"`
+	PrevHash   string                            `json:"prev_hash"`
+	Hash       string                            `json:"hash"`
+}
+
+// EntryPageOutput represents one page of audit log entries, newest first.
+// NextBefore is zero on the last page.
+type EntryPageOutput struct {
+	Entries    []*EntryOutput `json:"entries"`
+	NextBefore int64          `json:"next_before,omitempty"`
+}
+
+// VerifyOutput reports whether the hash chain is intact. If it is not,
+// BrokenAt is the sequence of the first entry that doesn't match.
+type VerifyOutput struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// WriteFailures is how many entries this instance failed to write since
+	// it started; the chain can be intact and still miss them
+	WriteFailures int64 `json:"write_failures"`
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+	// verifyPageSize is how many entries Verify reads at a time
+	verifyPageSize = 1000
+)
+
+type service struct {
+	repo repository.AuditRepository
+	// mu serializes appends so that each extends the last, which is cached
+	// in lastSequence and lastHash once loaded. It is held across the
+	// repository calls: every entry needs the hash of the one before it, so
+	// the audited mutations of an instance wait on each other's appends.
+	mu           sync.Mutex
+	loaded       bool
+	lastSequence int64
+	lastHash     string
+}
+
+// NewService creates a new audit log service
+func NewService(repo repository.AuditRepository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Record appends an entry for event to the chain
+func (s *service) Record(ctx context.Context, event Event) {
+	if err := s.Write(ctx, event); err != nil {
+		writeFailures.Add(1)
+		log.Printf("Failed to write audit entry: action=%s %s=%s: %v", event.Action, event.EntityType, event.EntityID, err)
+	}
+}
+
+// Write appends an entry for event to the chain, retrying with the new last
+// entry if another instance appended first
+func (s *service) Write(ctx context.Context, event Event) error {
+	changes, err := diff(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+	a := actor.FromContext(ctx)
+	entry := &repository.AuditEntryEntity{
+		// Postgres keeps microseconds, so more would not survive to be verified
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		ActorID:    a.ID,
+		ActorRole:  a.Role,
+		RequestID:  a.RequestID,
+		IP:         a.IP,
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Changes:    changes,
+	}
+	// The tenant of the records touched, which for staff is not their own
+	if partnerID, ok := tenant.PartnerFromContext(ctx); ok {
+		entry.PartnerID = &partnerID
+	} else if partnerID, err := uuid.Parse(a.PartnerID); err == nil {
+		entry.PartnerID = &partnerID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		if !s.loaded {
+			last, err := s.repo.Last(ctx)
+			if err != nil {
+				return err
+			}
+			s.lastSequence, s.lastHash = 0, genesisHash
+			if last != nil {
+				s.lastSequence, s.lastHash = last.Sequence, last.Hash
+			}
+			s.loaded = true
+		}
+
+		entry.Sequence = s.lastSequence + 1
+		entry.PrevHash = s.lastHash
+		if entry.Hash, err = hashEntry(entry); err != nil {
+			return err
+		}
+		err = s.repo.Append(ctx, entry)
+		if err == nil {
+			s.lastSequence, s.lastHash = entry.Sequence, entry.Hash
+			return nil
+		}
+		// Most likely another instance took the sequence; extend its entry
+		s.loaded = false
+		if attempt == maxAppendAttempts {
+			return fmt.Errorf("appending audit entry after %d attempts: %w", attempt, err)
+		}
+	}
+}
+
+// List retrieves audit entries, newest first
+func (s *service) List(ctx context.Context, input ListInput) (*EntryPageOutput, error) {
+	if input.Limit <= 0 {
+		input.Limit = defaultLimit
+	}
+	if input.Limit > maxLimit {
+		input.Limit = maxLimit
+	}
+	if input.Before < 0 {
+		return nil, fmt.Errorf("%w: before must be positive", ErrInvalidInput)
+	}
+
+	entries, err := s.repo.List(ctx, repository.AuditFilter{
+		PartnerID:      input.PartnerID,
+		ActorID:        input.ActorID,
+		Action:         input.Action,
+		EntityType:     input.EntityType,
+		EntityID:       input.EntityID,
+		Since:          input.Since,
+		Until:          input.Until,
+		BeforeSequence: input.Before,
+		Limit:          input.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := &EntryPageOutput{Entries: make([]*EntryOutput, len(entries))}
+	for i, entry := range entries {
+		output.Entries[i] = toOutput(entry)
+	}
+	if len(entries) == input.Limit {
+		output.NextBefore = entries[len(entries)-1].Sequence
+	}
+	return output, nil
+}
+
+// Verify walks the whole chain, checking that every entry follows the one
+// before it and still has the hash it was written with
+func (s *service) Verify(ctx context.Context) (*VerifyOutput, error) {
+	output := &VerifyOutput{Valid: true, WriteFailures: writeFailures.Value()}
+	prevSequence, prevHash := int64(0), genesisHash
+	for {
+		entries, err := s.repo.List(ctx, repository.AuditFilter{
+			AfterSequence: prevSequence,
+			Limit:         verifyPageSize,
+			Ascending:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			var reason string
+			hash, err := hashEntry(entry)
+			switch {
+			case err != nil:
+				return nil, err
+			case entry.Sequence != prevSequence+1:
+				reason = fmt.Sprintf("entry %d is missing", prevSequence+1)
+			case entry.PrevHash != prevHash:
+				reason = "previous hash does not match the entry before"
+			case entry.Hash != hash:
+				reason = "hash does not match the entry's contents"
+			}
+			if reason != "" {
+				output.Valid, output.BrokenAt, output.Reason = false, entry.Sequence, reason
+				return output, nil
+			}
+			output.Entries++
+			prevSequence, prevHash = entry.Sequence, entry.Hash
+		}
+		if len(entries) < verifyPageSize {
+			return output, nil
+		}
+	}
+}
+
+// hashedEntry is what the hash of an entry covers, in a fixed field order
+type hashedEntry struct {
+	Sequence   int64                             `json:"sequence"`
+	OccurredAt string                            `json:"occurred_at"`
+	ActorID    string                            `json:"actor_id"`
+	ActorRole  string                            `json:"actor_role"`
+	PartnerID  string                            `json:"partner_id"`
+	RequestID  string                            `json:"request_id"`
+	IP         string                            `json:"ip"`
+	Action     string                            `json:"action"`
+	EntityType string                            `json:"entity_type"`
+	EntityID   string                            `json:"entity_id"`
+	Changes    map[string]repository.AuditChange `json:"changes"`
+	PrevHash   string                            `json:"prev_hash"`
+}
+
+// hashEntry returns the hex SHA-256 of an entry's contents and the hash of
+// the entry before it
+func hashEntry(entry *repository.AuditEntryEntity) (string, error) {
+	hashed := hashedEntry{
+		Sequence:   entry.Sequence,
+		OccurredAt: entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		RequestID:  entry.RequestID,
+		IP:         entry.IP,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Changes:    entry.Changes,
+		PrevHash:   entry.PrevHash,
+	}
+	if entry.PartnerID != nil {
+		hashed.PartnerID = entry.PartnerID.String()
+	}
+	// Maps marshal with sorted keys, so the encoding is canonical
+	data, err := json.Marshal(hashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// diff returns the fields that differ between before and after, with
+// sensitive values masked
+func diff(before, after interface{}) (map[string]repository.AuditChange, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]repository.AuditChange)
+	for _, side := range []map[string]interface{}{beforeFields, afterFields} {
+		for name := range side {
+			if _, done := changes[name]; done || ignoredFields[name] {
+				continue
+			}
+			b, a := beforeFields[name], afterFields[name]
+			if reflect.DeepEqual(b, a) {
+				continue
+			}
+			if sensitiveFields[strings.ToLower(name)] {
+				b, a = mask(b), mask(a)
+			}
+			changes[name] = repository.AuditChange{Before: b, After: a}
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the JSON fields of v
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("audited value must be a JSON object: %w", err)
+	}
+	return out, nil
+}
+
+// mask hides a sensitive value, keeping whether there was one
+func mask(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return Masked
+}
+
+func toOutput(entry *repository.AuditEntryEntity) *EntryOutput {
+	return &EntryOutput{
+		Sequence:   entry.Sequence,
+		OccurredAt: entry.OccurredAt,
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		PartnerID:  entry.PartnerID,
+		RequestID:  entry.RequestID,
+		IP:         entry.IP,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Changes:    entry.Changes,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+)
+
+// fakeRepository is an audit log in memory with a unique sequence, like the
+// table. failures makes that many appends fail first.
+type fakeRepository struct {
+	entries  []*repository.AuditEntryEntity
+	failures int
+	appends  int
+}
+
+func (r *fakeRepository) Append(ctx context.Context, entry *repository.AuditEntryEntity) error {
+	r.appends++
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("connection reset")
+	}
+	for _, e := range r.entries {
+		if e.Sequence == entry.Sequence {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+func (r *fakeRepository) Last(ctx context.Context) (*repository.AuditEntryEntity, error) {
+	if len(r.entries) == 0 {
+		return nil, nil
+	}
+	return r.entries[len(r.entries)-1], nil
+}
+
+func (r *fakeRepository) List(ctx context.Context, filter repository.AuditFilter) ([]*repository.AuditEntryEntity, error) {
+	var entries []*repository.AuditEntryEntity
+	for _, e := range r.entries {
+		if e.Sequence > filter.AfterSequence {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// writeEntries writes n entries through svc
+func writeEntries(t *testing.T, svc Service, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := svc.Write(context.Background(), Event{
+			Action:     "person.update",
+			EntityType: EntityPerson,
+			EntityID:   "p1",
+			Before:     map[string]interface{}{"first_name": "Ann", "n": i},
+			After:      map[string]interface{}{"first_name": "Anne", "n": i + 1},
+		})
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(r *fakeRepository)
+		wantValid    bool
+		wantBrokenAt int64
+		wantReason   string
+	}{
+		{
+			name:      "intact",
+			tamper:    func(r *fakeRepository) {},
+			wantValid: true,
+		},
+		{
+			name:         "edited entry",
+			tamper:       func(r *fakeRepository) { r.entries[2].Action = "person.delete" },
+			wantBrokenAt: 3,
+			wantReason:   "hash does not match",
+		},
+		{
+			name: "edited and rehashed entry",
+			tamper: func(r *fakeRepository) {
+				r.entries[2].ActorID = "someone-else"
+				r.entries[2].Hash, _ = hashEntry(r.entries[2])
+			},
+			wantBrokenAt: 4,
+			wantReason:   "previous hash does not match",
+		},
+		{
+			name:         "deleted entry",
+			tamper:       func(r *fakeRepository) { r.entries = append(r.entries[:1], r.entries[2:]...) },
+			wantBrokenAt: 3,
+			wantReason:   "entry 2 is missing",
+		},
+		{
+			// The chain alone can't tell that the newest entries were dropped
+			name:      "truncated log",
+			tamper:    func(r *fakeRepository) { r.entries = r.entries[:3] },
+			wantValid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			svc := NewService(repo)
+			writeEntries(t, svc, 5)
+			tt.tamper(repo)
+
+			output, err := svc.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if output.Valid != tt.wantValid || output.BrokenAt != tt.wantBrokenAt || !strings.HasPrefix(output.Reason, tt.wantReason) {
+				t.Errorf("Verify = %+v, want valid %v broken at %d (%s)", output, tt.wantValid, tt.wantBrokenAt, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestWriteRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantErr      bool
+		wantAppends  int
+		wantSequence int64
+	}{
+		{name: "first attempt", failures: 0, wantAppends: 1, wantSequence: 1},
+		{name: "transient failure", failures: 2, wantAppends: 3, wantSequence: 1},
+		{name: "persistent failure", failures: maxAppendAttempts, wantErr: true, wantAppends: maxAppendAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{failures: tt.failures}
+			svc := NewService(repo)
+			err := svc.Write(context.Background(), Event{Action: "person.reveal", EntityType: EntityPerson, EntityID: "p1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Write error = %v, want error %v", err, tt.wantErr)
+			}
+			if repo.appends != tt.wantAppends {
+				t.Errorf("%d appends, want %d", repo.appends, tt.wantAppends)
+			}
+			if !tt.wantErr && repo.entries[0].Sequence != tt.wantSequence {
+				t.Errorf("sequence %d, want %d", repo.entries[0].Sequence, tt.wantSequence)
+			}
+		})
+	}
+}
+
+func TestRecordCountsFailures(t *testing.T) {
+	before := writeFailures.Value()
+	svc := NewService(&fakeRepository{failures: maxAppendAttempts})
+	svc.Record(context.Background(), Event{Action: "person.update", EntityType: EntityPerson, EntityID: "p1"})
+	if got := writeFailures.Value() - before; got != 1 {
+		t.Errorf("audit_write_failures grew by %d, want 1", got)
+	}
+}
+
+func TestWriteExtendsOtherInstances(t *testing.T) {
+	// Two instances share the log; each has cached the last entry it wrote
+	repo := &fakeRepository{}
+	first, second := NewService(repo), NewService(repo)
+	writeEntries(t, first, 1)
+	writeEntries(t, second, 1)
+	writeEntries(t, first, 1)
+
+	output, err := first.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !output.Valid || output.Entries != 3 {
+		t.Errorf("Verify = %+v, want 3 valid entries", output)
+	}
+}
+
+func TestWriteMasksSensitiveFields(t *testing.T) {
+	repo := &fakeRepository{}
+	svc := NewService(repo)
+	err := svc.Write(context.Background(), Event{
+		Action:     "person.update",
+		EntityType: EntityPerson,
+		EntityID:   "p1",
+		Before:     map[string]interface{}{"ssn": "123-45-6789", "email": "a@example.com", "updated_at": "1"},
+		After:      map[string]interface{}{"ssn": "987-65-4321", "email": "b@example.com", "updated_at": "2", "tax_id": "12-3456789"},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	want := map[string]repository.AuditChange{
+		"ssn":    {Before: Masked, After: Masked},
+		"email":  {Before: "a@example.com", After: "b@example.com"},
+		"tax_id": {Before: nil, After: Masked},
+	}
+	changes := repo.entries[0].Changes
+	if len(changes) != len(want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	for field, change := range want {
+		if changes[field] != change {
+			t.Errorf("change of %s = %v, want %v", field, changes[field], change)
+		}
+	}
+}
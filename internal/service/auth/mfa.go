@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/totp"
+	"github.com/google/uuid"
+)
+
+// Authentication methods recorded in the amr claim, from RFC 8176
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+)
+
+// recoveryCodeCount is how many recovery codes are issued when MFA is enabled
+const recoveryCodeCount = 10
+
+var (
+	ErrInvalidMFACode    = errors.New("invalid MFA code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired MFA token")
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	ErrMFANotEnrolled    = errors.New("MFA enrollment has not been started")
+	ErrMFANotEnabled     = errors.New("MFA is not enabled")
+)
+
+// MFAEnrollment is a new TOTP secret for a user to add to their
+// authenticator app, directly or by scanning ProvisioningURI as a QR code
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// CompleteMFA exchanges the MFA token a user got at login and a TOTP or
+// recovery code for a JWT and refresh token that record both factors
+func (s *service) CompleteMFA(ctx context.Context, mfaToken, code string) (*Tokens, error) {
+	userID, err := s.jwtService.ValidateMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	entity, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.MFAEnabledAt == nil {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.checkMFACode(ctx, entity, code, true); err != nil {
+		s.recordLogin(ctx, entity, entity.Username, nil, err)
+		return nil, err
+	}
+	if err := s.userRepo.RecordLogin(ctx, entity.ID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	amr := []string{AMRPassword, AMROTP, AMRMFA}
+	s.recordLogin(ctx, entity, entity.Username, amr, nil)
+
+	return s.issueTokens(ctx, entity, uuid.New(), amr)
+}
+
+// EnrollMFA starts TOTP enrollment with a new secret. MFA is not required
+// until ActivateMFA confirms the user's app generates the right codes.
+func (s *service) EnrollMFA(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	entity, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.MFAEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetMFA(ctx, entity.ID, &secret, nil, nil); err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.mfaIssuer, entity.Username, secret),
+	}, nil
+}
+
+// ActivateMFA enables MFA once the user proves their app is set up with a
+// current code, and returns recovery codes for when the app is lost. The
+// codes are only shown this once.
+func (s *service) ActivateMFA(ctx context.Context, userID, code string) ([]string, error) {
+	entity, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entity.MFAEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if entity.MFASecret == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.checkMFACode(ctx, entity, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if err := s.userRepo.SetMFA(ctx, entity.ID, entity.MFASecret, &now, hashes); err != nil {
+		return nil, err
+	}
+	// Keep the step of the code just used, so it can't be replayed
+	if step, ok := totp.Validate(*entity.MFASecret, code, now); ok {
+		if _, err := s.userRepo.AdvanceMFAStep(ctx, entity.ID, step); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after checking a current TOTP or recovery code
+func (s *service) DisableMFA(ctx context.Context, userID, code string) error {
+	entity, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if entity.MFAEnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+	if err := s.checkMFACode(ctx, entity, code, true); err != nil {
+		return err
+	}
+	return s.userRepo.SetMFA(ctx, entity.ID, nil, nil, nil)
+}
+
+// checkMFACode verifies a TOTP code, or a recovery code if allowRecovery is
+// set, which is then used up. Failures count towards a lockout like wrong
+// passwords do.
+func (s *service) checkMFACode(ctx context.Context, entity *repository.UserEntity, code string, allowRecovery bool) error {
+	now := time.Now().UTC()
+	if isLocked(entity, now) {
+		return ErrAccountLocked
+	}
+	if entity.MFASecret == nil {
+		return ErrMFANotEnrolled
+	}
+
+	if step, ok := totp.Validate(*entity.MFASecret, code, now); ok {
+		advanced, err := s.userRepo.AdvanceMFAStep(ctx, entity.ID, step)
+		if err != nil {
+			return err
+		}
+		if advanced {
+			return nil
+		}
+	} else if allowRecovery {
+		hash := hashRecoveryCode(code)
+		for _, stored := range entity.MFARecoveryCodes {
+			if subtle.ConstantTimeCompare([]byte(hash), []byte(stored)) != 1 {
+				continue
+			}
+			// Fails if the codes changed since they were read, such as
+			// when the same code is used concurrently
+			used, err := s.userRepo.UseMFARecoveryCode(ctx, entity.ID, entity.MFARecoveryCodes, hash)
+			if err != nil {
+				return err
+			}
+			if used {
+				return nil
+			}
+			break
+		}
+	}
+	return s.recordFailure(ctx, entity, now, ErrInvalidMFACode)
+}
+
+// generateRecoveryCodes returns new recovery codes of the form xxxxx-xxxxx
+// and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, 7)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, fmt.Errorf("generating recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(random))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage, ignoring case, spaces
+// and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
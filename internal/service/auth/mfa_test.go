@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/totp"
+	"github.com/google/uuid"
+)
+
+// fakeUsers holds one user and makes the same conditional updates as the
+// table
+type fakeUsers struct {
+	repository.UserRepository
+	mu   sync.Mutex
+	user repository.UserEntity
+}
+
+// read returns a copy of the user as a request would read it
+func (r *fakeUsers) read() *repository.UserEntity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := r.user
+	user.MFARecoveryCodes = append([]string{}, r.user.MFARecoveryCodes...)
+	return &user
+}
+
+func (r *fakeUsers) UseMFARecoveryCode(ctx context.Context, id uuid.UUID, recoveryCodes []string, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(recoveryCodes) != len(r.user.MFARecoveryCodes) {
+		return false, nil
+	}
+	var remaining []string
+	for i, code := range r.user.MFARecoveryCodes {
+		if code != recoveryCodes[i] {
+			return false, nil
+		}
+		if code != hash {
+			remaining = append(remaining, code)
+		}
+	}
+	r.user.MFARecoveryCodes = remaining
+	return true, nil
+}
+
+func (r *fakeUsers) AdvanceMFAStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.user.MFALastStep >= step {
+		return false, nil
+	}
+	r.user.MFALastStep = step
+	return true, nil
+}
+
+func (r *fakeUsers) SetLockout(ctx context.Context, id uuid.UUID, failedAttempts int, lockedUntil *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.user.FailedLoginAttempts = failedAttempts
+	r.user.LockedUntil = lockedUntil
+	return nil
+}
+
+func TestCheckMFACodeUsesCodesOnce(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	totpCode, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// use is the codes used in order. A concurrent use reads the user
+		// before the previous use is written.
+		use        []string
+		concurrent bool
+		want       []error
+		// wantRemaining is how many recovery codes are left
+		wantRemaining int
+	}{
+		{
+			name:          "recovery code",
+			use:           []string{codes[0]},
+			want:          []error{nil},
+			wantRemaining: len(codes) - 1,
+		},
+		{
+			name:          "recovery code twice",
+			use:           []string{codes[0], codes[0]},
+			want:          []error{nil, ErrInvalidMFACode},
+			wantRemaining: len(codes) - 1,
+		},
+		{
+			name:          "recovery code twice concurrently",
+			use:           []string{codes[0], codes[0]},
+			concurrent:    true,
+			want:          []error{nil, ErrInvalidMFACode},
+			wantRemaining: len(codes) - 1,
+		},
+		{
+			// The second code is refused rather than putting the first back
+			name:          "two recovery codes concurrently",
+			use:           []string{codes[0], codes[1]},
+			concurrent:    true,
+			want:          []error{nil, ErrInvalidMFACode},
+			wantRemaining: len(codes) - 1,
+		},
+		{
+			name:          "two recovery codes",
+			use:           []string{codes[0], codes[1]},
+			want:          []error{nil, nil},
+			wantRemaining: len(codes) - 2,
+		},
+		{
+			name:          "TOTP code twice concurrently",
+			use:           []string{totpCode, totpCode},
+			concurrent:    true,
+			want:          []error{nil, ErrInvalidMFACode},
+			wantRemaining: len(codes),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{user: repository.UserEntity{
+				ID:               uuid.New(),
+				MFASecret:        &secret,
+				MFARecoveryCodes: append([]string{}, hashes...),
+			}}
+			s := &service{userRepo: users, lockout: LockoutPolicy{MaxFailedAttempts: 5, Duration: time.Minute}}
+
+			first := users.read()
+			for i, code := range tt.use {
+				entity := first
+				if i > 0 && !tt.concurrent {
+					entity = users.read()
+				}
+				if err := s.checkMFACode(context.Background(), entity, code, true); !errors.Is(err, tt.want[i]) {
+					t.Errorf("use %d: error = %v, want %v", i, err, tt.want[i])
+				}
+			}
+			if remaining := len(users.read().MFARecoveryCodes); remaining != tt.wantRemaining {
+				t.Errorf("%d recovery codes left, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
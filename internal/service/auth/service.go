@@ -1,68 +1,385 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/Cassandra-Labs-Foundation/core/internal/actor"
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/user"
 	"github.com/Cassandra-Labs-Foundation/core/pkg/jwt"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/password"
+	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrAccountLocked       = errors.New("account is temporarily locked")
+	ErrInvalidPassword     = password.ErrInvalid
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+)
+
+// RefreshTokenPrefix starts every refresh token, so that they can be told
+// apart from access tokens and found by secret scanners
+const RefreshTokenPrefix = "crt_"
+
+// Tokens are issued at login and on every refresh. The refresh token can be
+// used once, to get the next pair. Users with MFA get only an MFAToken at
+// login, which CompleteMFA exchanges for the others.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+}
+
 // Service provides authentication business logic
 type Service interface {
-	Login(username, password string) (string, error)
-	RefreshToken(tokenString string) (string, error)
-	ValidateToken(tokenString string) (string, string, error)
+	Login(ctx context.Context, username, password string) (*Tokens, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*Tokens, error)
+	Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error
+	ValidateToken(ctx context.Context, tokenString string) (*jwt.Claims, error)
+	JWKS() jwt.JWKSet
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+	CompleteMFA(ctx context.Context, mfaToken, code string) (*Tokens, error)
+	EnrollMFA(ctx context.Context, userID string) (*MFAEnrollment, error)
+	ActivateMFA(ctx context.Context, userID, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID, code string) error
+}
+
+// LockoutPolicy controls how many failed logins in a row lock a user out, and
+// for how long. A zero MaxFailedAttempts disables lockout.
+type LockoutPolicy struct {
+	MaxFailedAttempts int
+	Duration          time.Duration
 }
 
 type service struct {
-	jwtService jwt.Service
-	// In a real application, you would have a repository to store users and their credentials
-	// userRepo repository.UserRepository
+	jwtService  jwt.Service
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	revokedRepo repository.RevokedTokenRepository
+	lockout     LockoutPolicy
+	refreshTTL  time.Duration
+	mfaIssuer   string
+	auditor     audit.Recorder
+	// dummyHash is compared against when a username is unknown, so that
+	// unknown and known usernames take as long to reject
+	dummyHash string
 }
 
-// NewService creates a new authentication service
-func NewService(jwtService jwt.Service) Service {
+// NewService creates a new authentication service. Refresh tokens are valid
+// for refreshTTL after they are issued, and authenticator apps list TOTP
+// enrollments under mfaIssuer. Logins are recorded with auditor.
+func NewService(jwtService jwt.Service, userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, revokedRepo repository.RevokedTokenRepository, lockout LockoutPolicy, refreshTTL time.Duration, mfaIssuer string, auditor audit.Recorder) (Service, error) {
+	dummyHash, err := password.Hash(uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
 	return &service{
-		jwtService: jwtService,
+		jwtService:  jwtService,
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		revokedRepo: revokedRepo,
+		lockout:     lockout,
+		refreshTTL:  refreshTTL,
+		mfaIssuer:   mfaIssuer,
+		auditor:     auditor,
+		dummyHash:   dummyHash,
+	}, nil
+}
+
+// Login authenticates a user and returns a JWT and a refresh token starting a
+// new token family if successful. Users who enabled MFA get an MFA challenge
+// token instead.
+func (s *service) Login(ctx context.Context, username, plain string) (*Tokens, error) {
+	entity, err := s.userRepo.GetByUsername(ctx, user.NormalizeUsername(username))
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		password.Compare(s.dummyHash, plain)
+		s.recordLogin(ctx, nil, username, nil, ErrInvalidCredentials)
+		return nil, ErrInvalidCredentials
+	}
+	
+	if err := s.checkPassword(ctx, entity, plain); err != nil {
+		s.recordLogin(ctx, entity, entity.Username, nil, err)
+		return nil, err
+	}
+	if entity.MFAEnabledAt != nil {
+		mfaToken, err := s.jwtService.GenerateMFAChallenge(entity.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		return &Tokens{MFAToken: mfaToken}, nil
 	}
+	if err := s.userRepo.RecordLogin(ctx, entity.ID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	s.recordLogin(ctx, entity, entity.Username, []string{AMRPassword}, nil)
+	
+	return s.issueTokens(ctx, entity, uuid.New(), []string{AMRPassword})
 }
 
-// Login authenticates a user and returns a JWT token if successful
-func (s *service) Login(username, password string) (string, error) {
-	// In a real application, you would look up the user in a database
-	// and verify the password. For this example, we'll use hardcoded credentials.
+// RefreshToken exchanges a refresh token for a new JWT with the user's current
+// role and a new refresh token. Each refresh token can only be used once: if
+// one is used again, it has probably leaked, so its whole family is revoked.
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (*Tokens, error) {
+	if !strings.HasPrefix(refreshToken, RefreshTokenPrefix) {
+		return nil, ErrInvalidRefreshToken
+	}
+	stored, err := s.refreshRepo.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if stored == nil || stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
 	
-	// This is just for demo purposes. In a real app, NEVER store passwords in code.
-	if username == "admin" && password == "password" {
-		// Generate a token for the admin user
-		return s.jwtService.GenerateToken("admin-user-id", "admin")
+	// Marking the token used only succeeds once, so concurrent uses are
+	// caught as reuse too
+	marked := false
+	if stored.UsedAt == nil {
+		marked, err = s.refreshRepo.MarkUsed(ctx, stored.ID, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !marked {
+		log.Printf("Refresh token reused: user=%s family=%s; revoking the family", stored.UserID, stored.FamilyID)
+		if err := s.refreshRepo.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	
+	// Users who were deleted or locked out since the token was issued can't refresh it
+	entity, err := s.getUser(ctx, stored.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+	if isLocked(entity, now) {
+		return nil, ErrAccountLocked
 	}
 	
-	if username == "user" && password == "password" {
-		// Generate a token for a regular user
-		return s.jwtService.GenerateToken("regular-user-id", "user")
+	return s.issueTokens(ctx, entity, stored.FamilyID, stored.AMR)
+}
+
+// Logout revokes the JWT with the given claims and, if one is given, the
+// family of the user's refresh token
+func (s *service) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	var familyID *uuid.UUID
+	if refreshToken != "" {
+		stored, err := s.refreshRepo.GetByHash(ctx, hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID.String() != claims.UserID {
+			return ErrInvalidRefreshToken
+		}
+		familyID = &stored.FamilyID
 	}
 	
-	return "", errors.New("invalid credentials")
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.revokedRepo.Add(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if familyID == nil {
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(ctx, *familyID, time.Now().UTC())
 }
 
-// RefreshToken validates an existing token and returns a new one
-func (s *service) RefreshToken(tokenString string) (string, error) {
-	// Validate the current token
+// ValidateToken validates a token and returns its claims. Tokens on the
+// denylist are rejected with ErrTokenRevoked.
+func (s *service) ValidateToken(ctx context.Context, tokenString string) (*jwt.Claims, error) {
 	claims, err := s.jwtService.ValidateToken(tokenString)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if claims.ID != "" {
+		revoked, err := s.revokedRepo.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys tokens can be verified with
+func (s *service) JWKS() jwt.JWKSet {
+	return s.jwtService.JWKS()
+}
+
+// issueTokens issues a JWT for a user, naming their partner if they act for
+// one and how they authenticated, and a refresh token in the given family
+func (s *service) issueTokens(ctx context.Context, entity *repository.UserEntity, familyID uuid.UUID, amr []string) (*Tokens, error) {
+	partnerID := ""
+	if entity.PartnerID != nil {
+		partnerID = entity.PartnerID.String()
+	}
+	accessToken, err := s.jwtService.GenerateToken(entity.ID.String(), entity.Role, partnerID, amr)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Generate a new token with the same claims
-	return s.jwtService.GenerateToken(claims.UserID, claims.Role)
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.refreshRepo.Create(ctx, &repository.RefreshTokenEntity{
+		FamilyID:  familyID,
+		UserID:    entity.ID,
+		TokenHash: hashToken(refreshToken),
+		AMR:       amr,
+		ExpiresAt: time.Now().UTC().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	return &Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-// ValidateToken validates a token and returns the user ID and role
-func (s *service) ValidateToken(tokenString string) (string, string, error) {
-	claims, err := s.jwtService.ValidateToken(tokenString)
+// ChangePassword replaces a user's password after checking their current one.
+// Wrong current passwords count towards a lockout like failed logins do.
+func (s *service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	entity, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(ctx, entity, currentPassword); err != nil {
+		return err
+	}
+	if currentPassword == newPassword {
+		return fmt.Errorf("%w: new password must differ from the current one", ErrInvalidPassword)
+	}
+	
+	hash, err := password.Hash(newPassword)
 	if err != nil {
-		return "", "", err
+		return err
+	}
+	now := time.Now().UTC()
+	if err := s.userRepo.SetPassword(ctx, entity.ID, hash, now); err != nil {
+		return err
+	}
+	// Sessions started with the old password can't be refreshed
+	return s.refreshRepo.RevokeUser(ctx, entity.ID, now)
+}
+
+// checkPassword verifies a user's password, counting failures and locking the
+// user out once they reach the policy's limit. Locked out users are rejected
+// without checking the password.
+func (s *service) checkPassword(ctx context.Context, entity *repository.UserEntity, plain string) error {
+	now := time.Now().UTC()
+	if isLocked(entity, now) {
+		return ErrAccountLocked
 	}
 	
-	return claims.UserID, claims.Role, nil
+	err := password.Compare(entity.PasswordHash, plain)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, password.ErrMismatch) {
+		return err
+	}
+	return s.recordFailure(ctx, entity, now, ErrInvalidCredentials)
+}
+
+// recordFailure counts a failed attempt at a user's password or MFA code,
+// locking the user out once they reach the policy's limit. It returns
+// ErrAccountLocked if they did, and failure otherwise.
+func (s *service) recordFailure(ctx context.Context, entity *repository.UserEntity, now time.Time, failure error) error {
+	attempts := entity.FailedLoginAttempts + 1
+	var lockedUntil *time.Time
+	if s.lockout.MaxFailedAttempts > 0 && attempts >= s.lockout.MaxFailedAttempts {
+		until := now.Add(s.lockout.Duration)
+		lockedUntil = &until
+		attempts = 0
+	}
+	if err := s.userRepo.SetLockout(ctx, entity.ID, attempts, lockedUntil); err != nil {
+		return err
+	}
+	if lockedUntil != nil {
+		return ErrAccountLocked
+	}
+	return failure
+}
+
+// recordLogin records a login attempt in the audit log as made by the user it
+// was for, if the username is known. Only wrong credentials and lockouts are
+// recorded as failures.
+func (s *service) recordLogin(ctx context.Context, entity *repository.UserEntity, username string, amr []string, err error) {
+	event := audit.Event{
+		Action:     "login.succeed",
+		EntityType: audit.EntityUser,
+		After:      map[string]interface{}{"username": username, "amr": amr},
+	}
+	if err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) && !errors.Is(err, ErrInvalidMFACode) && !errors.Is(err, ErrAccountLocked) {
+			return
+		}
+		event.Action = "login.fail"
+		event.After = map[string]interface{}{"username": username, "reason": err.Error()}
+	}
+	if entity != nil {
+		event.EntityID = entity.ID.String()
+		partnerID := ""
+		if entity.PartnerID != nil {
+			partnerID = entity.PartnerID.String()
+		}
+		ctx = actor.WithCaller(ctx, entity.ID.String(), entity.Role, partnerID)
+	}
+	s.auditor.Record(ctx, event)
+}
+
+// getUser looks up the user a token was issued to
+func (s *service) getUser(ctx context.Context, userID string) (*repository.UserEntity, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	entity, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return entity, nil
+}
+
+func isLocked(entity *repository.UserEntity, now time.Time) bool {
+	return entity.LockedUntil != nil && now.Before(*entity.LockedUntil)
+}
+
+// generateRefreshToken generates a random refresh token
+func generateRefreshToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating refresh token: %w", err)
+	}
+	return RefreshTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashToken hashes a refresh token for storage. Tokens are long and random, so
+// a single SHA-256 is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
\ No newline at end of file
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
 	"github.com/google/uuid"
 )
 
@@ -46,6 +47,7 @@ type UpdateBusinessInput struct {
 
 type BusinessOutput struct {
 	ID                 uuid.UUID  `json:"id"`
+	PartnerID          uuid.UUID  `json:"partner_id"`
 	Name               string     `json:"name"`
 	RegistrationNumber string     `json:"registration_number"`
 	Address            string     `json:"address"`
@@ -58,11 +60,13 @@ type BusinessOutput struct {
 
 type service struct {
 	businessRepo repository.BusinessRepository
+	auditor      audit.Recorder
 }
 
-func NewService(businessRepo repository.BusinessRepository) Service {
+func NewService(businessRepo repository.BusinessRepository, auditor audit.Recorder) Service {
 	return &service{
 		businessRepo: businessRepo,
+		auditor:      auditor,
 	}
 }
 
@@ -73,10 +77,12 @@ func (s *service) Create(ctx context.Context, input CreateBusinessInput) (*Busin
 		Address:            input.Address,
 		Country:            input.Country,
 		KYCStatus:          "pending",
+		TaxID:              input.TaxID,
 	}
 	if err := s.businessRepo.Create(ctx, business); err != nil {
 		return nil, err
 	}
+	s.auditor.Record(ctx, audit.Event{Action: "business.create", EntityType: audit.EntityBusiness, EntityID: business.ID.String(), After: business})
 	return s.entityToOutput(business), nil
 }
 
@@ -99,6 +105,7 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdateBusiness
 	if business == nil {
 		return nil, ErrBusinessNotFound
 	}
+	before := *business
 	if input.Name != nil {
 		business.Name = *input.Name
 	}
@@ -111,6 +118,9 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdateBusiness
 	if input.Country != nil {
 		business.Country = *input.Country
 	}
+	if input.TaxID != nil {
+		business.TaxID = input.TaxID
+	}
 	if input.KYCStatus != nil {
 		business.KYCStatus = *input.KYCStatus
 		if *input.KYCStatus == "verified" && business.KYCVerifiedAt == nil {
@@ -121,6 +131,7 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdateBusiness
 	if err := s.businessRepo.Update(ctx, business); err != nil {
 		return nil, err
 	}
+	s.auditor.Record(ctx, audit.Event{Action: "business.update", EntityType: audit.EntityBusiness, EntityID: business.ID.String(), Before: &before, After: business})
 	return s.entityToOutput(business), nil
 }
 
@@ -139,6 +150,7 @@ func (s *service) List(ctx context.Context, limit, offset int) ([]*BusinessOutpu
 func (s *service) entityToOutput(entity *repository.BusinessEntity) *BusinessOutput {
 	return &BusinessOutput{
 		ID:                 entity.ID,
+		PartnerID:          entity.PartnerID,
 		Name:               entity.Name,
 		RegistrationNumber: entity.RegistrationNumber,
 		Address:            entity.Address,
@@ -2,31 +2,1333 @@ package ledger
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger"
+	"github.com/Cassandra-Labs-Foundation/core/internal/money"
 	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	"github.com/google/uuid"
 )
 
+// MaxLookupAccounts is the largest number of accounts that can be looked up at once.
+const MaxLookupAccounts = 100
+
+// MaxBatchTransfers is the largest number of legs in an atomic transfer batch.
+const MaxBatchTransfers = 100
+
+var (
+	ErrAccountNotFound       = errors.New("account not found")
+	ErrTooManyAccounts       = errors.New("too many accounts requested")
+	ErrInvalidBatch          = errors.New("invalid transfer batch")
+	ErrInvalidTransferFilter = errors.New("invalid transfer filter")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidAmount         = money.ErrInvalidAmount
+	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrSameAccount           = errors.New("debit and credit accounts must be different")
+
+	ErrUnknownSettlementAccount = errors.New("unknown settlement account")
+	ErrUnsupportedCurrency      = errors.New("unsupported currency")
+	ErrCurrencyMismatch         = errors.New("currency mismatch")
+	ErrInvalidExchange          = errors.New("invalid currency exchange")
+	ErrInvalidTransferCode      = errors.New("invalid transfer code")
+	ErrInvalidUserData          = errors.New("invalid user data")
+
+	ErrInvalidAccount   = errors.New("invalid account data")
+	ErrOwnerNotFound    = errors.New("account owner not found")
+	ErrOwnerNotVerified = errors.New("account owner has not passed KYC verification")
+
+	ErrPendingTransferNotFound = errors.New("pending transfer not found")
+	ErrPendingTransferClosed   = errors.New("pending transfer is already posted, voided or expired")
+	ErrExceedsPendingAmount    = errors.New("amount exceeds the pending transfer amount")
+)
+
+// ProductTypes are the kinds of account that can be opened.
+var ProductTypes = map[string]bool{
+	"checking": true,
+	"savings":  true,
+}
+
+// ReservedCodes are the transfer codes the service assigns itself, which
+// requests cannot choose.
+var ReservedCodes = map[uint16]bool{
+	repository.DepositCode:        true,
+	repository.WithdrawalCode:     true,
+	repository.OpeningDepositCode: true,
+	repository.ExchangeCode:       true,
+}
+
+// Defaults for the account fields that a request may omit.
+const (
+	DefaultProductType = "checking"
+	DefaultCurrency    = "USD"
+)
+
+// FieldError reports the request field that caused an operation to fail.
+// Err is the service error the problem corresponds to, such as
+// ErrInvalidAmount or ErrAccountNotFound.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError attributes err to a request field
+func fieldError(field string, err error) *FieldError {
+	fieldErr := &FieldError{Field: field, Message: err.Error(), Err: err}
+	var inner *FieldError
+	if errors.As(err, &inner) {
+		fieldErr.Message = inner.Message
+	}
+	return fieldErr
+}
+
+// BatchRejectedError reports the leg that caused a transfer batch to be
+// rejected. No leg of a rejected batch is posted. Field is the error of the
+// request field of the leg at fault, if the ledger result points to one.
+type BatchRejectedError struct {
+	Index  int
+	Result string
+	Field  *FieldError
+	Err    error
+}
+
+func (e *BatchRejectedError) Error() string {
+	return fmt.Sprintf("transfer batch rejected at transfer %d: %s", e.Index, e.Result)
+}
+
+func (e *BatchRejectedError) Unwrap() error {
+	return e.Err
+}
+
 // Service defines ledger business operations.
 type Service interface {
-	CreateAccount(ctx context.Context, initialBalance int64) (string, error)
-	TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount int64) error
+	CreateSystemAccounts(ctx context.Context) error
+	CreateAccount(ctx context.Context, input CreateAccountInput) (*RegisteredAccountOutput, error)
+	ListOwnerAccounts(ctx context.Context, ownerType string, ownerID uuid.UUID) ([]*RegisteredAccountOutput, error)
+	Deposit(ctx context.Context, input SettlementTransferInput) (string, error)
+	Withdraw(ctx context.Context, input SettlementTransferInput) (string, error)
+	TransferFunds(ctx context.Context, input TransferInput) (string, error)
+	TransferBatch(ctx context.Context, input BatchTransferInput) ([]string, error)
+	ExchangeFunds(ctx context.Context, input ExchangeInput) ([]string, error)
+	CreatePendingTransfer(ctx context.Context, input PendingTransferInput) (string, error)
+	PostPendingTransfer(ctx context.Context, pendingID uuid.UUID, input PostPendingTransferInput) (string, error)
+	VoidPendingTransfer(ctx context.Context, pendingID uuid.UUID) (string, error)
+	GetAccount(ctx context.Context, id uuid.UUID) (*AccountOutput, error)
+	GetAccounts(ctx context.Context, ids []uuid.UUID) ([]*AccountOutput, error)
+	ListTransfers(ctx context.Context, accountID uuid.UUID, input ListTransfersInput) (*TransferPageOutput, error)
+}
+
+// AccountOutput represents a ledger account and its balances.
+// Amounts are decimal strings in the currency of the account's ledger.
+// Balances are expressed on the account's normal side, so a positive balance
+// always means the account holds value.
+type AccountOutput struct {
+	ID               uuid.UUID            `json:"id"`
+	Ledger           uint32               `json:"ledger"`
+	Currency         string               `json:"currency"`
+	Code             uint16               `json:"code"`
+	NormalBalance    ledger.NormalBalance `json:"normal_balance"`
+	DebitsPending    string               `json:"debits_pending"`
+	DebitsPosted     string               `json:"debits_posted"`
+	CreditsPending   string               `json:"credits_pending"`
+	CreditsPosted    string               `json:"credits_posted"`
+	PostedBalance    money.Money          `json:"posted_balance"`
+	PendingBalance   money.Money          `json:"pending_balance"`
+	AvailableBalance money.Money          `json:"available_balance"`
+	CreatedAt        time.Time            `json:"created_at"`
+}
+
+// CreateAccountInput represents the input for opening an account for a person
+// or business entity. A positive initial balance, a decimal amount in the
+// account's currency, is funded by an opening deposit from the default
+// settlement account.
+type CreateAccountInput struct {
+	OwnerType      string `json:"owner_type" binding:"required,oneof=person business"`
+	OwnerID        string `json:"owner_id" binding:"required,uuid"`
+	ProductType    string `json:"product_type"` // "checking" (default) or "savings"
+	Currency       string `json:"currency"`     // ISO 4217 code, default "USD"
+	InitialBalance string `json:"initial_balance"`
+}
+
+// RegisteredAccountOutput represents an account in the registry together with
+// the balances of its ledger account
+type RegisteredAccountOutput struct {
+	ID              uuid.UUID      `json:"id"`
+	LedgerAccountID uuid.UUID      `json:"ledger_account_id"`
+	OwnerType       string         `json:"owner_type"`
+	OwnerID         uuid.UUID      `json:"owner_id"`
+	ProductType     string         `json:"product_type"`
+	Currency        string         `json:"currency"`
+	Status          string         `json:"status"`
+	Balance         *AccountOutput `json:"balance,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// SettlementAccounts names the system accounts that deposits are funded from
+// and withdrawals are paid into, such as "cash" or "fbo". Default is the name
+// used when a request does not choose one, and funds opening deposits.
+//
+// Each currency has its own settlement account on its ledger; its ID is
+// derived from the configured base ID and the currency code.
+type SettlementAccounts struct {
+	Accounts map[string]uuid.UUID
+	Default  string
+}
+
+// Config holds the ledger layout of the service.
+type Config struct {
+	// Ledgers maps each supported ISO 4217 currency code to the ledger that
+	// holds its accounts. Transfers only move money within one ledger.
+	Ledgers    map[string]uint32
+	Settlement SettlementAccounts
+	// LiquidityAccount is the base ID of the per-currency system accounts
+	// that currency exchanges pass through.
+	LiquidityAccount uuid.UUID
+}
+
+// TransferDetailsInput describes a transfer for the people reading it later.
+// ExternalReference is the caller's own identifier for the transfer.
+type TransferDetailsInput struct {
+	Description       string `json:"description" binding:"max=255"`
+	ExternalReference string `json:"external_reference" binding:"max=128"`
+}
+
+// UserDataInput is opaque caller data stored on a ledger transfer, such as
+// the IDs of related records in the caller's systems
+type UserDataInput struct {
+	UserData128 string `json:"user_data_128" binding:"omitempty,uuid"`
+	UserData64  uint64 `json:"user_data_64"`
+	UserData32  uint32 `json:"user_data_32"`
+}
+
+// SettlementTransferInput represents a deposit into or a withdrawal from an
+// account. SettlementAccount names the system account on the other side and
+// defaults to the configured one.
+type SettlementTransferInput struct {
+	AccountID         string      `json:"account_id"`
+	Amount            money.Money `json:"amount"`
+	SettlementAccount string      `json:"settlement_account"`
+	TransferDetailsInput
+}
+
+// TransferInput represents a transfer between two accounts of the same
+// currency. A zero Code uses the configured transfer code.
+type TransferInput struct {
+	FromAccountID string      `json:"from_account_id"`
+	ToAccountID   string      `json:"to_account_id"`
+	Amount        money.Money `json:"amount"`
+	Code          uint16      `json:"code"`
+	TransferDetailsInput
+	UserDataInput
+}
+
+// TransferLegInput represents one posting of an atomic transfer batch
+type TransferLegInput struct {
+	FromAccountID string      `json:"from_account_id"`
+	ToAccountID   string      `json:"to_account_id"`
+	Amount        money.Money `json:"amount"`
+	Code          uint16      `json:"code"`
+	TransferDetailsInput
+	UserDataInput
+}
+
+// ExchangeInput represents a transfer between accounts of different
+// currencies at a rate fixed by the caller: the source amount leaves the
+// debit account and the destination amount arrives in the credit account.
+type ExchangeInput struct {
+	FromAccountID     string      `json:"from_account_id"`
+	ToAccountID       string      `json:"to_account_id"`
+	SourceAmount      money.Money `json:"source_amount"`
+	DestinationAmount money.Money `json:"destination_amount"`
+	TransferDetailsInput
+	UserDataInput
+}
+
+// BatchTransferInput represents an ordered list of legs that are posted
+// together or not at all
+type BatchTransferInput struct {
+	Transfers []TransferLegInput `json:"transfers"`
+}
+
+// PendingTransferInput represents the input for placing a hold
+type PendingTransferInput struct {
+	FromAccountID string      `json:"from_account_id"`
+	ToAccountID   string      `json:"to_account_id"`
+	Amount        money.Money `json:"amount"`
+	Code          uint16      `json:"code"`
+	// TimeoutSeconds releases the hold automatically; zero never expires.
+	TimeoutSeconds uint32 `json:"timeout_seconds"`
+	TransferDetailsInput
+	UserDataInput
+}
+
+// PostPendingTransferInput represents the input for settling a hold.
+// An empty amount posts the full pending amount. Amount and currency stay
+// decimal strings because an amount without a currency is in the currency of
+// the hold, which is only known once the hold is found. Currency is optional
+// and, if given, must be the currency of the hold.
+type PostPendingTransferInput struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ListTransfersInput represents the filters for an account's transaction history.
+// Amount bounds are decimal amounts in the account's currency.
+type ListTransfersInput struct {
+	From      string `form:"from"`      // RFC 3339, inclusive
+	To        string `form:"to"`        // RFC 3339, inclusive
+	Direction string `form:"direction"` // "debit", "credit" or empty for both
+	MinAmount string `form:"min_amount"`
+	MaxAmount string `form:"max_amount"`
+	Order     string `form:"order"` // "desc" (default, newest first) or "asc"
+	Limit     int    `form:"limit"`
+	Cursor    string `form:"cursor"`
+}
+
+// TransferOutput represents a transfer as seen from one of its accounts
+type TransferOutput struct {
+	ID              uuid.UUID   `json:"id"`
+	DebitAccountID  uuid.UUID   `json:"debit_account_id"`
+	CreditAccountID uuid.UUID   `json:"credit_account_id"`
+	Amount          money.Money `json:"amount"`
+	Currency        string      `json:"currency"`
+	// Type is "single_phase", "pending", "post_pending" or "void_pending".
+	Type string `json:"type"`
+	// Kind is "transfer", "deposit", "withdrawal", "opening_deposit" or "exchange".
+	Kind              string     `json:"kind"`
+	Description       string     `json:"description,omitempty"`
+	ExternalReference string     `json:"external_reference,omitempty"`
+	UserData128       *uuid.UUID `json:"user_data_128,omitempty"`
+	UserData64        uint64     `json:"user_data_64,omitempty"`
+	UserData32        uint32     `json:"user_data_32,omitempty"`
+	PendingID         *uuid.UUID `json:"pending_id,omitempty"`
+	Timeout           uint32     `json:"timeout_seconds,omitempty"`
+	// Direction is the side the account was on: "debit" or "credit".
+	Direction string    `json:"direction,omitempty"`
+	Ledger    uint32    `json:"ledger"`
+	Code      uint16    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransferPageOutput represents one page of an account's transaction history.
+// NextCursor is empty on the last page.
+type TransferPageOutput struct {
+	Transfers  []*TransferOutput `json:"transfers"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
 
 type service struct {
-	repo repository.LedgerRepository
+	repo         repository.LedgerRepository
+	accountRepo  repository.AccountRepository
+	detailsRepo  repository.TransferDetailsRepository
+	personRepo   repository.PersonRepository
+	businessRepo repository.BusinessRepository
+	auditor      audit.Recorder
+	config       Config
+	// currencies maps each ledger back to the currency it holds.
+	currencies map[uint32]money.Currency
 }
 
 // NewService creates a new ledger service.
-func NewService(repo repository.LedgerRepository) Service {
+// Accounts are registered in accountRepo against their owners, which are
+// looked up in personRepo and businessRepo. Descriptions and external
+// references of transfers are kept in detailsRepo. New accounts and transfers
+// are recorded with auditor. It fails if config maps a currency that is
+// unknown or maps two currencies to one ledger.
+func NewService(
+	repo repository.LedgerRepository,
+	accountRepo repository.AccountRepository,
+	detailsRepo repository.TransferDetailsRepository,
+	personRepo repository.PersonRepository,
+	businessRepo repository.BusinessRepository,
+	auditor audit.Recorder,
+	config Config,
+) (Service, error) {
+	currencies := make(map[uint32]money.Currency, len(config.Ledgers))
+	for code, ledgerID := range config.Ledgers {
+		currency, err := money.LookupCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		if ledgerID == 0 {
+			return nil, fmt.Errorf("currency %s: ledger must not be zero", code)
+		}
+		if other, ok := currencies[ledgerID]; ok {
+			return nil, fmt.Errorf("currencies %s and %s share ledger %d", other.Code, code, ledgerID)
+		}
+		currencies[ledgerID] = currency
+	}
+	if _, ok := config.Settlement.Accounts[config.Settlement.Default]; !ok {
+		return nil, fmt.Errorf("%w: default %q is not configured", ErrUnknownSettlementAccount, config.Settlement.Default)
+	}
+
 	return &service{
-		repo: repo,
+		repo:         repo,
+		accountRepo:  accountRepo,
+		detailsRepo:  detailsRepo,
+		personRepo:   personRepo,
+		businessRepo: businessRepo,
+		auditor:      auditor,
+		config:       config,
+		currencies:   currencies,
+	}, nil
+}
+
+// CreateSystemAccounts creates the settlement and liquidity accounts of every
+// configured currency. It is safe to call on every start.
+func (s *service) CreateSystemAccounts(ctx context.Context) error {
+	for _, code := range s.currencyCodes() {
+		ledgerID := s.config.Ledgers[code]
+		for name := range s.config.Settlement.Accounts {
+			id, _ := s.settlementAccount(name, code)
+			if err := s.repo.CreateSettlementAccount(ctx, id, ledgerID); err != nil {
+				return fmt.Errorf("creating %s settlement account %q: %w", code, name, err)
+			}
+		}
+		if err := s.repo.CreateLiquidityAccount(ctx, s.liquidityAccount(code), ledgerID); err != nil {
+			return fmt.Errorf("creating %s liquidity account: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// currencyCodes returns the configured currency codes in order
+func (s *service) currencyCodes() []string {
+	codes := make([]string, 0, len(s.config.Ledgers))
+	for code := range s.config.Ledgers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// currency resolves a configured currency code to the currency and its ledger
+func (s *service) currency(code string) (money.Currency, uint32, error) {
+	ledgerID, ok := s.config.Ledgers[code]
+	if !ok {
+		return money.Currency{}, 0, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return s.currencies[ledgerID], ledgerID, nil
+}
+
+// amountLedger checks that amount is positive and in a configured currency,
+// and returns the currency's ledger. Errors name the request fields the
+// amount and currency came from.
+func (s *service) amountLedger(amountField, currencyField string, amount money.Money) (uint32, error) {
+	_, ledgerID, err := s.currency(amount.Currency.Code)
+	if err != nil {
+		return 0, fieldError(currencyField, err)
+	}
+	if amount.Amount <= 0 {
+		return 0, fieldError(amountField, fmt.Errorf("%w: amount must be positive", ErrInvalidAmount))
+	}
+	return ledgerID, nil
+}
+
+// newTransferLeg validates a transfer requested by a caller. Errors name the
+// request fields, prefixed with prefix.
+func (s *service) newTransferLeg(prefix string, from, to string, amount money.Money, code uint16, userData UserDataInput) (repository.TransferLeg, error) {
+	leg := repository.TransferLeg{
+		FromAccountID: from,
+		ToAccountID:   to,
+		Amount:        amount.Amount,
+		Code:          code,
+		UserData64:    userData.UserData64,
+		UserData32:    userData.UserData32,
+	}
+	var err error
+	if leg.Ledger, err = s.amountLedger(prefix+"amount", prefix+"currency", amount); err != nil {
+		return leg, err
+	}
+	if ReservedCodes[code] {
+		return leg, fieldError(prefix+"code", fmt.Errorf("%w: code %d is reserved", ErrInvalidTransferCode, code))
+	}
+	if userData.UserData128 != "" {
+		if leg.UserData128, err = uuid.Parse(userData.UserData128); err != nil {
+			return leg, fieldError(prefix+"user_data_128", fmt.Errorf("%w: must be a UUID", ErrInvalidUserData))
+		}
+	}
+	return leg, nil
+}
+
+// recordTransfers records new transfers in the audit log, each with the
+// request that made it
+func (s *service) recordTransfers(ctx context.Context, action string, transferIDs []string, request interface{}) {
+	for _, id := range transferIDs {
+		s.auditor.Record(ctx, audit.Event{Action: action, EntityType: audit.EntityTransfer, EntityID: id, After: request})
+	}
+}
+
+// saveDetails records the partner, description and external reference of
+// transfers. Every transfer is recorded, so that its partner is known.
+func (s *service) saveDetails(ctx context.Context, transferIDs []string, details TransferDetailsInput) error {
+	for _, id := range transferIDs {
+		err := s.detailsRepo.Save(ctx, &repository.TransferDetailsEntity{
+			TransferID:        uuid.MustParse(id),
+			Description:       details.Description,
+			ExternalReference: details.ExternalReference,
+		})
+		if err != nil {
+			return fmt.Errorf("recording details of transfer %s: %w", id, err)
+		}
 	}
+	return nil
 }
 
-func (s *service) CreateAccount(ctx context.Context, initialBalance int64) (string, error) {
-	return s.repo.CreateAccount(ctx, initialBalance)
+// accountRef is a ledger account named by a request field
+type accountRef struct {
+	field string
+	id    string
 }
 
-func (s *service) TransferFunds(ctx context.Context, fromAccountID, toAccountID string, amount int64) error {
-	return s.repo.Transfer(ctx, fromAccountID, toAccountID, amount)
-}
\ No newline at end of file
+// partnerAccounts returns which of the given ledger accounts are registered to
+// the request's partner
+func (s *service) partnerAccounts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	accounts, err := s.accountRepo.ListByLedgerAccountIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[uuid.UUID]bool, len(accounts))
+	for _, account := range accounts {
+		owned[account.LedgerAccountID] = true
+	}
+	return owned, nil
+}
+
+// requireAccounts checks that the accounts named by a request belong to the
+// request's partner. Accounts of other partners are reported as not found.
+func (s *service) requireAccounts(ctx context.Context, refs ...accountRef) error {
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if id, err := uuid.Parse(ref.id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	owned, err := s.partnerAccounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if id, err := uuid.Parse(ref.id); err != nil || !owned[id] {
+			return fieldError(ref.field, ErrAccountNotFound)
+		}
+	}
+	return nil
+}
+
+// requireAccount checks that a ledger account belongs to the request's partner
+func (s *service) requireAccount(ctx context.Context, id uuid.UUID) error {
+	owned, err := s.partnerAccounts(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+	if !owned[id] {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// requirePendingTransfer checks that a hold was placed by the request's partner
+func (s *service) requirePendingTransfer(ctx context.Context, pendingID uuid.UUID) error {
+	details, err := s.detailsRepo.ListByTransferIDs(ctx, []uuid.UUID{pendingID})
+	if err != nil {
+		return err
+	}
+	if len(details) == 0 {
+		return ErrPendingTransferNotFound
+	}
+	return nil
+}
+
+// CreateAccount opens an account for a KYC-verified person or business.
+// The ledger account is created first and then registered against its owner;
+// a positive initial balance is funded last by an opening deposit from the
+// default settlement account.
+func (s *service) CreateAccount(ctx context.Context, input CreateAccountInput) (*RegisteredAccountOutput, error) {
+	ownerID, err := uuid.Parse(input.OwnerID)
+	if err != nil {
+		return nil, fieldError("owner_id", fmt.Errorf("%w: invalid owner ID", ErrInvalidAccount))
+	}
+	if input.ProductType == "" {
+		input.ProductType = DefaultProductType
+	}
+	if !ProductTypes[input.ProductType] {
+		return nil, fieldError("product_type", fmt.Errorf("%w: unknown product type %q", ErrInvalidAccount, input.ProductType))
+	}
+	if input.Currency == "" {
+		input.Currency = DefaultCurrency
+	}
+	currency, ledgerID, err := s.currency(input.Currency)
+	if err != nil {
+		return nil, fieldError("currency", err)
+	}
+	var initialBalance int64
+	if input.InitialBalance != "" {
+		if initialBalance, err = currency.Parse(input.InitialBalance); err != nil {
+			return nil, fieldError("initial_balance", err)
+		}
+	}
+	settlementID, err := s.settlementAccount("", currency.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkOwner(ctx, input.OwnerType, ownerID); err != nil {
+		if errors.Is(err, ErrOwnerNotFound) {
+			return nil, fieldError("owner_id", err)
+		}
+		return nil, err
+	}
+
+	ledgerAccountID, err := s.repo.CreateAccount(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A retried request finds its ledger account already registered.
+	account, err := s.accountRepo.GetByLedgerAccountID(ctx, uuid.MustParse(ledgerAccountID))
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		account = &repository.AccountEntity{
+			OwnerType:       input.OwnerType,
+			OwnerID:         ownerID,
+			ProductType:     input.ProductType,
+			Currency:        input.Currency,
+			Status:          repository.AccountStatusActive,
+			LedgerAccountID: uuid.MustParse(ledgerAccountID),
+		}
+		if err := s.accountRepo.Create(ctx, account); err != nil {
+			return nil, fmt.Errorf("registering ledger account %s: %w", ledgerAccountID, err)
+		}
+		s.auditor.Record(ctx, audit.Event{Action: "account.create", EntityType: audit.EntityAccount, EntityID: ledgerAccountID, After: account})
+	}
+
+	if initialBalance > 0 {
+		transferID, err := s.repo.OpeningDeposit(ctx, ledgerID, settlementID, ledgerAccountID, initialBalance)
+		if err != nil {
+			return nil, fmt.Errorf("funding opening deposit of account %s: %w", ledgerAccountID, transferError(err, "", ""))
+		}
+		s.recordTransfers(ctx, "opening_deposit.create", []string{transferID}, map[string]interface{}{
+			"account_id":      ledgerAccountID,
+			"initial_balance": input.InitialBalance,
+			"currency":        input.Currency,
+		})
+		if err := s.saveDetails(ctx, []string{transferID}, TransferDetailsInput{}); err != nil {
+			return nil, err
+		}
+	}
+
+	outputs, err := s.registeredAccountsToOutput(ctx, []*repository.AccountEntity{account})
+	if err != nil {
+		return nil, err
+	}
+	return outputs[0], nil
+}
+
+// checkOwner verifies that the owner of a new account exists and has passed KYC
+func (s *service) checkOwner(ctx context.Context, ownerType string, ownerID uuid.UUID) error {
+	kycStatus, err := s.ownerKYCStatus(ctx, ownerType, ownerID)
+	if err != nil {
+		return err
+	}
+	if kycStatus != "verified" {
+		return fmt.Errorf("%w: KYC status is %q", ErrOwnerNotVerified, kycStatus)
+	}
+	return nil
+}
+
+// ownerKYCStatus returns the KYC status of a person or business entity
+func (s *service) ownerKYCStatus(ctx context.Context, ownerType string, ownerID uuid.UUID) (string, error) {
+	switch ownerType {
+	case repository.OwnerTypePerson:
+		person, err := s.personRepo.GetByID(ctx, ownerID)
+		if err != nil {
+			return "", err
+		}
+		if person == nil {
+			return "", ErrOwnerNotFound
+		}
+		return person.KYCStatus, nil
+	case repository.OwnerTypeBusiness:
+		business, err := s.businessRepo.GetByID(ctx, ownerID)
+		if err != nil {
+			return "", err
+		}
+		if business == nil {
+			return "", ErrOwnerNotFound
+		}
+		return business.KYCStatus, nil
+	}
+	return "", fmt.Errorf("%w: owner type must be person or business", ErrInvalidAccount)
+}
+
+// ListOwnerAccounts retrieves the accounts of a person or business entity
+// together with their balances
+func (s *service) ListOwnerAccounts(ctx context.Context, ownerType string, ownerID uuid.UUID) ([]*RegisteredAccountOutput, error) {
+	if _, err := s.ownerKYCStatus(ctx, ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	accounts, err := s.accountRepo.ListByOwner(ctx, ownerType, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.registeredAccountsToOutput(ctx, accounts)
+}
+
+// registeredAccountsToOutput converts registry entries to output, attaching
+// the balances of their ledger accounts
+func (s *service) registeredAccountsToOutput(ctx context.Context, accounts []*repository.AccountEntity) ([]*RegisteredAccountOutput, error) {
+	ids := make([]uuid.UUID, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.LedgerAccountID
+	}
+	ledgerAccounts, err := s.repo.GetAccounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[uuid.UUID]*AccountOutput, len(ledgerAccounts))
+	for _, account := range ledgerAccounts {
+		balances[account.ID] = s.accountToOutput(account)
+	}
+
+	outputs := make([]*RegisteredAccountOutput, len(accounts))
+	for i, account := range accounts {
+		outputs[i] = &RegisteredAccountOutput{
+			ID:              account.ID,
+			LedgerAccountID: account.LedgerAccountID,
+			OwnerType:       account.OwnerType,
+			OwnerID:         account.OwnerID,
+			ProductType:     account.ProductType,
+			Currency:        account.Currency,
+			Status:          account.Status,
+			Balance:         balances[account.LedgerAccountID],
+			CreatedAt:       account.CreatedAt,
+		}
+	}
+	return outputs, nil
+}
+
+// Deposit credits an account with money received into a settlement account
+func (s *service) Deposit(ctx context.Context, input SettlementTransferInput) (string, error) {
+	ledgerID, err := s.amountLedger("amount", "currency", input.Amount)
+	if err != nil {
+		return "", err
+	}
+	settlementID, err := s.settlementAccount(input.SettlementAccount, input.Amount.Currency.Code)
+	if err != nil {
+		return "", fieldError("settlement_account", err)
+	}
+	if err := s.requireAccounts(ctx, accountRef{"account_id", input.AccountID}); err != nil {
+		return "", err
+	}
+	transferID, err := s.repo.Deposit(ctx, ledgerID, settlementID, input.AccountID, input.Amount.Amount)
+	if err != nil {
+		return "", transferError(err, "", "account_id")
+	}
+	s.recordTransfers(ctx, "deposit.create", []string{transferID}, struct {
+		SettlementTransferInput
+		Currency string `json:"currency"`
+	}{input, input.Amount.Currency.Code})
+	return transferID, s.saveDetails(ctx, []string{transferID}, input.TransferDetailsInput)
+}
+
+// Withdraw debits an account for money paid out of a settlement account
+func (s *service) Withdraw(ctx context.Context, input SettlementTransferInput) (string, error) {
+	ledgerID, err := s.amountLedger("amount", "currency", input.Amount)
+	if err != nil {
+		return "", err
+	}
+	settlementID, err := s.settlementAccount(input.SettlementAccount, input.Amount.Currency.Code)
+	if err != nil {
+		return "", fieldError("settlement_account", err)
+	}
+	if err := s.requireAccounts(ctx, accountRef{"account_id", input.AccountID}); err != nil {
+		return "", err
+	}
+	transferID, err := s.repo.Withdraw(ctx, ledgerID, settlementID, input.AccountID, input.Amount.Amount)
+	if err != nil {
+		return "", transferError(err, "account_id", "")
+	}
+	s.recordTransfers(ctx, "withdrawal.create", []string{transferID}, struct {
+		SettlementTransferInput
+		Currency string `json:"currency"`
+	}{input, input.Amount.Currency.Code})
+	return transferID, s.saveDetails(ctx, []string{transferID}, input.TransferDetailsInput)
+}
+
+// settlementAccount resolves the name of a settlement account to its account
+// for a currency; an empty name selects the default one.
+func (s *service) settlementAccount(name, currency string) (uuid.UUID, error) {
+	if name == "" {
+		name = s.config.Settlement.Default
+	}
+	id, ok := s.config.Settlement.Accounts[name]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%w: %q", ErrUnknownSettlementAccount, name)
+	}
+	return uuid.NewSHA1(id, []byte(currency)), nil
+}
+
+// liquidityAccount returns the exchange liquidity account for a currency
+func (s *service) liquidityAccount(currency string) uuid.UUID {
+	return uuid.NewSHA1(s.config.LiquidityAccount, []byte(currency))
+}
+
+// TransferFunds moves money between two accounts of the same currency
+func (s *service) TransferFunds(ctx context.Context, input TransferInput) (string, error) {
+	leg, err := s.newTransferLeg("", input.FromAccountID, input.ToAccountID, input.Amount, input.Code, input.UserDataInput)
+	if err != nil {
+		return "", err
+	}
+	err = s.requireAccounts(ctx, accountRef{"from_account_id", input.FromAccountID}, accountRef{"to_account_id", input.ToAccountID})
+	if err != nil {
+		return "", err
+	}
+	transferID, err := s.repo.Transfer(ctx, leg)
+	if err != nil {
+		return "", transferError(err, "from_account_id", "to_account_id")
+	}
+	s.recordTransfers(ctx, "transfer.create", []string{transferID}, struct {
+		TransferInput
+		Currency string `json:"currency"`
+	}{input, input.Amount.Currency.Code})
+	return transferID, s.saveDetails(ctx, []string{transferID}, input.TransferDetailsInput)
+}
+
+// ExchangeFunds moves money between accounts of different currencies through
+// the liquidity accounts of both currencies, as one atomic batch: the source
+// account pays the source liquidity account, and the destination liquidity
+// account pays the destination account.
+func (s *service) ExchangeFunds(ctx context.Context, input ExchangeInput) ([]string, error) {
+	sourceCurrency, destinationCurrency := input.SourceAmount.Currency.Code, input.DestinationAmount.Currency.Code
+	if sourceCurrency == destinationCurrency {
+		return nil, fmt.Errorf("%w: source and destination currencies must differ", ErrInvalidExchange)
+	}
+	source, err := s.newTransferLeg("source_", input.FromAccountID, s.liquidityAccount(sourceCurrency).String(),
+		input.SourceAmount, 0, input.UserDataInput)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := s.newTransferLeg("destination_", s.liquidityAccount(destinationCurrency).String(), input.ToAccountID,
+		input.DestinationAmount, 0, input.UserDataInput)
+	if err != nil {
+		return nil, err
+	}
+	source.Code, destination.Code = repository.ExchangeCode, repository.ExchangeCode
+	err = s.requireAccounts(ctx, accountRef{"from_account_id", input.FromAccountID}, accountRef{"to_account_id", input.ToAccountID})
+	if err != nil {
+		return nil, err
+	}
+
+	transferIDs, err := s.repo.TransferBatch(ctx, []repository.TransferLeg{source, destination})
+	var chainErr *ledger.ChainError
+	if errors.As(err, &chainErr) {
+		if chainErr.Index == 0 {
+			return nil, transferError(chainErr.Cause, "from_account_id", "")
+		}
+		return nil, transferError(chainErr.Cause, "", "to_account_id")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordTransfers(ctx, "exchange.create", transferIDs, struct {
+		ExchangeInput
+		SourceCurrency      string `json:"source_currency"`
+		DestinationCurrency string `json:"destination_currency"`
+	}{input, sourceCurrency, destinationCurrency})
+	return transferIDs, s.saveDetails(ctx, transferIDs, input.TransferDetailsInput)
+}
+
+// TransferBatch posts every leg of a batch atomically, such as a customer
+// debit together with a merchant credit and a fee credit
+func (s *service) TransferBatch(ctx context.Context, input BatchTransferInput) ([]string, error) {
+	if len(input.Transfers) == 0 {
+		return nil, fmt.Errorf("%w: at least one transfer is required", ErrInvalidBatch)
+	}
+	if len(input.Transfers) > MaxBatchTransfers {
+		return nil, fmt.Errorf("%w: at most %d transfers are allowed", ErrInvalidBatch, MaxBatchTransfers)
+	}
+
+	legs := make([]repository.TransferLeg, len(input.Transfers))
+	refs := make([]accountRef, 0, 2*len(input.Transfers))
+	for i, leg := range input.Transfers {
+		prefix := fmt.Sprintf("transfers[%d].", i)
+		var err error
+		legs[i], err = s.newTransferLeg(prefix, leg.FromAccountID, leg.ToAccountID, leg.Amount, leg.Code, leg.UserDataInput)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, accountRef{prefix + "from_account_id", leg.FromAccountID}, accountRef{prefix + "to_account_id", leg.ToAccountID})
+	}
+	if err := s.requireAccounts(ctx, refs...); err != nil {
+		return nil, err
+	}
+
+	transferIDs, err := s.repo.TransferBatch(ctx, legs)
+	var chainErr *ledger.ChainError
+	if errors.As(err, &chainErr) {
+		rejected := &BatchRejectedError{Index: chainErr.Index, Result: chainErr.Cause.Result.String(), Err: err}
+		var fieldErr *FieldError
+		if errors.As(transferError(chainErr.Cause, "from_account_id", "to_account_id"), &fieldErr) {
+			fieldErr.Field = fmt.Sprintf("transfers[%d].%s", chainErr.Index, fieldErr.Field)
+			rejected.Field = fieldErr
+		}
+		return nil, rejected
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, leg := range input.Transfers {
+		s.recordTransfers(ctx, "batch_transfer.create", transferIDs[i:i+1], struct {
+			TransferLegInput
+			Currency string `json:"currency"`
+		}{leg, leg.Amount.Currency.Code})
+		if err := s.saveDetails(ctx, transferIDs[i:i+1], leg.TransferDetailsInput); err != nil {
+			return nil, err
+		}
+	}
+	return transferIDs, nil
+}
+
+// CreatePendingTransfer places a hold that reduces the available balance of
+// the debit account until it is posted, voided or expires
+func (s *service) CreatePendingTransfer(ctx context.Context, input PendingTransferInput) (string, error) {
+	leg, err := s.newTransferLeg("", input.FromAccountID, input.ToAccountID, input.Amount, input.Code, input.UserDataInput)
+	if err != nil {
+		return "", err
+	}
+	err = s.requireAccounts(ctx, accountRef{"from_account_id", input.FromAccountID}, accountRef{"to_account_id", input.ToAccountID})
+	if err != nil {
+		return "", err
+	}
+	transferID, err := s.repo.CreatePendingTransfer(ctx, leg, input.TimeoutSeconds)
+	if err != nil {
+		return "", transferError(err, "from_account_id", "to_account_id")
+	}
+	s.recordTransfers(ctx, "pending_transfer.create", []string{transferID}, struct {
+		PendingTransferInput
+		Currency string `json:"currency"`
+	}{input, input.Amount.Currency.Code})
+	return transferID, s.saveDetails(ctx, []string{transferID}, input.TransferDetailsInput)
+}
+
+// PostPendingTransfer settles part of a hold, in the currency of the hold;
+// an empty amount settles the full amount
+func (s *service) PostPendingTransfer(ctx context.Context, pendingID uuid.UUID, input PostPendingTransferInput) (string, error) {
+	if err := s.requirePendingTransfer(ctx, pendingID); err != nil {
+		return "", err
+	}
+	var amount int64
+	if input.Amount != "" || input.Currency != "" {
+		pending, err := s.repo.GetTransfer(ctx, pendingID)
+		if err != nil {
+			return "", err
+		}
+		if pending == nil {
+			return "", ErrPendingTransferNotFound
+		}
+		currency := s.currencies[pending.Ledger]
+		if input.Currency != "" && input.Currency != currency.Code {
+			return "", fieldError("currency", fmt.Errorf("%w: pending transfer is in %s", ErrCurrencyMismatch, currency.Code))
+		}
+		if input.Amount != "" {
+			if amount, err = currency.Parse(input.Amount); err != nil {
+				return "", fieldError("amount", err)
+			}
+			if amount == 0 {
+				return "", fieldError("amount", fmt.Errorf("%w: amount must be positive", ErrInvalidAmount))
+			}
+		}
+	}
+	transferID, err := s.repo.PostPendingTransfer(ctx, pendingID, amount)
+	if err != nil {
+		return "", pendingTransferError(err)
+	}
+	s.recordTransfers(ctx, "pending_transfer.post", []string{transferID}, map[string]interface{}{
+		"pending_id": pendingID,
+		"amount":     input.Amount,
+		"currency":   input.Currency,
+	})
+	return transferID, s.saveDetails(ctx, []string{transferID}, TransferDetailsInput{})
+}
+
+// VoidPendingTransfer releases a hold without moving any funds
+func (s *service) VoidPendingTransfer(ctx context.Context, pendingID uuid.UUID) (string, error) {
+	if err := s.requirePendingTransfer(ctx, pendingID); err != nil {
+		return "", err
+	}
+	transferID, err := s.repo.VoidPendingTransfer(ctx, pendingID)
+	if err != nil {
+		return "", pendingTransferError(err)
+	}
+	s.recordTransfers(ctx, "pending_transfer.void", []string{transferID}, map[string]interface{}{"pending_id": pendingID})
+	return transferID, s.saveDetails(ctx, []string{transferID}, TransferDetailsInput{})
+}
+
+// transferError translates the ledger results of a transfer between accounts
+// into service errors. A missing account is attributed to debitField or
+// creditField, the request fields that name the debit and credit accounts;
+// an empty field means the account was not chosen by the request.
+func transferError(err error, debitField, creditField string) error {
+	var transferErr *ledger.TransferError
+	if !errors.As(err, &transferErr) {
+		return err
+	}
+	switch transferErr.Result {
+	case ledger.TransferDebitAccountNotFound:
+		if debitField != "" {
+			return fieldError(debitField, ErrAccountNotFound)
+		}
+		return fmt.Errorf("%w: %v", ErrAccountNotFound, err)
+	case ledger.TransferCreditAccountNotFound:
+		if creditField != "" {
+			return fieldError(creditField, ErrAccountNotFound)
+		}
+		return fmt.Errorf("%w: %v", ErrAccountNotFound, err)
+	case ledger.TransferAccountsMustBeDifferent:
+		if creditField != "" {
+			return fieldError(creditField, ErrSameAccount)
+		}
+		return fmt.Errorf("%w: %v", ErrSameAccount, err)
+	case ledger.TransferExceedsCredits, ledger.TransferExceedsDebits:
+		return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+	case ledger.TransferAccountsMustHaveTheSameLedger, ledger.TransferTransferMustHaveTheSameLedgerAsAccounts:
+		return fmt.Errorf("%w: accounts must hold the currency of the transfer", ErrCurrencyMismatch)
+	}
+	return err
+}
+
+// pendingTransferError translates the ledger results of a post or void into
+// service errors.
+func pendingTransferError(err error) error {
+	var transferErr *ledger.TransferError
+	if !errors.As(err, &transferErr) {
+		return err
+	}
+	switch transferErr.Result {
+	case ledger.TransferPendingTransferNotFound, ledger.TransferPendingTransferNotPending:
+		return fmt.Errorf("%w: %v", ErrPendingTransferNotFound, err)
+	case ledger.TransferPendingTransferAlreadyPosted,
+		ledger.TransferPendingTransferAlreadyVoided,
+		ledger.TransferPendingTransferExpired:
+		return fmt.Errorf("%w: %v", ErrPendingTransferClosed, err)
+	case ledger.TransferExceedsPendingTransferAmount:
+		return fmt.Errorf("%w: %v", ErrExceedsPendingAmount, err)
+	}
+	return err
+}
+
+// GetAccount retrieves a ledger account of the request's partner and its
+// balances by ID
+func (s *service) GetAccount(ctx context.Context, id uuid.UUID) (*AccountOutput, error) {
+	if err := s.requireAccount(ctx, id); err != nil {
+		return nil, err
+	}
+	account, err := s.repo.GetAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+	return s.accountToOutput(account), nil
+}
+
+// GetAccounts retrieves several ledger accounts at once. Accounts that do not
+// exist or belong to another partner are omitted from the result.
+func (s *service) GetAccounts(ctx context.Context, ids []uuid.UUID) ([]*AccountOutput, error) {
+	if len(ids) > MaxLookupAccounts {
+		return nil, ErrTooManyAccounts
+	}
+	owned, err := s.partnerAccounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	partnerIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if owned[id] {
+			partnerIDs = append(partnerIDs, id)
+		}
+	}
+	accounts, err := s.repo.GetAccounts(ctx, partnerIDs)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*AccountOutput, len(accounts))
+	for i, account := range accounts {
+		outputs[i] = s.accountToOutput(account)
+	}
+	return outputs, nil
+}
+
+// ListTransfers retrieves a page of the transfers that touched an account
+func (s *service) ListTransfers(ctx context.Context, accountID uuid.UUID, input ListTransfersInput) (*TransferPageOutput, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	account, err := s.repo.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	filter, err := s.transferFilter(accountID, s.currencies[account.Ledger], input)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, hasMore, err := s.repo.ListTransfers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TransferPageOutput{Transfers: make([]*TransferOutput, len(transfers))}
+	for i := range transfers {
+		page.Transfers[i] = s.transferToOutput(&transfers[i], accountID)
+	}
+	if err := s.attachDetails(ctx, page.Transfers); err != nil {
+		return nil, err
+	}
+	if hasMore {
+		page.NextCursor = encodeCursor(filter.Reversed, transfers[len(transfers)-1].Timestamp)
+	}
+	return page, nil
+}
+
+// attachDetails adds the saved descriptions and external references to transfers
+func (s *service) attachDetails(ctx context.Context, outputs []*TransferOutput) error {
+	ids := make([]uuid.UUID, len(outputs))
+	for i, output := range outputs {
+		ids[i] = output.ID
+	}
+	details, err := s.detailsRepo.ListByTransferIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]*repository.TransferDetailsEntity, len(details))
+	for _, d := range details {
+		byID[d.TransferID] = d
+	}
+	for _, output := range outputs {
+		if d, ok := byID[output.ID]; ok {
+			output.Description = d.Description
+			output.ExternalReference = d.ExternalReference
+		}
+	}
+	return nil
+}
+
+// transferFilter validates the history query and resolves the cursor into
+// timestamp bounds for the repository. Amount bounds are in currency.
+func (s *service) transferFilter(accountID uuid.UUID, currency money.Currency, input ListTransfersInput) (repository.TransferFilter, error) {
+	filter := repository.TransferFilter{
+		AccountID: accountID,
+		Limit:     input.Limit,
+	}
+	if input.MinAmount != "" {
+		minAmount, err := currency.Parse(input.MinAmount)
+		if err != nil {
+			return filter, fmt.Errorf("%w: min_amount: %v", ErrInvalidTransferFilter, err)
+		}
+		filter.MinAmount = uint64(minAmount)
+	}
+	if input.MaxAmount != "" {
+		maxAmount, err := currency.Parse(input.MaxAmount)
+		if err != nil {
+			return filter, fmt.Errorf("%w: max_amount: %v", ErrInvalidTransferFilter, err)
+		}
+		filter.MaxAmount = uint64(maxAmount)
+	}
+
+	// Apply sensible defaults
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	switch input.Direction {
+	case "":
+		filter.Debits, filter.Credits = true, true
+	case "debit":
+		filter.Debits = true
+	case "credit":
+		filter.Credits = true
+	default:
+		return filter, fmt.Errorf("%w: direction must be debit or credit", ErrInvalidTransferFilter)
+	}
+
+	switch input.Order {
+	case "", "desc":
+		filter.Reversed = true
+	case "asc":
+	default:
+		return filter, fmt.Errorf("%w: order must be asc or desc", ErrInvalidTransferFilter)
+	}
+
+	if filter.MaxAmount != 0 && filter.MinAmount > filter.MaxAmount {
+		return filter, fmt.Errorf("%w: min_amount must not exceed max_amount", ErrInvalidTransferFilter)
+	}
+
+	if input.From != "" {
+		from, err := time.Parse(time.RFC3339, input.From)
+		if err != nil {
+			return filter, fmt.Errorf("%w: from must be an RFC 3339 timestamp", ErrInvalidTransferFilter)
+		}
+		filter.TimestampMin = uint64(from.UnixNano())
+	}
+	if input.To != "" {
+		to, err := time.Parse(time.RFC3339, input.To)
+		if err != nil {
+			return filter, fmt.Errorf("%w: to must be an RFC 3339 timestamp", ErrInvalidTransferFilter)
+		}
+		filter.TimestampMax = uint64(to.UnixNano())
+	}
+	if filter.TimestampMax != 0 && filter.TimestampMin > filter.TimestampMax {
+		return filter, fmt.Errorf("%w: from must not be after to", ErrInvalidTransferFilter)
+	}
+
+	// The cursor narrows the time range to the transfers after the last one
+	// already returned, in the direction of the listing.
+	if input.Cursor != "" {
+		reversed, timestamp, err := decodeCursor(input.Cursor)
+		if err != nil || reversed != filter.Reversed {
+			return filter, ErrInvalidCursor
+		}
+		if filter.Reversed {
+			if filter.TimestampMax == 0 || timestamp-1 < filter.TimestampMax {
+				filter.TimestampMax = timestamp - 1
+			}
+		} else if timestamp+1 > filter.TimestampMin {
+			filter.TimestampMin = timestamp + 1
+		}
+	}
+
+	return filter, nil
+}
+
+// encodeCursor builds the opaque cursor pointing after the transfer at timestamp
+func encodeCursor(reversed bool, timestamp uint64) string {
+	order := "asc"
+	if reversed {
+		order = "desc"
+	}
+	raw := order + ":" + strconv.FormatUint(timestamp, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (bool, uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, 0, err
+	}
+	order, timestampStr, ok := strings.Cut(string(raw), ":")
+	if !ok || (order != "asc" && order != "desc") {
+		return false, 0, ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseUint(timestampStr, 10, 64)
+	if err != nil || timestamp == 0 {
+		return false, 0, ErrInvalidCursor
+	}
+	return order == "desc", timestamp, nil
+}
+
+// Helper function to convert a ledger account to output.
+// Amounts on a ledger with no configured currency are shown in minor units.
+func (s *service) accountToOutput(account *ledger.Account) *AccountOutput {
+	currency := s.currencies[account.Ledger]
+	return &AccountOutput{
+		ID:               account.ID,
+		Ledger:           account.Ledger,
+		Currency:         currency.Code,
+		Code:             account.Code,
+		NormalBalance:    account.NormalBalance(),
+		DebitsPending:    currency.FormatUint(account.DebitsPending),
+		DebitsPosted:     currency.FormatUint(account.DebitsPosted),
+		CreditsPending:   currency.FormatUint(account.CreditsPending),
+		CreditsPosted:    currency.FormatUint(account.CreditsPosted),
+		PostedBalance:    money.Money{Amount: account.PostedBalance(), Currency: currency},
+		PendingBalance:   money.Money{Amount: account.PendingBalance(), Currency: currency},
+		AvailableBalance: money.Money{Amount: account.AvailableBalance(), Currency: currency},
+		CreatedAt:        time.Unix(0, int64(account.Timestamp)).UTC(),
+	}
+}
+
+// Helper function to convert a transfer to output, as seen from accountID
+func (s *service) transferToOutput(transfer *ledger.Transfer, accountID uuid.UUID) *TransferOutput {
+	currency := s.currencies[transfer.Ledger]
+	output := &TransferOutput{
+		ID:              transfer.ID,
+		DebitAccountID:  transfer.DebitAccountID,
+		CreditAccountID: transfer.CreditAccountID,
+		Amount:          money.Money{Amount: int64(transfer.Amount), Currency: currency},
+		Currency:        currency.Code,
+		Type:            transferType(transfer.Flags),
+		Kind:            transferKind(transfer.Code),
+		UserData64:      transfer.UserData64,
+		UserData32:      transfer.UserData32,
+		Timeout:         transfer.Timeout,
+		Ledger:          transfer.Ledger,
+		Code:            transfer.Code,
+		CreatedAt:       time.Unix(0, int64(transfer.Timestamp)).UTC(),
+	}
+	if transfer.UserData128 != uuid.Nil {
+		output.UserData128 = &transfer.UserData128
+	}
+	if transfer.PendingID != uuid.Nil {
+		output.PendingID = &transfer.PendingID
+	}
+	switch accountID {
+	case transfer.DebitAccountID:
+		output.Direction = "debit"
+	case transfer.CreditAccountID:
+		output.Direction = "credit"
+	}
+	return output
+}
+
+func transferType(flags ledger.TransferFlags) string {
+	switch {
+	case flags.Pending:
+		return "pending"
+	case flags.PostPendingTransfer:
+		return "post_pending"
+	case flags.VoidPendingTransfer:
+		return "void_pending"
+	}
+	return "single_phase"
+}
+
+func transferKind(code uint16) string {
+	switch code {
+	case repository.DepositCode:
+		return "deposit"
+	case repository.WithdrawalCode:
+		return "withdrawal"
+	case repository.OpeningDepositCode:
+		return "opening_deposit"
+	case repository.ExchangeCode:
+		return "exchange"
+	}
+	return "transfer"
+}
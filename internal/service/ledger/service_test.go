@@ -0,0 +1,345 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/ledger/memory"
+	"github.com/Cassandra-Labs-Foundation/core/internal/money"
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	"github.com/google/uuid"
+)
+
+// fakeAccounts is an account registry that holds every account it is given
+type fakeAccounts struct {
+	repository.AccountRepository
+	accounts []*repository.AccountEntity
+}
+
+func (r *fakeAccounts) Create(ctx context.Context, account *repository.AccountEntity) error {
+	account.ID = uuid.New()
+	r.accounts = append(r.accounts, account)
+	return nil
+}
+
+func (r *fakeAccounts) GetByLedgerAccountID(ctx context.Context, ledgerAccountID uuid.UUID) (*repository.AccountEntity, error) {
+	for _, account := range r.accounts {
+		if account.LedgerAccountID == ledgerAccountID {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAccounts) ListByLedgerAccountIDs(ctx context.Context, ledgerAccountIDs []uuid.UUID) ([]*repository.AccountEntity, error) {
+	var accounts []*repository.AccountEntity
+	for _, id := range ledgerAccountIDs {
+		if account, _ := r.GetByLedgerAccountID(ctx, id); account != nil {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+// fakeDetails keeps transfer details in memory
+type fakeDetails struct {
+	details map[uuid.UUID]*repository.TransferDetailsEntity
+}
+
+func (r *fakeDetails) Save(ctx context.Context, details *repository.TransferDetailsEntity) error {
+	r.details[details.TransferID] = details
+	return nil
+}
+
+func (r *fakeDetails) ListByTransferIDs(ctx context.Context, ids []uuid.UUID) ([]*repository.TransferDetailsEntity, error) {
+	var details []*repository.TransferDetailsEntity
+	for _, id := range ids {
+		if d, ok := r.details[id]; ok {
+			details = append(details, d)
+		}
+	}
+	return details, nil
+}
+
+// verifiedPersons finds a KYC-verified person for every ID
+type verifiedPersons struct {
+	repository.PersonRepository
+}
+
+func (verifiedPersons) GetByID(ctx context.Context, id uuid.UUID) (*repository.PersonEntity, error) {
+	return &repository.PersonEntity{ID: id, KYCStatus: "verified"}, nil
+}
+
+type nopAuditor struct{}
+
+func (nopAuditor) Record(ctx context.Context, event audit.Event)      {}
+func (nopAuditor) Write(ctx context.Context, event audit.Event) error { return nil }
+
+// newTestService returns a service on an empty in-memory ledger with USD on
+// ledger 1
+func newTestService(t *testing.T) Service {
+	t.Helper()
+	svc, err := NewService(
+		repository.NewLedgerRepository(memory.NewLedger(), 1),
+		&fakeAccounts{},
+		&fakeDetails{details: make(map[uuid.UUID]*repository.TransferDetailsEntity)},
+		verifiedPersons{},
+		nil,
+		nopAuditor{},
+		Config{
+			Ledgers: map[string]uint32{"USD": 1},
+			Settlement: SettlementAccounts{
+				Accounts: map[string]uuid.UUID{"cash": uuid.New()},
+				Default:  "cash",
+			},
+			LiquidityAccount: uuid.New(),
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	if err := svc.CreateSystemAccounts(context.Background()); err != nil {
+		t.Fatalf("CreateSystemAccounts: %v", err)
+	}
+	return svc
+}
+
+// openAccount opens a USD account funded with balance and returns its ledger
+// account ID
+func openAccount(t *testing.T, svc Service, balance string) string {
+	t.Helper()
+	account, err := svc.CreateAccount(context.Background(), CreateAccountInput{
+		OwnerType:      repository.OwnerTypePerson,
+		OwnerID:        uuid.NewString(),
+		InitialBalance: balance,
+	})
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	return account.LedgerAccountID.String()
+}
+
+// checkBalances checks the posted and available balances of accounts
+func checkBalances(t *testing.T, svc Service, ids []string, posted, available []string) {
+	t.Helper()
+	for i, id := range ids {
+		account, err := svc.GetAccount(context.Background(), uuid.MustParse(id))
+		if err != nil {
+			t.Fatalf("GetAccount: %v", err)
+		}
+		if account.PostedBalance.Decimal() != posted[i] || account.AvailableBalance.Decimal() != available[i] {
+			t.Errorf("account %d: posted %s, available %s; want %s, %s",
+				i, account.PostedBalance.Decimal(), account.AvailableBalance.Decimal(), posted[i], available[i])
+		}
+	}
+}
+
+// usd returns a USD amount
+func usd(t *testing.T, amount string) money.Money {
+	t.Helper()
+	m, err := money.Parse(amount, "USD")
+	if err != nil {
+		t.Fatalf("money.Parse: %v", err)
+	}
+	return m
+}
+
+// testLeg moves amount between accounts by index; -1 is an account that
+// isn't registered
+type testLeg struct {
+	from, to int
+	amount   string
+}
+
+func TestTransferBatch(t *testing.T) {
+	unregistered := uuid.NewString()
+	tests := []struct {
+		name      string
+		legs      []testLeg
+		wantIndex int
+		wantField string
+		want      []string
+	}{
+		{
+			name:      "every leg is posted",
+			legs:      []testLeg{{0, 1, "30.00"}, {1, 2, "10.00"}},
+			wantIndex: -1,
+			want:      []string{"70.00", "20.00", "10.00"},
+		},
+		{
+			name:      "an overdraft rolls back the earlier legs",
+			legs:      []testLeg{{0, 1, "30.00"}, {1, 2, "10.00"}, {2, 0, "50.00"}},
+			wantIndex: 2,
+			want:      []string{"100.00", "0.00", "0.00"},
+		},
+		{
+			name:      "an unregistered account is reported by field",
+			legs:      []testLeg{{0, 1, "30.00"}, {1, -1, "10.00"}},
+			wantIndex: -1,
+			wantField: "transfers[1].to_account_id",
+			want:      []string{"100.00", "0.00", "0.00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+			accounts := []string{openAccount(t, svc, "100.00"), openAccount(t, svc, ""), openAccount(t, svc, "")}
+			account := func(i int) string {
+				if i < 0 {
+					return unregistered
+				}
+				return accounts[i]
+			}
+			var input BatchTransferInput
+			for _, leg := range tt.legs {
+				input.Transfers = append(input.Transfers, TransferLegInput{
+					FromAccountID: account(leg.from),
+					ToAccountID:   account(leg.to),
+					Amount:        usd(t, leg.amount),
+				})
+			}
+
+			ids, err := svc.TransferBatch(context.Background(), input)
+			var rejected *BatchRejectedError
+			var fieldErr *FieldError
+			switch {
+			case tt.wantIndex >= 0:
+				if !errors.As(err, &rejected) || rejected.Index != tt.wantIndex {
+					t.Fatalf("TransferBatch error = %v, want rejection at leg %d", err, tt.wantIndex)
+				}
+			case tt.wantField != "":
+				if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField || !errors.Is(err, ErrAccountNotFound) {
+					t.Fatalf("TransferBatch error = %v, want %s not found", err, tt.wantField)
+				}
+			case err != nil:
+				t.Fatalf("TransferBatch: %v", err)
+			case len(ids) != len(tt.legs):
+				t.Fatalf("TransferBatch returned %d IDs, want %d", len(ids), len(tt.legs))
+			}
+			checkBalances(t, svc, accounts, tt.want, tt.want)
+		})
+	}
+}
+
+func TestPendingTransfer(t *testing.T) {
+	tests := []struct {
+		name string
+		// resolve posts or voids the hold of 40.00 from account 0 to 1
+		resolve func(svc Service, pendingID uuid.UUID) error
+		wantErr error
+		// posted and available balances of accounts 0 and 1
+		posted, available []string
+	}{
+		{
+			name:      "held",
+			resolve:   func(svc Service, pendingID uuid.UUID) error { return nil },
+			posted:    []string{"100.00", "0.00"},
+			available: []string{"60.00", "0.00"},
+		},
+		{
+			name: "posted in full",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				_, err := svc.PostPendingTransfer(context.Background(), pendingID, PostPendingTransferInput{})
+				return err
+			},
+			posted:    []string{"60.00", "40.00"},
+			available: []string{"60.00", "40.00"},
+		},
+		{
+			name: "posted in part",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				_, err := svc.PostPendingTransfer(context.Background(), pendingID, PostPendingTransferInput{Amount: "15.00"})
+				return err
+			},
+			posted:    []string{"85.00", "15.00"},
+			available: []string{"85.00", "15.00"},
+		},
+		{
+			name: "posted beyond the hold",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				_, err := svc.PostPendingTransfer(context.Background(), pendingID, PostPendingTransferInput{Amount: "40.01"})
+				return err
+			},
+			wantErr:   ErrExceedsPendingAmount,
+			posted:    []string{"100.00", "0.00"},
+			available: []string{"60.00", "0.00"},
+		},
+		{
+			name: "voided",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				_, err := svc.VoidPendingTransfer(context.Background(), pendingID)
+				return err
+			},
+			posted:    []string{"100.00", "0.00"},
+			available: []string{"100.00", "0.00"},
+		},
+		{
+			name: "posted after it was voided",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				if _, err := svc.VoidPendingTransfer(context.Background(), pendingID); err != nil {
+					return err
+				}
+				_, err := svc.PostPendingTransfer(context.Background(), pendingID, PostPendingTransferInput{})
+				return err
+			},
+			wantErr:   ErrPendingTransferClosed,
+			posted:    []string{"100.00", "0.00"},
+			available: []string{"100.00", "0.00"},
+		},
+		{
+			name: "unknown hold",
+			resolve: func(svc Service, pendingID uuid.UUID) error {
+				_, err := svc.VoidPendingTransfer(context.Background(), uuid.New())
+				return err
+			},
+			wantErr:   ErrPendingTransferNotFound,
+			posted:    []string{"100.00", "0.00"},
+			available: []string{"60.00", "0.00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+			accounts := []string{openAccount(t, svc, "100.00"), openAccount(t, svc, "")}
+			pendingID, err := svc.CreatePendingTransfer(context.Background(), PendingTransferInput{
+				FromAccountID: accounts[0],
+				ToAccountID:   accounts[1],
+				Amount:        usd(t, "40.00"),
+			})
+			if err != nil {
+				t.Fatalf("CreatePendingTransfer: %v", err)
+			}
+
+			if err := tt.resolve(svc, uuid.MustParse(pendingID)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			checkBalances(t, svc, accounts, tt.posted, tt.available)
+		})
+	}
+}
+
+func TestPendingTransferExpiry(t *testing.T) {
+	svc := newTestService(t)
+	accounts := []string{openAccount(t, svc, "100.00"), openAccount(t, svc, "")}
+	pendingID, err := svc.CreatePendingTransfer(context.Background(), PendingTransferInput{
+		FromAccountID:  accounts[0],
+		ToAccountID:    accounts[1],
+		Amount:         usd(t, "40.00"),
+		TimeoutSeconds: 1,
+	})
+	if err != nil {
+		t.Fatalf("CreatePendingTransfer: %v", err)
+	}
+	checkBalances(t, svc, accounts, []string{"100.00", "0.00"}, []string{"60.00", "0.00"})
+
+	// Timeouts are whole seconds, so the hold can't expire any sooner
+	time.Sleep(1100 * time.Millisecond)
+	checkBalances(t, svc, accounts, []string{"100.00", "0.00"}, []string{"100.00", "0.00"})
+	_, err = svc.PostPendingTransfer(context.Background(), uuid.MustParse(pendingID), PostPendingTransferInput{})
+	if !errors.Is(err, ErrPendingTransferClosed) {
+		t.Fatalf("PostPendingTransfer after expiry: error = %v, want %v", err, ErrPendingTransferClosed)
+	}
+}
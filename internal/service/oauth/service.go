@@ -0,0 +1,315 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/apikey"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/auth"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/jwt"
+	"github.com/google/uuid"
+)
+
+// ClientIDPrefix starts every client ID and ClientSecretPrefix every client
+// secret, so that they can be told apart and found by secret scanners.
+const (
+	ClientIDPrefix     = "cbc_"
+	ClientSecretPrefix = "cbs_"
+)
+
+// ClientRole is the role of tokens issued to OAuth clients, the same as that
+// of partner API keys
+const ClientRole = "partner"
+
+var (
+	ErrClientNotFound = errors.New("OAuth client not found")
+	ErrClientRevoked  = errors.New("OAuth client is revoked")
+	ErrInvalidInput   = errors.New("invalid OAuth client data")
+	ErrInvalidClient  = errors.New("invalid client credentials")
+	ErrInvalidScope   = errors.New("invalid scope")
+)
+
+// Service registers the OAuth clients of fintech partners and issues them
+// tokens with the client credentials grant of RFC 6749
+type Service interface {
+	Register(ctx context.Context, input RegisterClientInput) (*RegisteredClientOutput, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*ClientOutput, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	IssueToken(ctx context.Context, clientID, clientSecret, scope string) (*TokenOutput, error)
+	Introspect(ctx context.Context, clientID, clientSecret, token string) (*IntrospectionOutput, error)
+}
+
+// RegisterClientInput represents the input for registering an OAuth client.
+// Scopes are the most the client can ask for.
+type RegisterClientInput struct {
+	PartnerID string   `json:"partner_id" binding:"required,uuid"`
+	Name      string   `json:"name" binding:"required,max=100"`
+	Scopes    []string `json:"scopes" binding:"required,min=1"`
+}
+
+// ClientOutput represents an OAuth client without its secret
+type ClientOutput struct {
+	ID        uuid.UUID  `json:"id"`
+	ClientID  string     `json:"client_id"`
+	PartnerID uuid.UUID  `json:"partner_id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RegisteredClientOutput represents a newly registered OAuth client.
+// ClientSecret is never shown again.
+type RegisteredClientOutput struct {
+	ClientOutput
+	ClientSecret string `json:"client_secret"`
+}
+
+// TokenOutput is the successful access token response of RFC 6749 section 5.1
+type TokenOutput struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// IntrospectionOutput is the introspection response of RFC 7662 section 2.2.
+// Inactive tokens only have Active set.
+type IntrospectionOutput struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Role      string `json:"role,omitempty"`
+	PartnerID string `json:"partner_id,omitempty"`
+}
+
+type service struct {
+	repo        repository.OAuthClientRepository
+	authService auth.Service
+	jwtService  jwt.Service
+}
+
+// NewService creates a new OAuth service. Tokens are validated by
+// authService, so that revoked tokens are reported inactive.
+func NewService(repo repository.OAuthClientRepository, authService auth.Service, jwtService jwt.Service) Service {
+	return &service{
+		repo:        repo,
+		authService: authService,
+		jwtService:  jwtService,
+	}
+}
+
+// Register creates an OAuth client for a partner
+func (s *service) Register(ctx context.Context, input RegisterClientInput) (*RegisteredClientOutput, error) {
+	partnerID, err := uuid.Parse(input.PartnerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid partner ID", ErrInvalidInput)
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	clientID, secret, err := generateCredentials()
+	if err != nil {
+		return nil, err
+	}
+	entity := &repository.OAuthClientEntity{
+		ClientID:   clientID,
+		PartnerID:  partnerID,
+		Name:       input.Name,
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return nil, err
+	}
+	return &RegisteredClientOutput{ClientOutput: *toOutput(entity), ClientSecret: secret}, nil
+}
+
+// List retrieves OAuth clients, optionally only those of one partner
+func (s *service) List(ctx context.Context, partnerID *uuid.UUID) ([]*ClientOutput, error) {
+	clients, err := s.repo.List(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*ClientOutput, len(clients))
+	for i, client := range clients {
+		outputs[i] = toOutput(client)
+	}
+	return outputs, nil
+}
+
+// Revoke stops an OAuth client from getting new tokens. Tokens it already
+// has stay valid until they expire.
+func (s *service) Revoke(ctx context.Context, id uuid.UUID) error {
+	client, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return ErrClientNotFound
+	}
+	if client.RevokedAt != nil {
+		return ErrClientRevoked
+	}
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+// IssueToken issues a token to an authenticated client for the requested
+// space-separated scopes, or for all of its scopes if none are requested
+func (s *service) IssueToken(ctx context.Context, clientID, clientSecret, scope string) (*TokenOutput, error) {
+	client, err := s.authenticate(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		scopes, err = normalizeScopes(requested)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidScope, err)
+		}
+		allowed := make(map[string]bool, len(client.Scopes))
+		for _, scope := range client.Scopes {
+			allowed[scope] = true
+		}
+		for _, scope := range scopes {
+			if !allowed[scope] {
+				return nil, fmt.Errorf("%w: client may not request %q", ErrInvalidScope, scope)
+			}
+		}
+	}
+
+	token, err := s.jwtService.GenerateClientToken(client.ClientID, ClientRole, client.PartnerID.String(), scopes)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenOutput{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.jwtService.Lifetime().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect reports whether a token is active to an authenticated client.
+// Clients only learn about tokens of their own partner; any other token is
+// reported inactive.
+func (s *service) Introspect(ctx context.Context, clientID, clientSecret, token string) (*IntrospectionOutput, error) {
+	client, err := s.authenticate(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.authService.ValidateToken(ctx, token)
+	if err != nil || claims.PartnerID != client.PartnerID.String() {
+		return &IntrospectionOutput{Active: false}, nil
+	}
+
+	output := &IntrospectionOutput{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       claims.UserID,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Role:      claims.Role,
+		PartnerID: claims.PartnerID,
+	}
+	if claims.ExpiresAt != nil {
+		output.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		output.Iat = claims.IssuedAt.Unix()
+	}
+	return output, nil
+}
+
+// authenticate returns the active client clientSecret is the secret of
+func (s *service) authenticate(ctx context.Context, clientID, clientSecret string) (*repository.OAuthClientEntity, error) {
+	if !strings.HasPrefix(clientID, ClientIDPrefix) || clientSecret == "" {
+		return nil, ErrInvalidClient
+	}
+	client, err := s.repo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || subtle.ConstantTimeCompare([]byte(hashSecret(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	if client.RevokedAt != nil {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// generateCredentials returns a new client ID and secret
+func generateCredentials() (string, string, error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	return ClientIDPrefix + hex.EncodeToString(idBytes), ClientSecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// hashSecret hashes a client secret for storage. Secrets are long and random,
+// so a single round of SHA-256 is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates scopes against those of API keys and removes
+// duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !apikey.Scopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func toOutput(client *repository.OAuthClientEntity) *ClientOutput {
+	return &ClientOutput{
+		ID:        client.ID,
+		ClientID:  client.ClientID,
+		PartnerID: client.PartnerID,
+		Name:      client.Name,
+		Scopes:    client.Scopes,
+		RevokedAt: client.RevokedAt,
+		CreatedAt: client.CreatedAt,
+	}
+}
@@ -3,9 +3,13 @@ package person
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/internal/service/audit"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/redact"
 	"github.com/google/uuid"
 )
 
@@ -18,8 +22,10 @@ var (
 type Service interface {
 	Create(ctx context.Context, input CreatePersonInput) (*PersonOutput, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*PersonOutput, error)
+	RevealSSN(ctx context.Context, id uuid.UUID) (*PersonOutput, error)
 	Update(ctx context.Context, id uuid.UUID, input UpdatePersonInput) (*PersonOutput, error)
 	List(ctx context.Context, limit, offset int) ([]*PersonOutput, error)
+	FindBySSN(ctx context.Context, ssn string) ([]*PersonOutput, error)
 }
 
 // CreatePersonInput represents the input for creating a person
@@ -66,9 +72,11 @@ type UpdatePersonInput struct {
 // PersonOutput represents the output for person entity operations
 type PersonOutput struct {
 	ID            uuid.UUID  `json:"id"`
+	PartnerID     uuid.UUID  `json:"partner_id"`
 	FirstName     string     `json:"first_name"`
 	LastName      string     `json:"last_name"`
 	DateOfBirth   string     `json:"date_of_birth"` // Format: YYYY-MM-DD
+	// SSN shows only the last four digits, unless revealed with RevealSSN
 	SSN           *string    `json:"ssn,omitempty"`
 	Email         *string    `json:"email,omitempty"`
 	PhoneNumber   *string    `json:"phone_number,omitempty"`
@@ -86,12 +94,15 @@ type PersonOutput struct {
 
 type service struct {
 	personRepo repository.PersonRepository
+	auditor    audit.Recorder
 }
 
-// NewService creates a new person service
-func NewService(personRepo repository.PersonRepository) Service {
+// NewService creates a new person service. Creates and updates are recorded
+// with auditor.
+func NewService(personRepo repository.PersonRepository, auditor audit.Recorder) Service {
 	return &service{
 		personRepo: personRepo,
+		auditor:    auditor,
 	}
 }
 
@@ -127,6 +138,7 @@ func (s *service) Create(ctx context.Context, input CreatePersonInput) (*PersonO
 	if err := s.personRepo.Create(ctx, person); err != nil {
 		return nil, err
 	}
+	s.auditor.Record(ctx, audit.Event{Action: "person.create", EntityType: audit.EntityPerson, EntityID: person.ID.String(), After: person})
 
 	return s.entityToOutput(person), nil
 }
@@ -145,6 +157,26 @@ func (s *service) GetByID(ctx context.Context, id uuid.UUID) (*PersonOutput, err
 	return s.entityToOutput(person), nil
 }
 
+// RevealSSN retrieves a person entity by ID with its full SSN. Every reveal
+// is recorded in the audit log, and the SSN is not revealed if it can't be.
+func (s *service) RevealSSN(ctx context.Context, id uuid.UUID) (*PersonOutput, error) {
+	person, err := s.personRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if person == nil {
+		return nil, ErrPersonNotFound
+	}
+	if err := s.auditor.Write(ctx, audit.Event{Action: "person.reveal", EntityType: audit.EntityPerson, EntityID: person.ID.String(), After: map[string]interface{}{"fields": []string{"ssn"}}}); err != nil {
+		return nil, fmt.Errorf("recording SSN reveal: %w", err)
+	}
+
+	output := s.entityToOutput(person)
+	output.SSN = person.SSN
+	return output, nil
+}
+
 // Update updates an existing person entity
 func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdatePersonInput) (*PersonOutput, error) {
 	// Get existing person
@@ -156,6 +188,7 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdatePersonIn
 	if person == nil {
 		return nil, ErrPersonNotFound
 	}
+	before := *person
 
 	// Update fields if provided
 	if input.FirstName != nil {
@@ -174,6 +207,9 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdatePersonIn
 	if input.SSN != nil {
 		person.SSN = input.SSN
 	}
+	if input.GovernmentID != nil {
+		person.GovernmentID = input.GovernmentID
+	}
 	if input.Email != nil {
 		person.Email = input.Email
 	}
@@ -212,6 +248,7 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdatePersonIn
 	if err := s.personRepo.Update(ctx, person); err != nil {
 		return nil, err
 	}
+	s.auditor.Record(ctx, audit.Event{Action: "person.update", EntityType: audit.EntityPerson, EntityID: person.ID.String(), Before: &before, After: person})
 
 	return s.entityToOutput(person), nil
 }
@@ -242,14 +279,39 @@ func (s *service) List(ctx context.Context, limit, offset int) ([]*PersonOutput,
 	return outputs, nil
 }
 
-// Helper function to convert entity to output
+// FindBySSN retrieves the person entities with an SSN
+func (s *service) FindBySSN(ctx context.Context, ssn string) ([]*PersonOutput, error) {
+	if strings.TrimSpace(ssn) == "" {
+		return nil, ErrInvalidPerson
+	}
+
+	people, err := s.personRepo.FindBySSN(ctx, ssn)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*PersonOutput, len(people))
+	for i, person := range people {
+		outputs[i] = s.entityToOutput(person)
+	}
+
+	return outputs, nil
+}
+
+// Helper function to convert entity to output, with the SSN masked
 func (s *service) entityToOutput(entity *repository.PersonEntity) *PersonOutput {
+	var ssn *string
+	if entity.SSN != nil {
+		masked := redact.SSN(*entity.SSN)
+		ssn = &masked
+	}
 	return &PersonOutput{
 		ID:             entity.ID,
+		PartnerID:      entity.PartnerID,
 		FirstName:      entity.FirstName,
 		LastName:       entity.LastName,
 		DateOfBirth:    entity.DateOfBirth.Format("2006-01-02"),
-		SSN:            entity.SSN,
+		SSN:            ssn,
 		Email:          entity.Email,
 		PhoneNumber:    entity.PhoneNumber,
 		Street1:        entity.Street1,
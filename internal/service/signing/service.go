@@ -0,0 +1,169 @@
+package signing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/signature"
+	"github.com/google/uuid"
+)
+
+// KeyIDPrefix starts every signing key ID and SecretPrefix every secret, so
+// that they can be told apart and found by secret scanners.
+const (
+	KeyIDPrefix  = "csk_"
+	SecretPrefix = "css_"
+)
+
+var (
+	ErrKeyNotFound      = errors.New("signing key not found")
+	ErrKeyRevoked       = errors.New("signing key is revoked")
+	ErrInvalidInput     = errors.New("invalid signing key data")
+	ErrInvalidSignature = errors.New("invalid request signature")
+)
+
+// Service manages the keys fintech partners sign their requests with, and
+// checks those signatures
+type Service interface {
+	Issue(ctx context.Context, input IssueKeyInput) (*IssuedKeyOutput, error)
+	List(ctx context.Context, partnerID *uuid.UUID) ([]*KeyOutput, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// Verify checks that sig is a signature of r by an active key of
+	// partnerID. Every failure is reported as ErrInvalidSignature.
+	Verify(ctx context.Context, partnerID uuid.UUID, keyID string, r signature.Request, sig string) error
+}
+
+// IssueKeyInput represents the input for issuing a signing key
+type IssueKeyInput struct {
+	PartnerID string `json:"partner_id" binding:"required,uuid"`
+	Name      string `json:"name" binding:"required,max=100"`
+}
+
+// KeyOutput represents a signing key without its secret
+type KeyOutput struct {
+	ID        uuid.UUID  `json:"id"`
+	KeyID     string     `json:"key_id"`
+	PartnerID uuid.UUID  `json:"partner_id"`
+	Name      string     `json:"name"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IssuedKeyOutput represents a newly issued signing key. Secret is never
+// shown again.
+type IssuedKeyOutput struct {
+	KeyOutput
+	Secret string `json:"secret"`
+}
+
+type service struct {
+	repo repository.SigningKeyRepository
+}
+
+// NewService creates a new signing key service
+func NewService(repo repository.SigningKeyRepository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Issue creates a signing key for a partner. A partner can hold several keys
+// at once, so that keys can be rotated without downtime.
+func (s *service) Issue(ctx context.Context, input IssueKeyInput) (*IssuedKeyOutput, error) {
+	partnerID, err := uuid.Parse(input.PartnerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid partner ID", ErrInvalidInput)
+	}
+
+	keyID, secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	entity := &repository.SigningKeyEntity{
+		KeyID:     keyID,
+		PartnerID: partnerID,
+		Name:      input.Name,
+		Secret:    secret,
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return nil, err
+	}
+	return &IssuedKeyOutput{KeyOutput: *toOutput(entity), Secret: secret}, nil
+}
+
+// List retrieves signing keys, optionally only those of one partner
+func (s *service) List(ctx context.Context, partnerID *uuid.UUID) ([]*KeyOutput, error) {
+	keys, err := s.repo.List(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*KeyOutput, len(keys))
+	for i, key := range keys {
+		outputs[i] = toOutput(key)
+	}
+	return outputs, nil
+}
+
+// Revoke stops a signing key from being accepted
+func (s *service) Revoke(ctx context.Context, id uuid.UUID) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return ErrKeyRevoked
+	}
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+// Verify checks a request signature against the partner's key
+func (s *service) Verify(ctx context.Context, partnerID uuid.UUID, keyID string, r signature.Request, sig string) error {
+	if !strings.HasPrefix(keyID, KeyIDPrefix) {
+		return ErrInvalidSignature
+	}
+	key, err := s.repo.GetByKeyID(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.RevokedAt != nil || key.PartnerID != partnerID {
+		return ErrInvalidSignature
+	}
+	if !signature.Verify(key.Secret, r, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// generateKey returns a new key ID and secret
+func generateKey() (string, string, error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	return KeyIDPrefix + hex.EncodeToString(idBytes), SecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+func toOutput(key *repository.SigningKeyEntity) *KeyOutput {
+	return &KeyOutput{
+		ID:        key.ID,
+		KeyID:     key.KeyID,
+		PartnerID: key.PartnerID,
+		Name:      key.Name,
+		RevokedAt: key.RevokedAt,
+		CreatedAt: key.CreatedAt,
+	}
+}
@@ -0,0 +1,270 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cassandra-Labs-Foundation/core/internal/repository"
+	"github.com/Cassandra-Labs-Foundation/core/pkg/password"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidUser   = errors.New("invalid user data")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username is already taken")
+)
+
+// Roles are the roles a user can be given
+var Roles = map[string]bool{
+	"admin": true,
+	"user":  true,
+}
+
+// Service manages the users who log in to the API
+type Service interface {
+	Create(ctx context.Context, input CreateUserInput) (*UserOutput, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*UserOutput, error)
+	List(ctx context.Context, limit, offset int) ([]*UserOutput, error)
+	Update(ctx context.Context, id uuid.UUID, input UpdateUserInput) (*UserOutput, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Unlock(ctx context.Context, id uuid.UUID) (*UserOutput, error)
+	ResetMFA(ctx context.Context, id uuid.UUID) (*UserOutput, error)
+	EnsureAdmin(ctx context.Context, username, password string) error
+}
+
+// CreateUserInput represents the input for creating a user
+type CreateUserInput struct {
+	Username string `json:"username" binding:"required,min=3,max=64"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+	// PartnerID limits the user to the customers of one fintech partner
+	PartnerID string `json:"partner_id" binding:"omitempty,uuid"`
+}
+
+// UpdateUserInput represents the input for updating a user. Password resets
+// the user's password without the current one.
+type UpdateUserInput struct {
+	Role     *string `json:"role"`
+	Password *string `json:"password"`
+}
+
+// UserOutput represents a user without their password hash
+type UserOutput struct {
+	ID                  uuid.UUID  `json:"id"`
+	Username            string     `json:"username"`
+	Role                string     `json:"role"`
+	PartnerID           *uuid.UUID `json:"partner_id,omitempty"`
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt         *time.Time `json:"last_login_at,omitempty"`
+	PasswordChangedAt   *time.Time `json:"password_changed_at,omitempty"`
+	MFAEnabled          bool       `json:"mfa_enabled"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type service struct {
+	repo repository.UserRepository
+}
+
+// NewService creates a new user service
+func NewService(repo repository.UserRepository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Create creates a user with a hashed password
+func (s *service) Create(ctx context.Context, input CreateUserInput) (*UserOutput, error) {
+	username := NormalizeUsername(input.Username)
+	if username == "" {
+		return nil, fmt.Errorf("%w: username is required", ErrInvalidUser)
+	}
+	if !Roles[input.Role] {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, input.Role)
+	}
+
+	var partnerID *uuid.UUID
+	if input.PartnerID != "" {
+		id, err := uuid.Parse(input.PartnerID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid partner ID", ErrInvalidUser)
+		}
+		partnerID = &id
+	}
+
+	existing, err := s.repo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrUsernameTaken
+	}
+
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	entity := &repository.UserEntity{
+		Username:          username,
+		PasswordHash:      hash,
+		Role:              input.Role,
+		PartnerID:         partnerID,
+		PasswordChangedAt: &now,
+	}
+	if err := s.repo.Create(ctx, entity); err != nil {
+		return nil, err
+	}
+	return toOutput(entity), nil
+}
+
+// GetByID retrieves a user by their ID
+func (s *service) GetByID(ctx context.Context, id uuid.UUID) (*UserOutput, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toOutput(entity), nil
+}
+
+// List retrieves a paginated list of users
+func (s *service) List(ctx context.Context, limit, offset int) ([]*UserOutput, error) {
+	entities, err := s.repo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]*UserOutput, len(entities))
+	for i, entity := range entities {
+		outputs[i] = toOutput(entity)
+	}
+	return outputs, nil
+}
+
+// Update changes the role of a user or resets their password
+func (s *service) Update(ctx context.Context, id uuid.UUID, input UpdateUserInput) (*UserOutput, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Role != nil && *input.Role != entity.Role {
+		if !Roles[*input.Role] {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, *input.Role)
+		}
+		if err := s.repo.UpdateRole(ctx, id, *input.Role); err != nil {
+			return nil, err
+		}
+		entity.Role = *input.Role
+	}
+
+	if input.Password != nil {
+		hash, err := hashPassword(*input.Password)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		if err := s.repo.SetPassword(ctx, id, hash, now); err != nil {
+			return nil, err
+		}
+		entity.PasswordChangedAt = &now
+	}
+	return toOutput(entity), nil
+}
+
+// Delete removes a user
+func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Unlock lifts a lockout caused by failed logins
+func (s *service) Unlock(ctx context.Context, id uuid.UUID) (*UserOutput, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetLockout(ctx, id, 0, nil); err != nil {
+		return nil, err
+	}
+	entity.FailedLoginAttempts = 0
+	entity.LockedUntil = nil
+	return toOutput(entity), nil
+}
+
+// ResetMFA turns MFA off for a user who lost their authenticator app and
+// recovery codes, so that they can log in with their password and enroll again
+func (s *service) ResetMFA(ctx context.Context, id uuid.UUID) (*UserOutput, error) {
+	entity, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetMFA(ctx, id, nil, nil, nil); err != nil {
+		return nil, err
+	}
+	entity.MFASecret = nil
+	entity.MFAEnabledAt = nil
+	entity.MFARecoveryCodes = nil
+	return toOutput(entity), nil
+}
+
+// EnsureAdmin creates an admin user with the given credentials unless a user
+// with that username already exists, so that a new deployment can be logged
+// in to
+func (s *service) EnsureAdmin(ctx context.Context, username, password string) error {
+	_, err := s.Create(ctx, CreateUserInput{
+		Username: username,
+		Password: password,
+		Role:     "admin",
+	})
+	if errors.Is(err, ErrUsernameTaken) {
+		return nil
+	}
+	return err
+}
+
+func (s *service) get(ctx context.Context, id uuid.UUID) (*repository.UserEntity, error) {
+	entity, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrUserNotFound
+	}
+	return entity, nil
+}
+
+// NormalizeUsername makes usernames case-insensitive
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func hashPassword(plain string) (string, error) {
+	hash, err := password.Hash(plain)
+	if errors.Is(err, password.ErrInvalid) {
+		return "", fmt.Errorf("%w: %v", ErrInvalidUser, err)
+	}
+	return hash, err
+}
+
+func toOutput(entity *repository.UserEntity) *UserOutput {
+	return &UserOutput{
+		ID:                  entity.ID,
+		Username:            entity.Username,
+		Role:                entity.Role,
+		PartnerID:           entity.PartnerID,
+		FailedLoginAttempts: entity.FailedLoginAttempts,
+		LockedUntil:         entity.LockedUntil,
+		LastLoginAt:         entity.LastLoginAt,
+		PasswordChangedAt:   entity.PasswordChangedAt,
+		MFAEnabled:          entity.MFAEnabledAt != nil,
+		CreatedAt:           entity.CreatedAt,
+		UpdatedAt:           entity.UpdatedAt,
+	}
+}
@@ -0,0 +1,52 @@
+// Package tenant carries the fintech partner a request acts for through to the
+// repositories, which stamp it on the records they create and only read and
+// change records of that partner.
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoPartner is returned by repositories asked to read or write
+// partner-owned records through a context that names no partner.
+var ErrNoPartner = errors.New("request is not associated with a partner")
+
+type contextKey struct{}
+
+// scope is the set of partners whose records a context can reach: either one
+// partner, or every partner if all is set.
+type scope struct {
+	partner uuid.UUID
+	all     bool
+}
+
+// WithPartner returns a copy of ctx limited to the records of partnerID.
+func WithPartner(ctx context.Context, partnerID uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{partner: partnerID})
+}
+
+// WithAllPartners returns a copy of ctx that reaches the records of every
+// partner. It is only for the cross-tenant admin view.
+func WithAllPartners(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{all: true})
+}
+
+// PartnerFromContext returns the partner ctx is limited to, if any.
+func PartnerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return s.partner, ok && !s.all
+}
+
+// Filter returns the partner that queries through ctx must be limited to, or
+// uuid.Nil if ctx reaches every partner. It fails closed with ErrNoPartner
+// when ctx carries neither.
+func Filter(ctx context.Context) (uuid.UUID, error) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	if !ok {
+		return uuid.Nil, ErrNoPartner
+	}
+	return s.partner, nil
+}
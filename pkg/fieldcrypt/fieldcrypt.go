@@ -0,0 +1,237 @@
+// Package fieldcrypt encrypts single database fields, such as SSNs, with
+// envelope encryption: every value gets its own random data key, which is
+// sealed with AES-256-GCM under it and then wrapped with a key-encryption key
+// (KEK) held outside the database. Values are tagged with the version of the
+// KEK that wrapped them, so KEKs can be rotated while older values stay
+// readable.
+//
+// An encrypted value looks like
+//
+//	enc:v1:<KEK version>:<wrapped data key>:<ciphertext>
+//
+// with both binary parts in unpadded base64url and each prefixed by its GCM
+// nonce.
+//
+// Encrypted values can't be searched, so a keyed HMAC of the plaintext, the
+// blind index, is stored next to them to look values up by equality.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix starts every encrypted value, followed by the format version
+const prefix = "enc:v1:"
+
+// KeySize is the length of KEKs, data keys and the blind index key
+const KeySize = 32
+
+var (
+	ErrUnknownKey = errors.New("unknown key version")
+	ErrMalformed  = errors.New("malformed encrypted value")
+	ErrDecrypt    = errors.New("decryption failed")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Keyring encrypts with the current KEK and decrypts with any KEK it holds
+type Keyring struct {
+	keks     map[string]cipher.AEAD
+	current  string
+	indexKey []byte
+}
+
+// NewKeyring creates a keyring from KEKs by version. New values are wrapped
+// with the KEK of version current; the others only decrypt. indexKey keys the
+// blind index and can't be rotated without recomputing every index.
+func NewKeyring(keks map[string][]byte, current string, indexKey []byte) (*Keyring, error) {
+	if _, ok := keks[current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q", ErrUnknownKey, current)
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("blind index key must be %d bytes", KeySize)
+	}
+	k := &Keyring{keks: make(map[string]cipher.AEAD, len(keks)), current: current, indexKey: indexKey}
+	for version, kek := range keks {
+		if version == "" || strings.Contains(version, ":") {
+			return nil, fmt.Errorf("invalid key version %q", version)
+		}
+		if len(kek) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", version, KeySize)
+		}
+		aead, err := newAEAD(kek)
+		if err != nil {
+			return nil, err
+		}
+		k.keks[version] = aead
+	}
+	return k, nil
+}
+
+// LoadKeyring creates a keyring from base64 KEKs by version and a base64
+// blind index key, as they are given in configuration. current may be empty
+// if there is only one KEK.
+func LoadKeyring(keks map[string]string, current, indexKey string) (*Keyring, error) {
+	decoded := make(map[string][]byte, len(keks))
+	for version, encoded := range keks {
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", version, err)
+		}
+		decoded[version] = key
+		if current == "" && len(keks) == 1 {
+			current = version
+		}
+	}
+	index, err := ParseKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind index key: %w", err)
+	}
+	return NewKeyring(decoded, current, index)
+}
+
+// ParseKey decodes a base64 key, as keys are given in configuration
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with a new data key wrapped with the current
+// KEK. field names what the value is, such as "person_entities.ssn"; a value
+// only decrypts with the same field, so it can't be moved to another column.
+func (k *Keyring) Encrypt(plaintext, field string) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generating data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(data, []byte(plaintext), []byte(field))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keks[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.current + ":" + encoding.EncodeToString(wrapped) + ":" + encoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value made by Encrypt for the same field. Values that
+// aren't encrypted, such as ones stored before encryption was turned on, are
+// returned as they are.
+func (k *Keyring) Decrypt(value, field string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	kek, ok := k.keks[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dataKey, err := open(kek, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	plaintext, err := open(data, sealed, []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// KeyVersion returns the version of the KEK an encrypted value was wrapped
+// with, so values under an old KEK can be found and re-encrypted
+func KeyVersion(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	version, _, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return version, ok
+}
+
+// Stale reports whether value should be encrypted again: it isn't encrypted
+// yet, or its KEK is not the current one
+func (k *Keyring) Stale(value string) bool {
+	version, ok := KeyVersion(value)
+	return !ok || version != k.current
+}
+
+// IsEncrypted reports whether value was made by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// BlindIndex returns the hex HMAC-SHA256 of value for field. Equal values of
+// the same field get equal indexes; callers should normalize values first so
+// that, say, "123-45-6789" and "123456789" match.
+func (k *Keyring) BlindIndex(value, field string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext under a random nonce, which it returns first
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts what seal returned
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,200 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func newTestKeyring(t *testing.T, current string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(map[string][]byte{"1": testKey(1), "2": testKey(2)}, current, testKey(9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := newTestKeyring(t, "1")
+	for _, plaintext := range []string{"123-45-6789", "", "ünïcode", strings.Repeat("x", 4096)} {
+		encrypted, err := k.Encrypt(plaintext, "person_entities.ssn")
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if !IsEncrypted(encrypted) || (plaintext != "" && strings.Contains(encrypted, plaintext)) {
+			t.Errorf("Encrypt(%q) = %q", plaintext, encrypted)
+		}
+		if version, ok := KeyVersion(encrypted); !ok || version != "1" {
+			t.Errorf("KeyVersion = %q, %v; want 1", version, ok)
+		}
+		got, err := k.Decrypt(encrypted, "person_entities.ssn")
+		if err != nil || got != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+
+	a, _ := k.Encrypt("123-45-6789", "person_entities.ssn")
+	b, _ := k.Encrypt("123-45-6789", "person_entities.ssn")
+	if a == b {
+		t.Error("Encrypt is deterministic")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	k := newTestKeyring(t, "1")
+	encrypted, err := k.Encrypt("123-45-6789", "person_entities.ssn")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	parts := strings.Split(encrypted, ":")
+	flip := func(part string) string {
+		data, _ := encoding.DecodeString(part)
+		data[len(data)-1] ^= 1
+		return encoding.EncodeToString(data)
+	}
+	only3, err := NewKeyring(map[string][]byte{"3": testKey(3)}, "3", testKey(9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		keys    *Keyring
+		value   string
+		field   string
+		wantErr error
+	}{
+		{"other field", k, encrypted, "person_entities.government_id", ErrDecrypt},
+		{"other table", k, encrypted, "business_entities.ssn", ErrDecrypt},
+		{"unknown key", only3, encrypted, "person_entities.ssn", ErrUnknownKey},
+		{"relabeled key version", k, strings.Join([]string{parts[0], parts[1], "2", parts[3], parts[4]}, ":"), "person_entities.ssn", ErrDecrypt},
+		{"tampered data key", k, strings.Join([]string{parts[0], parts[1], parts[2], flip(parts[3]), parts[4]}, ":"), "person_entities.ssn", ErrDecrypt},
+		{"tampered ciphertext", k, strings.Join([]string{parts[0], parts[1], parts[2], parts[3], flip(parts[4])}, ":"), "person_entities.ssn", ErrDecrypt},
+		{"missing part", k, strings.Join(parts[:4], ":"), "person_entities.ssn", ErrMalformed},
+		{"not base64", k, strings.Join([]string{parts[0], parts[1], parts[2], "!!", parts[4]}, ":"), "person_entities.ssn", ErrMalformed},
+		{"too short", k, "enc:v1:1:AA:AA", "person_entities.ssn", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keys.Decrypt(tt.value, tt.field)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decrypt = %q, %v; want %v", got, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecryptPlaintext(t *testing.T) {
+	k := newTestKeyring(t, "1")
+	for _, value := range []string{"123-45-6789", "", "enc:v2:1:a:b"} {
+		got, err := k.Decrypt(value, "person_entities.ssn")
+		if err != nil || got != value {
+			t.Errorf("Decrypt(%q) = %q, %v; want it unchanged", value, got, err)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old := newTestKeyring(t, "1")
+	encrypted, err := old.Encrypt("123-45-6789", "person_entities.ssn")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := newTestKeyring(t, "2")
+	if got, err := rotated.Decrypt(encrypted, "person_entities.ssn"); err != nil || got != "123-45-6789" {
+		t.Fatalf("Decrypt under the old key after rotation = %q, %v", got, err)
+	}
+	if !rotated.Stale(encrypted) || old.Stale(encrypted) {
+		t.Errorf("Stale = %v after rotation, %v before; want true, false", rotated.Stale(encrypted), old.Stale(encrypted))
+	}
+	if !rotated.Stale("123-45-6789") {
+		t.Error("plaintext is not stale")
+	}
+
+	reencrypted, err := rotated.Encrypt("123-45-6789", "person_entities.ssn")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if version, _ := KeyVersion(reencrypted); version != "2" || rotated.Stale(reencrypted) {
+		t.Errorf("re-encrypted under key %q, stale %v", version, rotated.Stale(reencrypted))
+	}
+	only1, err := NewKeyring(map[string][]byte{"1": testKey(1)}, "1", testKey(9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if _, err := only1.Decrypt(reencrypted, "person_entities.ssn"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt without the new key: %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	k := newTestKeyring(t, "1")
+	index := k.BlindIndex("123456789", "person_entities.ssn")
+	if len(index) != 64 {
+		t.Errorf("BlindIndex = %q, want 64 hex digits", index)
+	}
+	if k.BlindIndex("123456789", "person_entities.ssn") != index {
+		t.Error("BlindIndex is not deterministic")
+	}
+	if k.BlindIndex("123456780", "person_entities.ssn") == index {
+		t.Error("BlindIndex of another value matches")
+	}
+	if k.BlindIndex("123456789", "business_entities.tax_id") == index {
+		t.Error("BlindIndex of another field matches")
+	}
+	// Rotating KEKs keeps the index
+	if newTestKeyring(t, "2").BlindIndex("123456789", "person_entities.ssn") != index {
+		t.Error("BlindIndex changed with the current KEK")
+	}
+	other, _ := NewKeyring(map[string][]byte{"1": testKey(1)}, "1", testKey(8))
+	if other.BlindIndex("123456789", "person_entities.ssn") == index {
+		t.Error("BlindIndex under another index key matches")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(testKey(b)) }
+	tests := []struct {
+		name        string
+		keks        map[string]string
+		current     string
+		indexKey    string
+		wantCurrent string
+		wantErr     bool
+	}{
+		{name: "one key", keks: map[string]string{"1": key(1)}, indexKey: key(9), wantCurrent: "1"},
+		{name: "current given", keks: map[string]string{"1": key(1), "2": key(2)}, current: "2", indexKey: key(9), wantCurrent: "2"},
+		{name: "current missing", keks: map[string]string{"1": key(1), "2": key(2)}, indexKey: key(9), wantErr: true},
+		{name: "unknown current", keks: map[string]string{"1": key(1)}, current: "2", indexKey: key(9), wantErr: true},
+		{name: "short key", keks: map[string]string{"1": base64.StdEncoding.EncodeToString([]byte("short"))}, indexKey: key(9), wantErr: true},
+		{name: "not base64", keks: map[string]string{"1": "!!"}, indexKey: key(9), wantErr: true},
+		{name: "no index key", keks: map[string]string{"1": key(1)}, wantErr: true},
+		{name: "version with a colon", keks: map[string]string{"1:2": key(1)}, indexKey: key(9), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := LoadKeyring(tt.keks, tt.current, tt.indexKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeyring error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			encrypted, err := k.Encrypt("x", "f")
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if version, _ := KeyVersion(encrypted); version != tt.wantCurrent {
+				t.Errorf("encrypted under key %q, want %q", version, tt.wantCurrent)
+			}
+		})
+	}
+}
@@ -2,68 +2,184 @@ package jwt
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // Custom claims structure
 type Claims struct {
 	UserID string `json:"sub"`
 	Role   string `json:"role"`
+	// PartnerID is the fintech partner the user acts for, if any
+	PartnerID string `json:"partner_id,omitempty"`
+	// ClientID and Scope are set on tokens issued to OAuth clients; Scope is
+	// a space-separated list as in RFC 6749
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// AMR lists how the user authenticated, as in RFC 8176: "pwd" for a
+	// password, and "otp" and "mfa" once a one-time code was checked too
+	AMR []string `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// mfaAudience is the audience of MFA challenge tokens, which are only
+// accepted by ValidateMFAChallenge
+const mfaAudience = "mfa-challenge"
+
+// mfaChallengeLifetime is how long a user has to complete an MFA challenge
+const mfaChallengeLifetime = 5 * time.Minute
+
 // Service provides methods for JWT token handling
 type Service interface {
-	GenerateToken(userID, role string) (string, error)
+	GenerateToken(userID, role, partnerID string, amr []string) (string, error)
+	// GenerateClientToken creates a token for an OAuth client, whose ID is
+	// both its subject and its client_id
+	GenerateClientToken(clientID, role, partnerID string, scopes []string) (string, error)
+	// Lifetime is how long tokens are valid after they are issued
+	Lifetime() time.Duration
 	ValidateToken(tokenString string) (*Claims, error)
+	// GenerateMFAChallenge creates a short-lived token proving that a user
+	// passed the first factor, to be exchanged for an access token once they
+	// pass the second. It is not accepted as an access token.
+	GenerateMFAChallenge(userID string) (string, error)
+	// ValidateMFAChallenge validates a challenge token and returns its user ID
+	ValidateMFAChallenge(tokenString string) (string, error)
+	// JWKS returns the public keys tokens are verified with
+	JWKS() JWKSet
 }
 
 type service struct {
-	jwtSecret     string
+	signingKey    *Key
+	keys          map[string]*Key
+	jwks          JWKSet
 	expiryMinutes int
 }
 
-// NewService creates a new JWT service
-func NewService(jwtSecret string, expiryMinutes int) Service {
+// NewService creates a new JWT service that signs tokens with signingKey.
+// Tokens signed with any of verificationKeys are accepted too, so that keys
+// can be rotated without logging everyone out.
+func NewService(signingKey *Key, verificationKeys []*Key, expiryMinutes int) (Service, error) {
+	if signingKey == nil || !signingKey.CanSign() {
+		return nil, errors.New("a private signing key is required")
+	}
+	keys := map[string]*Key{signingKey.ID: signingKey}
+	jwks := JWKSet{Keys: []JWK{signingKey.JWK()}}
+	for _, key := range verificationKeys {
+		if _, ok := keys[key.ID]; ok {
+			continue
+		}
+		keys[key.ID] = key
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
 	return &service{
-		jwtSecret:     jwtSecret,
+		signingKey:    signingKey,
+		keys:          keys,
+		jwks:          jwks,
 		expiryMinutes: expiryMinutes,
-	}
+	}, nil
 }
 
 // GenerateToken creates a new JWT token
-func (s *service) GenerateToken(userID, role string) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(s.expiryMinutes) * time.Minute)
+func (s *service) GenerateToken(userID, role, partnerID string, amr []string) (string, error) {
+	return s.sign(&Claims{
+		UserID:    userID,
+		Role:      role,
+		PartnerID: partnerID,
+		AMR:       amr,
+	}, s.Lifetime())
+}
+
+// GenerateClientToken creates a new JWT token for an OAuth client
+func (s *service) GenerateClientToken(clientID, role, partnerID string, scopes []string) (string, error) {
+	return s.sign(&Claims{
+		UserID:    clientID,
+		Role:      role,
+		PartnerID: partnerID,
+		ClientID:  clientID,
+		Scope:     strings.Join(scopes, " "),
+	}, s.Lifetime())
+}
+
+// GenerateMFAChallenge creates a new MFA challenge token
+func (s *service) GenerateMFAChallenge(userID string) (string, error) {
+	return s.sign(&Claims{UserID: userID}, mfaChallengeLifetime, mfaAudience)
+}
+
+// Lifetime is how long tokens are valid after they are issued
+func (s *service) Lifetime() time.Duration {
+	return time.Duration(s.expiryMinutes) * time.Minute
+}
+
+// sign completes claims with their expiry, audience and ID and signs them
+func (s *service) sign(claims *Claims, lifetime time.Duration, audience ...string) (string, error) {
+	expirationTime := time.Now().Add(lifetime)
 	
-	// Create the JWT claims, which includes the user ID and expiry time
-	claims := &Claims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			// In JWT, the expiry time is expressed as unix milliseconds
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "banking-core-mock",
-		},
+	// Add the registered claims, which include the expiry time
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Audience:  audience,
+		// In JWT, the expiry time is expressed as unix milliseconds
+		ExpiresAt: jwt.NewNumericDate(expirationTime),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "banking-core-mock",
+		// The jti identifies the token on the denylist if it is revoked
+		ID: uuid.NewString(),
 	}
 	
-	// Create the token using the claims and sign it with the secret key
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	// Create the token using the claims and sign it with the signing key,
+	// naming the key in the kid header
+	token := jwt.NewWithClaims(s.signingKey.Method, claims)
+	token.Header["kid"] = s.signingKey.ID
+	tokenString, err := token.SignedString(s.signingKey.private)
 	
 	return tokenString, err
 }
 
-// ValidateToken validates the JWT token and returns the claims if valid
+// ValidateToken validates the JWT token and returns the claims if valid.
+// Tokens with an audience, such as MFA challenges, are not access tokens.
 func (s *service) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("not an access token")
+	}
+	return claims, nil
+}
+
+// ValidateMFAChallenge validates an MFA challenge token
+func (s *service) ValidateMFAChallenge(tokenString string) (string, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if !claims.VerifyAudience(mfaAudience, true) {
+		return "", errors.New("not an MFA challenge")
+	}
+	return claims.UserID, nil
+}
+
+// parse checks the signature and expiry of a token and returns its claims
+func (s *service) parse(tokenString string) (*Claims, error) {
 	// Parse the JWT string and store the result in a claims variable
 	claims := &Claims{}
 	
 	// Parse the token
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		// The algorithm is fixed by the key, never chosen by the token
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
 	})
 	
 	if err != nil {
@@ -76,4 +192,9 @@ func (s *service) ValidateToken(tokenString string) (*Claims, error) {
 	}
 	
 	return claims, nil
+}
+
+// JWKS returns the public keys tokens are verified with, signing key first
+func (s *service) JWKS() JWKSet {
+	return s.jwks
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cost is the bcrypt work factor new hashes are created with
+const Cost = 12
+
+const (
+	// MinLength is the fewest characters a password may have
+	MinLength = 8
+	// MaxLength is the most bytes a password may have; bcrypt ignores the rest
+	MaxLength = 72
+)
+
+var (
+	ErrMismatch = errors.New("password does not match")
+	ErrInvalid  = errors.New("invalid password")
+)
+
+// Validate checks that a password is long enough and short enough to hash
+func Validate(password string) error {
+	if len([]rune(password)) < MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrInvalid, MinLength)
+	}
+	if len(password) > MaxLength {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrInvalid, MaxLength)
+	}
+	return nil
+}
+
+// Hash validates a password and returns its bcrypt hash
+func Hash(password string) (string, error) {
+	if err := Validate(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Compare checks a password against a bcrypt hash
+func Compare(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Package redact keeps personal data and credentials out of logs and masks
+// them in API responses.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces what is removed from a log line
+const Redacted = "[redacted]"
+
+// sensitiveKeys are the JSON fields and query parameters whose values are
+// removed from logs
+var sensitiveKeys = `ssn|tax_id|government_id|date_of_birth|password|new_password|current_password|token|access_token|refresh_token|mfa_token|client_secret|secret`
+
+var (
+	// "ssn": "123-45-6789", "date_of_birth":"1990-01-01", "password": null
+	jsonField = regexp.MustCompile(`(?i)("(?:` + sensitiveKeys + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[^,}\s]+)`)
+	// ssn=123456789 in a query string or form
+	queryField = regexp.MustCompile(`(?i)\b((?:` + sensitiveKeys + `)=)[^&\s"]*`)
+	bearer     = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	// 123-45-6789, 123 45 6789 or 123456789; a bare run of nine digits
+	// may be something else, such as an amount in minor units, but is
+	// removed anyway
+	ssn = regexp.MustCompile(`\b\d{3}[- ]?\d{2}[- ]?\d{4}\b`)
+	ein = regexp.MustCompile(`\b\d{2}-\d{7}\b`)
+)
+
+// String removes SSNs, tax IDs, dates of birth, passwords and bearer tokens
+// from s. Fields are recognized by name in JSON and query strings, and SSNs
+// and EINs also by their format anywhere: dashed, spaced or as a bare run of
+// nine digits.
+func String(s string) string {
+	s = jsonField.ReplaceAllString(s, `${1}"`+Redacted+`"`)
+	s = queryField.ReplaceAllString(s, "${1}"+Redacted)
+	s = bearer.ReplaceAllString(s, "${1}"+Redacted)
+	s = ssn.ReplaceAllString(s, Redacted)
+	return ein.ReplaceAllString(s, Redacted)
+}
+
+// NewWriter returns a writer that redacts everything written to w with
+// String. Each write is redacted on its own, which suits loggers that write
+// a line at a time.
+func NewWriter(w io.Writer) io.Writer {
+	return &writer{w: w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SSN masks all but the last four digits of an SSN, as ***-**-6789. SSNs
+// of four digits or fewer are masked completely.
+func SSN(value string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+	if len(digits) <= 4 {
+		return "***-**-****"
+	}
+	return "***-**-" + digits[len(digits)-4:]
+}
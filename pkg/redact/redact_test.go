@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"dashed SSN", "ssn 123-45-6789 given", "ssn [redacted] given"},
+		{"spaced SSN", "ssn 123 45 6789 given", "ssn [redacted] given"},
+		{"bare SSN", "ssn 123456789 given", "ssn [redacted] given"},
+		{"EIN", "ein 12-3456789", "ein [redacted]"},
+		{"longer digit run", "id 1234567890", "id 1234567890"},
+		{"shorter digit run", "amount 12345678", "amount 12345678"},
+		{"UUID", "id 4f1a2b3c-0000-4000-8000-123456789012", "id 4f1a2b3c-0000-4000-8000-123456789012"},
+		{"JSON field", `{"ssn":"123456789","name":"Ann"}`, `{"ssn":"[redacted]","name":"Ann"}`},
+		{"JSON field with spaces", `{"password" : "hunter2"}`, `{"password" : "[redacted]"}`},
+		{"JSON field with escapes", `{"secret":"a\"b","x":1}`, `{"secret":"[redacted]","x":1}`},
+		{"JSON non-string field", `{"token": null, "x": 1}`, `{"token": "[redacted]", "x": 1}`},
+		{"JSON field case", `{"Date_Of_Birth":"1990-01-01"}`, `{"Date_Of_Birth":"[redacted]"}`},
+		{"query field", "GET /lookup?tax_id=123&x=1", "GET /lookup?tax_id=[redacted]&x=1"},
+		{"bearer token", "Authorization: Bearer eyJhbGciOi.abc-_.x9", "Authorization: Bearer [redacted]"},
+		{"API key", "authorization: bearer cbk_abc_def", "authorization: bearer [redacted]"},
+		{"other fields", `{"email":"a@example.com","amount":"12.34"}`, `{"email":"a@example.com","amount":"12.34"}`},
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("%s: String(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	line := "created person ssn=123-45-6789\n"
+	n, err := w.Write([]byte(line))
+	if err != nil || n != len(line) {
+		t.Fatalf("Write = %d, %v; want %d", n, err, len(line))
+	}
+	if got, want := buf.String(), "created person ssn=[redacted]\n"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestSSN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"123-45-6789", "***-**-6789"},
+		{"123456789", "***-**-6789"},
+		{"123 45 6789", "***-**-6789"},
+		{"6789", "***-**-****"},
+		{"", "***-**-****"},
+	}
+	for _, tt := range tests {
+		if got := SSN(tt.in); got != tt.want {
+			t.Errorf("SSN(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
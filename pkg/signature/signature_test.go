@@ -0,0 +1,101 @@
+package signature
+
+import (
+	"testing"
+	"time"
+)
+
+const testSecret = "css_secret"
+
+func testRequest() Request {
+	return Request{
+		Method:    "POST",
+		URI:       "/api/v1/ledger/transfer?x=1",
+		Timestamp: time.Unix(1760000000, 0),
+		Nonce:     "nonce-1",
+		Body:      []byte(`{"amount":"12.34"}`),
+	}
+}
+
+func TestStringToSign(t *testing.T) {
+	want := "v1\nPOST\n/api/v1/ledger/transfer?x=1\n1760000000\nnonce-1\n" +
+		"e8c3cf2819b30b87a8b6b124d5006bdaa895f2b286d66995f3cd3a8e65412446"
+	if got := StringToSign(testRequest()); got != want {
+		t.Errorf("StringToSign = %q, want %q", got, want)
+	}
+
+	lower := testRequest()
+	lower.Method = "post"
+	if StringToSign(lower) != want {
+		t.Error("StringToSign depends on the case of the method")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed independently with Python's hmac module
+	want := "v1=5942157fd6d54e054847e2b256b6869bdba92f2a2e4bd54f77b4840353be5558"
+	if got := Sign(testSecret, testRequest()); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signature := Sign(testSecret, testRequest())
+	tests := []struct {
+		name      string
+		secret    string
+		change    func(r *Request)
+		signature string
+		want      bool
+	}{
+		{name: "unchanged", want: true},
+		{name: "surrounding whitespace", signature: " " + signature + "\n", want: true},
+		{name: "other secret", secret: "css_other"},
+		{name: "method", change: func(r *Request) { r.Method = "PUT" }},
+		{name: "path", change: func(r *Request) { r.URI = "/api/v1/ledger/deposit?x=1" }},
+		{name: "query", change: func(r *Request) { r.URI = "/api/v1/ledger/transfer?x=2" }},
+		{name: "timestamp", change: func(r *Request) { r.Timestamp = r.Timestamp.Add(time.Second) }},
+		{name: "nonce", change: func(r *Request) { r.Nonce = "nonce-2" }},
+		{name: "body", change: func(r *Request) { r.Body = []byte(`{"amount":"99.99"}`) }},
+		{name: "empty body", change: func(r *Request) { r.Body = nil }},
+		{name: "missing version", signature: signature[len("v1="):]},
+		{name: "empty signature", signature: " "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, r, sig := testSecret, testRequest(), signature
+			if tt.secret != "" {
+				secret = tt.secret
+			}
+			if tt.change != nil {
+				tt.change(&r)
+			}
+			if tt.signature != "" {
+				sig = tt.signature
+			}
+			if got := Verify(secret, r, sig); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   int64
+		wantOK bool
+	}{
+		{"1760000000", 1760000000, true},
+		{"0", 0, true},
+		{"1760000000.5", 0, false},
+		{"2025-10-09T00:00:00Z", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTimestamp(tt.value)
+		if ok != tt.wantOK || (ok && got.Unix() != tt.want) {
+			t.Errorf("ParseTimestamp(%q) = %v, %v; want %d, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors,
+// "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSecret(t *testing.T) {
+	lower, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil || lower != "287082" {
+		t.Errorf("Code with a lowercase secret = %s, %v; want 287082", lower, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code with an invalid secret succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "050471", Step(now), true},
+		{"with spaces", " 050471 ", Step(now), true},
+		{"previous step", "081804", Step(now) - 1, true},
+		{"two steps ago", mustCode(t, Step(now)-2), 0, false},
+		{"next step", mustCode(t, Step(now)+1), Step(now) + 1, true},
+		{"two steps ahead", mustCode(t, Step(now)+2), 0, false},
+		{"wrong code", "123456", 0, false},
+		{"eight digits", "14050471", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, now)
+		if ok != tt.wantOK || step != tt.wantStep {
+			t.Errorf("%s: Validate(%q) = %d, %v; want %d, %v", tt.name, tt.code, step, ok, tt.wantStep, tt.wantOK)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == other {
+		t.Error("GenerateSecret returned the same secret twice")
+	}
+	if key, err := encoding.DecodeString(secret); err != nil || len(key) != secretSize {
+		t.Errorf("GenerateSecret = %q, which decodes to %d bytes, %v", secret, len(key), err)
+	}
+}
+
+func mustCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := Code(rfcSecret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}